@@ -0,0 +1,114 @@
+package raztracer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Addr2LineMode selects the line format of WriteAddr2Line
+type Addr2LineMode string
+
+// Addr2Line modes
+const (
+	Addr2LineOffsets    Addr2LineMode = "offsets"    // "binary +offset" lines to feed addr2line or symbolizers
+	Addr2LineSymbolized Addr2LineMode = "symbolized" // "func at file:line" lines like addr2line -f output
+)
+
+// WriteAddr2Line writes the backtraces of the exported events to 'w' in an addr2line compatible
+// format, one frame per line. The backtraces of events are separated by empty lines.
+func (e *TraceExport) WriteAddr2Line(w io.Writer, mode Addr2LineMode) error {
+	bw := bufio.NewWriter(w)
+	first := true
+
+	for _, evt := range e.Events {
+		if len(evt.Backtrace) == 0 {
+			continue
+		}
+
+		if !first {
+			fmt.Fprintln(bw)
+		}
+		first = false
+
+		err := writeAddr2LineFrames(bw, evt.Backtrace, mode)
+		if err != nil {
+			return Error(err)
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return Error(err)
+	}
+	return nil
+}
+
+// WriteBacktraceAddr2Line writes a backtrace to 'w' in an addr2line compatible format
+func WriteBacktraceAddr2Line(w io.Writer, frames []*BacktraceFrame, mode Addr2LineMode) error {
+	bw := bufio.NewWriter(w)
+
+	err := writeAddr2LineFrames(bw, frames, mode)
+	if err != nil {
+		return Error(err)
+	}
+
+	if err := bw.Flush(); err != nil {
+		return Error(err)
+	}
+	return nil
+}
+
+func writeAddr2LineFrames(w io.Writer, frames []*BacktraceFrame, mode Addr2LineMode) error {
+	for _, frame := range frames {
+		var line string
+
+		switch mode {
+		case Addr2LineOffsets:
+			module := frame.Module
+			if len(module) == 0 {
+				module = "??"
+			}
+			offset := frame.Offset
+			if len(offset) == 0 {
+				offset = frame.PC
+			}
+			line = fmt.Sprintf("%s +%s", module, offset)
+
+		case Addr2LineSymbolized:
+			source := frame.Source
+			if len(source) == 0 || strings.HasSuffix(source, "(no debug info)") {
+				source = "??:0"
+			}
+			line = fmt.Sprintf("%s at %s", frame.functionName(), source)
+
+		default:
+			return Errorf("unknown addr2line mode: %s", mode)
+		}
+
+		_, err := fmt.Fprintln(w, line)
+		if err != nil {
+			return Error(err)
+		}
+	}
+
+	return nil
+}
+
+// functionName returns the name of the frame's function without its address
+func (bt *BacktraceFrame) functionName() string {
+	if bt.fn != nil {
+		return bt.fn.Name
+	}
+
+	// frames of imported exports only have the formatted function
+	if i := strings.LastIndex(bt.Function, " ("); i > 0 {
+		return bt.Function[:i]
+	}
+
+	if len(bt.Function) == 0 {
+		return "??"
+	}
+
+	return bt.Function
+}
@@ -0,0 +1,44 @@
+package raztracer
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteAddr2Line(t *testing.T) {
+	export := &TraceExport{
+		Events: []*TraceEvent{
+			{Backtrace: []*BacktraceFrame{
+				{Function: "traced (0x1139+0x555555554000)", Source: "tracee.c:12", PC: "0x55555555513d", Module: "/tmp/tracee", Offset: "0x113d"},
+				{Function: "main (0x1189+0x555555554000)", Source: "main.c (no debug info)", PC: "0x5555555551a0", Module: "/tmp/tracee", Offset: "0x11a0"},
+			}},
+			{}, // events without a backtrace are skipped
+			{Backtrace: []*BacktraceFrame{
+				{Function: "", PC: "0x7ffff7e1d000"},
+			}},
+		},
+	}
+
+	tests := []struct {
+		mode     Addr2LineMode
+		expected string
+	}{
+		{Addr2LineOffsets, "/tmp/tracee +0x113d\n/tmp/tracee +0x11a0\n\n?? +0x7ffff7e1d000\n"},
+		{Addr2LineSymbolized, "traced at tracee.c:12\nmain at ??:0\n\n?? at ??:0\n"},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := export.WriteAddr2Line(&buf, test.mode); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != test.expected {
+			t.Errorf("%s: expected\n%q, got\n%q", test.mode, test.expected, buf.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteBacktraceAddr2Line(&buf, export.Events[0].Backtrace, "unknown"); err == nil {
+		t.Error("unknown mode was accepted")
+	}
+}
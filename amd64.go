@@ -3,8 +3,11 @@
 package raztracer
 
 import (
+	"debug/elf"
+
 	"github.com/razzie/raztracer/internal/dwarf/frame"
 	"github.com/razzie/raztracer/internal/dwarf/op"
+	"golang.org/x/arch/x86/x86asm"
 )
 
 // TrapInstruction contains the int3 trap instruction for x86-64 platform
@@ -16,8 +19,81 @@ const (
 	PCRegNum = 16 // rip
 	SPRegNum = 19 // rsp
 	FPRegNum = 4  // rbp
+
+	RetRegNum     = 10 // rax
+	SyscallRegNum = 15 // orig_rax
+
+	ThreadPointerDwarfReg = 58 // fs_base
+)
+
+// Core file parameters (struct elf_prstatus)
+const (
+	coreMachine       = elf.EM_X86_64
+	prstatusSize      = 336
+	prstatusPIDOffset = 32
+	prstatusRegOffset = 112
 )
 
+// maxInstructionSize is the maximum length of a single instruction
+const maxInstructionSize = 15
+
+// Disassemble decodes the first instruction in 'code' located at 'pc'
+// and returns it in GNU syntax together with its length
+func Disassemble(code []byte, pc uintptr) (string, int, error) {
+	inst, err := x86asm.Decode(code, 64)
+	if err != nil {
+		return "", 0, Error(err)
+	}
+
+	return x86asm.GNUSyntax(inst, uint64(pc), nil), inst.Len, nil
+}
+
+// classifyInstruction returns whether the first instruction in 'code' is a call or a return
+func classifyInstruction(code []byte) (isCall, isReturn bool) {
+	inst, err := x86asm.Decode(code, 64)
+	if err != nil {
+		return false, false
+	}
+
+	return inst.Op == x86asm.CALL, inst.Op == x86asm.RET
+}
+
+// directCallTarget decodes the first instruction in 'code' located at 'pc' and returns
+// the target if it's a direct call, and the length of the instruction (1 if undecodable)
+func directCallTarget(code []byte, pc uintptr) (uintptr, int, bool) {
+	inst, err := x86asm.Decode(code, 64)
+	if err != nil {
+		return 0, 1, false
+	}
+
+	if inst.Op != x86asm.CALL {
+		return 0, inst.Len, false
+	}
+
+	rel, ok := inst.Args[0].(x86asm.Rel)
+	if !ok {
+		return 0, inst.Len, false
+	}
+
+	return uintptr(int64(pc) + int64(inst.Len) + int64(rel)), inst.Len, true
+}
+
+// sigcontextOffset is the offset of the saved general registers (uc_mcontext.gregs)
+// from the stack pointer of the signal trampoline
+const sigcontextOffset = 40
+
+// sigcontextRegs maps the saved general registers of a signal frame to dwarf register numbers
+var sigcontextRegs = []uint64{8, 9, 10, 11, 12, 13, 14, 15, 5, 4, 6, 3, 1, 0, 2, 7, 16} // r8-r15, rdi, rsi, rbp, rbx, rdx, rax, rcx, rsp, rip
+
+// sigreturnCode is the code of the signal trampoline (__restore_rt): mov $15,%rax; syscall
+var sigreturnCode = []byte{0x48, 0xc7, 0xc0, 0x0f, 0x00, 0x00, 0x00, 0x0f, 0x05}
+
+// stackRedZone is the area below SP that must not be touched when injecting calls
+const stackRedZone = 128
+
+// ArgRegNums contains the indexes of integer argument registers in calling convention order
+var ArgRegNums = []int{14, 13, 12, 11, 9, 8} // rdi, rsi, rdx, rcx, r8, r9
+
 // AsmToDwarfReg converts a ptrace reg number to dwarf reg number
 func AsmToDwarfReg(reg int) (uint64, bool) {
 	asm2dwarf := map[int]uint64{
@@ -37,7 +113,8 @@ func AsmToDwarfReg(reg int) (uint64, bool) {
 		13: 4,
 		14: 5,
 		16: 49, // rip
-		19: 7}  // rsp
+		19: 7,  // rsp
+		21: 58} // fs_base
 
 	dreg, ok := asm2dwarf[reg]
 	return dreg, ok
@@ -81,5 +158,14 @@ func FixFrameContext(framectx *frame.FrameContext, pc uintptr, regs *op.DwarfReg
 		}
 	}
 
+	// the stack pointer of the caller is the CFA unless the CFI says otherwise
+	if framectx.Regs[7].Rule == frame.RuleUndefined {
+		framectx.Regs[7] = frame.DWRule{
+			Rule:   frame.RuleValOffset,
+			Reg:    7,
+			Offset: 0,
+		}
+	}
+
 	return framectx
 }
@@ -0,0 +1,92 @@
+package raztracer
+
+import (
+	"time"
+)
+
+// DefaultOverheadGrace is the default time after attaching before the overhead limit is checked
+const DefaultOverheadGrace = 10 * time.Second
+
+// DetachCause tells why the tracer detached automatically
+type DetachCause string
+
+// Auto-detach causes
+const (
+	DetachMaxEvents   DetachCause = "max_events"
+	DetachDuration    DetachCause = "duration"
+	DetachBreakpoint  DetachCause = "breakpoint"
+	DetachMaxOverhead DetachCause = "max_overhead"
+)
+
+// AutoDetach contains the conditions that detach the tracer automatically.
+// Zero values disable the conditions, the first matching one triggers the detach.
+type AutoDetach struct {
+	MaxEvents     uint64        `yaml:"max_events,omitempty" json:"max_events,omitempty"`         // number of reported events
+	Duration      time.Duration `yaml:"duration,omitempty" json:"duration,omitempty"`             // time since attaching
+	Breakpoint    string        `yaml:"breakpoint,omitempty" json:"breakpoint,omitempty"`         // function name of the breakpoint
+	MaxOverhead   float64       `yaml:"max_overhead,omitempty" json:"max_overhead,omitempty"`     // percent of wall time the target spent stopped
+	OverheadGrace time.Duration `yaml:"overhead_grace,omitempty" json:"overhead_grace,omitempty"` // DefaultOverheadGrace if 0
+
+	// OnDetach is called after an automatic detach
+	OnDetach func(*DetachReport, error) `yaml:"-" json:"-"`
+}
+
+// SetAutoDetach sets the conditions of automatic detaching (nil disables it).
+// The conditions are checked by CheckAutoDetach, which TraceManager calls after every event.
+func (t *Tracer) SetAutoDetach(cfg *AutoDetach) {
+	t.autoDetach = cfg
+}
+
+// CheckAutoDetach detaches the tracer if an auto-detach condition is met.
+// 'evt' is the last event or nil if the wait timed out. Returns true if the tracer detached.
+func (t *Tracer) CheckAutoDetach(evt *TraceEvent) (bool, error) {
+	if t.autoDetach == nil || t.detached {
+		return false, nil
+	}
+
+	cause := t.autoDetach.cause(t, evt)
+	if len(cause) == 0 {
+		return false, nil
+	}
+
+	report, err := t.DetachWithReport()
+	report.Cause = cause
+
+	if t.autoDetach.OnDetach != nil {
+		t.autoDetach.OnDetach(report, err)
+	}
+
+	if err != nil {
+		return true, Error(err)
+	}
+	return true, nil
+}
+
+func (cfg *AutoDetach) cause(t *Tracer, evt *TraceEvent) DetachCause {
+	if cfg.MaxEvents > 0 && t.stats.Events >= cfg.MaxEvents {
+		return DetachMaxEvents
+	}
+
+	if cfg.Duration > 0 && time.Since(t.session.AttachTime) >= cfg.Duration {
+		return DetachDuration
+	}
+
+	if len(cfg.Breakpoint) > 0 && evt != nil && evt.IsBreakpoint &&
+		matchFunctionName(t.breakpointFunction(evt.PC), cfg.Breakpoint) {
+		return DetachBreakpoint
+	}
+
+	if cfg.MaxOverhead > 0 {
+		grace := cfg.OverheadGrace
+		if grace <= 0 {
+			grace = DefaultOverheadGrace
+		}
+
+		overhead := t.profile.overhead()
+		if overhead.WallTime >= grace && overhead.StoppedPercent > cfg.MaxOverhead {
+			return DetachMaxOverhead
+		}
+	}
+
+	return ""
+}
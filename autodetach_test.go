@@ -0,0 +1,21 @@
+package raztracer
+
+import (
+	"testing"
+)
+
+func TestAutoDetachBreakpoint(t *testing.T) {
+	cfg := &AutoDetach{Breakpoint: "traced"}
+	tracer := newRuleTracer()
+
+	// the backtrace is empty, as with a backtrace depth of 0
+	if cause := cfg.cause(tracer, breakpointEvent(tracer, "other")); len(cause) > 0 {
+		t.Errorf("detached at the breakpoint of other(): %s", cause)
+	}
+	if cause := cfg.cause(tracer, breakpointEvent(tracer, "traced")); cause != DetachBreakpoint {
+		t.Errorf("expected to detach at the breakpoint of traced(), got %q", cause)
+	}
+	if cause := cfg.cause(tracer, nil); len(cause) > 0 {
+		t.Errorf("detached without an event: %s", cause)
+	}
+}
@@ -0,0 +1,36 @@
+package raztracer
+
+// DefaultBacktraceDepth is the default maximum number of frames collected in events
+const DefaultBacktraceDepth = 8
+
+// BacktraceEnd tells why a backtrace ended
+type BacktraceEnd string
+
+// Possible backtrace ends
+const (
+	BacktraceComplete      BacktraceEnd = "complete"       // the outermost frame was reached
+	BacktraceTruncated     BacktraceEnd = "truncated"      // the frame limit was reached
+	BacktraceUnwindFailure BacktraceEnd = "unwind_failure" // the stack could not be unwound further
+)
+
+// Backtrace contains the frames of a thread and the reason the unwinding ended
+type Backtrace struct {
+	Frames []*BacktraceFrame `json:"frames"`
+	End    BacktraceEnd      `json:"end"`
+	Unwind *UnwindDiagnostic `json:"unwind,omitempty"`
+}
+
+// SetBacktraceDepth sets the maximum number of frames collected in events
+// (DefaultBacktraceDepth is used if 'depth' is not positive)
+func (t *Tracer) SetBacktraceDepth(depth int) {
+	if depth <= 0 {
+		depth = DefaultBacktraceDepth
+	}
+
+	t.backtraceDepth = depth
+}
+
+// GetBacktraceDepth returns the maximum number of frames collected in events
+func (t *Tracer) GetBacktraceDepth() int {
+	return t.backtraceDepth
+}
@@ -2,7 +2,6 @@ package raztracer
 
 import (
 	"fmt"
-	"path"
 	"strings"
 
 	"github.com/razzie/raztracer/internal/dwarf/op"
@@ -11,9 +10,12 @@ import (
 // BacktraceFrame contains the name and variables of a function in the backtrace
 type BacktraceFrame struct {
 	fn        *FunctionEntry
+	pc        uintptr
 	Function  string    `json:"function"`
 	Source    string    `json:"source"`
 	PC        string    `json:"pc"`
+	Module    string    `json:"module,omitempty"` // path of the executable or library of the function
+	Offset    string    `json:"offset"`           // PC relative to the static base of the module
 	CFA       string    `json:"cfa"`
 	FrameBase string    `json:"framebase"`
 	Variables []Reading `json:"variables"`
@@ -21,27 +23,34 @@ type BacktraceFrame struct {
 
 // NewBacktraceFrame returns a new BacktraceFrame
 func NewBacktraceFrame(pid int, fn *FunctionEntry, pc uintptr, regs *op.DwarfRegisters) (*BacktraceFrame, error) {
+	frame, err := NewBacktraceFrameWithOptions(pid, fn, pc, regs, DefaultReadingOptions)
+	return frame, Error(err)
+}
+
+// NewBacktraceFrameWithOptions returns a new BacktraceFrame and reads its variables using 'opts'
+func NewBacktraceFrameWithOptions(pid int, fn *FunctionEntry, pc uintptr, regs *op.DwarfRegisters, opts ReadingOptions) (*BacktraceFrame, error) {
 	vars, err := fn.GetVariables()
 	if err != nil {
 		return nil, Error(err)
 	}
 
-	values, err := GetReadings(pid, pc, regs, vars...)
+	values, err := GetReadingsWithOptions(pid, pc, regs, opts, vars...)
 
 	source := fmt.Sprintf("%#x (no debug info)", pc)
 	if fn.entry.data != nil {
-		lineEntry, _ := NewLineEntry(pc, fn.entry.data)
-		if lineEntry != nil {
-			filename := path.Base(lineEntry.Filename)
-			source = fmt.Sprintf("%s:%d", filename, lineEntry.Line)
+		if lineSource := fn.entry.data.lineSource(pc - fn.StaticBase); len(lineSource) > 0 {
+			source = lineSource
 		}
 	}
 
 	return &BacktraceFrame{
 		fn:        fn,
+		pc:        pc,
 		Function:  fmt.Sprintf("%s (%#x+%#x)", fn.Name, fn.LowPC, fn.StaticBase),
 		Source:    source,
 		PC:        fmt.Sprintf("%#x", pc),
+		Module:    fn.modulePath(),
+		Offset:    fmt.Sprintf("%#x", pc-fn.StaticBase),
 		CFA:       fmt.Sprintf("%#x", regs.CFA),
 		FrameBase: fmt.Sprintf("%#x", regs.FrameBase),
 		Variables: values,
@@ -0,0 +1,239 @@
+package raztracer
+
+import (
+	"debug/elf"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// BinaryIdentity identifies a version of an executable file
+type BinaryIdentity struct {
+	Path    string    `json:"path"`
+	Device  uint64    `json:"dev"`
+	Inode   uint64    `json:"inode"`
+	ModTime time.Time `json:"mtime"`
+	BuildID string    `json:"build_id,omitempty"`
+}
+
+// BinaryChange describes how the executable of the process changed since attaching
+type BinaryChange struct {
+	Attached BinaryIdentity  `json:"attached"`          // the image the debug data was loaded from
+	Running  BinaryIdentity  `json:"running"`           // the image the process is running now
+	OnDisk   *BinaryIdentity `json:"on_disk,omitempty"` // the file at the executable path (nil if removed)
+	Reloaded bool            `json:"reloaded"`          // the debug data was reloaded and breakpoints re-resolved
+}
+
+// String returns the change as a warning message
+func (change *BinaryChange) String() string {
+	if change.Reloaded {
+		return fmt.Sprintf("executable changed from %s (build-id: %s) to %s (build-id: %s), breakpoints re-resolved",
+			change.Attached.Path, change.Attached.BuildID, change.Running.Path, change.Running.BuildID)
+	}
+
+	if change.OnDisk == nil {
+		return fmt.Sprintf("executable %s was removed from disk, the running image is used", change.Running.Path)
+	}
+
+	return fmt.Sprintf("executable %s was replaced on disk (build-id: %s), the running image (build-id: %s) is used",
+		change.OnDisk.Path, change.OnDisk.BuildID, change.Running.BuildID)
+}
+
+// CheckBinary compares the executable of the process with the one the debug data was loaded from.
+// If the process runs a different executable (e.g. after exec), the debug data is reloaded and the
+// breakpoints set by function name are re-resolved. It returns nil if nothing changed.
+func (t *Tracer) CheckBinary() (*BinaryChange, error) {
+	running, err := t.pid.exeIdentity()
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	change := &BinaryChange{
+		Attached: t.exe,
+		Running:  running,
+	}
+
+	if !running.sameFile(&t.exe) {
+		err := t.reloadBinary()
+		if err != nil {
+			return change, Error(err)
+		}
+
+		change.Running = t.exe
+		change.Reloaded = true
+		return change, nil
+	}
+
+	change.Running.BuildID = t.exe.BuildID
+
+	onDisk, err := statBinary(t.pid.ResolvePath(running.Path))
+	if err != nil {
+		return change, nil
+	}
+
+	if onDisk.sameFile(&running) {
+		return nil, nil
+	}
+
+	onDisk.Path = running.Path
+	onDisk.BuildID, _ = readBuildID(t.pid.ResolvePath(running.Path))
+	change.OnDisk = onDisk
+	return change, nil
+}
+
+// reloadBinary loads the debug data of the currently running executable
+// and sets the breakpoints of the previously set functions again
+func (t *Tracer) reloadBinary() error {
+	exe, err := t.pid.exeIdentity()
+	if err != nil {
+		return Error(err)
+	}
+
+	debugData, err := loadDebugData(t.pid, t.debugData.libFilter)
+	if err != nil {
+		return Error(err)
+	}
+
+	exe.BuildID, _ = debugData.GetBuildID()
+	debugData.SetMemoryBudget(t.memoryBudget)
+
+	functions := t.bpFunctions
+	exitPaths := t.exitPaths
+
+	// the old breakpoints were overwritten by the new executable image
+	unregisterBreakpoints(t.pid)
+	t.breakpoints = make(map[uintptr]*Breakpoint)
+	t.exitPaths = make(map[uintptr]bool)
+	t.bpFunctions = make(map[uintptr]string)
+	t.bpLimits = make(map[uintptr]*rateLimiter)
+	t.bpThreads = make(map[uintptr]*ThreadFilter)
+	t.retBreakpoints = make(map[uintptr]*returnBreakpoint)
+	t.calls = make(map[Process][]*pendingCall)
+	t.debugData = debugData
+	t.exe = exe
+
+	var errors []error
+	resolved := make(map[string]bool)
+
+	for oldAddr, name := range functions {
+		if resolved[name] {
+			continue
+		}
+		resolved[name] = true
+
+		locs, err := t.SetBreakpointAtFunction(name)
+		if err != nil {
+			errors = append(errors, Error(err))
+		}
+
+		if exitPaths[oldAddr] {
+			for _, addr := range breakpointAddresses(locs) {
+				t.exitPaths[addr] = true
+			}
+		}
+	}
+
+	if t.rendezvous != 0 {
+		t.rendezvous = 0
+		err := t.SetLibraryTracking(true)
+		if err != nil {
+			errors = append(errors, Error(err))
+		}
+	}
+
+	return MergeErrors(errors)
+}
+
+// loadDebugData loads the debug data of the executable and shared libraries of the process
+func loadDebugData(pid Process, filter *LibraryFilter) (*DebugData, error) {
+	prog, err := os.Open(fmt.Sprintf("/proc/%d/exe", pid))
+	if err != nil {
+		return nil, Errorf("process not found: %d", pid)
+	}
+
+	debugData, err := NewDebugData(prog, pid.exeStaticBase(prog))
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	debugData.SetLibraryFilter(filter)
+
+	libs, _ := pid.SharedLibs()
+	for _, lib := range libs {
+		debugData.AddSharedLib(lib)
+	}
+
+	debugData.LoadJITSymbols(pid)
+	return debugData, nil
+}
+
+// exeStaticBase returns the load address of a position independent executable (0 otherwise)
+func (pid Process) exeStaticBase(prog *os.File) uintptr {
+	elfData, err := elf.NewFile(prog)
+	if err != nil || elfData.Type != elf.ET_DYN {
+		return 0
+	}
+
+	exe, err := pid.Executable()
+	if err != nil {
+		return 0
+	}
+
+	regions, err := pid.MemRegions()
+	if err != nil {
+		return 0
+	}
+
+	return moduleBase(regions, exe)
+}
+
+// exeIdentity returns the identity of the executable image the process is running
+func (pid Process) exeIdentity() (BinaryIdentity, error) {
+	identity, err := statBinary(fmt.Sprintf("/proc/%d/exe", pid))
+	if err != nil {
+		return BinaryIdentity{}, Error(err)
+	}
+
+	exe, _ := pid.Executable()
+	identity.Path = strings.TrimSuffix(exe, " (deleted)")
+	return *identity, nil
+}
+
+func statBinary(path string) (*BinaryIdentity, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	identity := &BinaryIdentity{
+		Path:    path,
+		ModTime: info.ModTime(),
+	}
+
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		identity.Device = uint64(stat.Dev)
+		identity.Inode = stat.Ino
+	}
+
+	return identity, nil
+}
+
+func (identity *BinaryIdentity) sameFile(other *BinaryIdentity) bool {
+	return identity.Device == other.Device &&
+		identity.Inode == other.Inode &&
+		identity.ModTime.Equal(other.ModTime)
+}
+
+func readBuildID(path string) (string, error) {
+	elfData, err := elf.Open(path)
+	if err != nil {
+		return "", Error(err)
+	}
+	defer elfData.Close()
+
+	d := &DebugData{elfData: elfData}
+	id, err := d.GetBuildID()
+	return id, Error(err)
+}
@@ -10,6 +10,7 @@ var emptyInstr = make([]byte, len(TrapInstruction))
 // Breakpoint represents a software breakpoint
 type Breakpoint struct {
 	pid       Process
+	owner     Process // process the breakpoint was created for ('pid' can be one of its threads)
 	addr      uintptr
 	enabled   bool
 	savedData []byte
@@ -19,6 +20,7 @@ type Breakpoint struct {
 func NewBreakpoint(pid Process, addr uintptr) *Breakpoint {
 	return &Breakpoint{
 		pid:       pid,
+		owner:     pid,
 		addr:      addr,
 		enabled:   false,
 		savedData: make([]byte, trapInstructionSize)}
@@ -45,6 +47,7 @@ func (bp *Breakpoint) Enable() error {
 	}
 
 	bp.enabled = true
+	registerBreakpoint(bp)
 	return nil
 }
 
@@ -60,6 +63,7 @@ func (bp *Breakpoint) Disable() error {
 	}
 
 	bp.enabled = false
+	unregisterBreakpoint(bp)
 	return nil
 }
 
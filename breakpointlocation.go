@@ -0,0 +1,117 @@
+package raztracer
+
+import (
+	"fmt"
+)
+
+// BreakpointPlacement selects where breakpoints are placed in functions
+type BreakpointPlacement string
+
+// Breakpoint placements
+const (
+	PlaceAtEntry       BreakpointPlacement = "entry"          // exactly at the low PC
+	PlaceAfterPrologue BreakpointPlacement = "after_prologue" // at the first statement after the low PC
+	PlaceFirstLine     BreakpointPlacement = "first_line"     // at every statement of the first line after the prologue
+)
+
+// BreakpointLocation describes where a breakpoint set by function name landed
+type BreakpointLocation struct {
+	Function     string              `json:"function"`
+	Library      string              `json:"library,omitempty"`
+	LowPC        uintptr             `json:"low_pc"`        // runtime address of the function
+	Address      uintptr             `json:"address"`       // runtime address of the breakpoint
+	SkipPrologue bool                `json:"skip_prologue"` // the breakpoint is after the function prologue
+	Placement    BreakpointPlacement `json:"placement"`     // the placement actually used
+	Error        string              `json:"error,omitempty"`
+}
+
+// SetBreakpointPlacement sets where SetBreakpointAtFunction places breakpoints in functions
+func (t *Tracer) SetBreakpointPlacement(placement BreakpointPlacement) {
+	t.placement = placement
+}
+
+// GetBreakpointPlacement returns where SetBreakpointAtFunction places breakpoints in functions
+func (t *Tracer) GetBreakpointPlacement() BreakpointPlacement {
+	return t.placement
+}
+
+// newBreakpointLocations returns the breakpoint locations of the function for the placement.
+// Functions without line info fall back to their entry point.
+func newBreakpointLocations(fn *FunctionEntry, main *DebugData, placement BreakpointPlacement) []BreakpointLocation {
+	var addrs []uintptr
+
+	switch placement {
+	case PlaceAtEntry:
+		addrs = []uintptr{fn.LowPC}
+
+	case PlaceFirstLine:
+		addrs, _ = fn.firstLineAddresses()
+		if len(addrs) == 0 {
+			addrs = []uintptr{fn.BreakpointAddress}
+		}
+
+	default:
+		addrs = []uintptr{fn.BreakpointAddress}
+	}
+
+	locs := make([]BreakpointLocation, 0, len(addrs))
+	for _, addr := range addrs {
+		loc := newBreakpointLocation(fn, main, addr)
+		if loc.SkipPrologue {
+			loc.Placement = placement
+			if placement == PlaceAtEntry {
+				loc.Placement = PlaceAfterPrologue
+			}
+		} else {
+			loc.Placement = PlaceAtEntry
+		}
+		locs = append(locs, loc)
+	}
+
+	return locs
+}
+
+func newBreakpointLocation(fn *FunctionEntry, main *DebugData, addr uintptr) BreakpointLocation {
+	loc := BreakpointLocation{
+		Function:     fn.Name,
+		LowPC:        fn.LowPC + fn.StaticBase,
+		Address:      addr + fn.StaticBase,
+		SkipPrologue: addr != fn.LowPC,
+	}
+
+	if fn.Lib != nil || (fn.entry.data != nil && fn.entry.data != main) {
+		loc.Library = fn.moduleName()
+	}
+
+	return loc
+}
+
+// String returns the breakpoint location as a string
+func (loc *BreakpointLocation) String() string {
+	str := loc.Function
+	if len(loc.Library) > 0 {
+		str = loc.Library + "!" + str
+	}
+
+	str += fmt.Sprintf(" at %#x", loc.Address)
+	if loc.SkipPrologue {
+		str += fmt.Sprintf(" (%#x+%#x, after prologue)", loc.LowPC, loc.Address-loc.LowPC)
+	}
+
+	if len(loc.Error) > 0 {
+		str += ": " + loc.Error
+	}
+
+	return str
+}
+
+// breakpointAddresses returns the addresses of the successfully set breakpoints
+func breakpointAddresses(locs []BreakpointLocation) []uintptr {
+	var addrs []uintptr
+	for _, loc := range locs {
+		if len(loc.Error) == 0 {
+			addrs = append(addrs, loc.Address)
+		}
+	}
+	return addrs
+}
@@ -0,0 +1,93 @@
+package raztracer
+
+import (
+	"sort"
+	"sync"
+)
+
+// installedBreakpoints contains the enabled breakpoints of each process,
+// so memory reads can return the original bytes instead of the trap instructions
+var installedBreakpoints = struct {
+	sync.RWMutex
+	procs map[Process]map[uintptr]*Breakpoint
+}{procs: make(map[Process]map[uintptr]*Breakpoint)}
+
+func registerBreakpoint(bp *Breakpoint) {
+	installedBreakpoints.Lock()
+	defer installedBreakpoints.Unlock()
+
+	bps := installedBreakpoints.procs[bp.owner]
+	if bps == nil {
+		bps = make(map[uintptr]*Breakpoint)
+		installedBreakpoints.procs[bp.owner] = bps
+	}
+	bps[bp.addr] = bp
+}
+
+func unregisterBreakpoint(bp *Breakpoint) {
+	installedBreakpoints.Lock()
+	defer installedBreakpoints.Unlock()
+
+	bps := installedBreakpoints.procs[bp.owner]
+	if bps[bp.addr] != bp {
+		return
+	}
+
+	delete(bps, bp.addr)
+	if len(bps) == 0 {
+		delete(installedBreakpoints.procs, bp.owner)
+	}
+}
+
+// unregisterBreakpoints forgets the breakpoints of the process (e.g. after exec replaced its image)
+func unregisterBreakpoints(pid Process) {
+	installedBreakpoints.Lock()
+	defer installedBreakpoints.Unlock()
+
+	delete(installedBreakpoints.procs, pid)
+}
+
+// maskBreakpoints replaces the trap instructions of the enabled breakpoints in the data read
+// from 'addr' by the original bytes. Only reads through the ID of the traced process are masked.
+func maskBreakpoints(pid Process, addr uintptr, data []byte) {
+	installedBreakpoints.RLock()
+	defer installedBreakpoints.RUnlock()
+
+	bps := installedBreakpoints.procs[pid]
+	if len(bps) == 0 {
+		return
+	}
+
+	end := addr + uintptr(len(data))
+	if len(bps) > len(data) {
+		// small reads of processes with many breakpoints
+		for pos := addr - trapInstructionSize + 1; pos < end; pos++ {
+			if bp, found := bps[pos]; found {
+				bp.restoreBytes(addr, data)
+			}
+		}
+		return
+	}
+
+	addrs := make([]uintptr, 0, len(bps))
+	for bpAddr := range bps {
+		if bpAddr+trapInstructionSize > addr && bpAddr < end {
+			addrs = append(addrs, bpAddr)
+		}
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+
+	for _, bpAddr := range addrs {
+		bps[bpAddr].restoreBytes(addr, data)
+	}
+}
+
+// restoreBytes copies the saved original bytes of the breakpoint to the data read from 'addr'
+func (bp *Breakpoint) restoreBytes(addr uintptr, data []byte) {
+	for i, b := range bp.savedData {
+		pos := int(bp.addr-addr) + i
+		if pos >= 0 && pos < len(data) {
+			data[pos] = b
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package raztracer
+
+import (
+	"time"
+)
+
+// CallInfo correlates the entry and exit events of a function call
+type CallInfo struct {
+	ID       uint64        `json:"id"` // shared by the entry and exit events of the call
+	Function string        `json:"function"`
+	Depth    int           `json:"depth"` // number of traced calls in progress on the thread
+	Exit     bool          `json:"exit"`
+	Duration time.Duration `json:"duration,omitempty"` // time between the entry and exit events
+	Return   string        `json:"return,omitempty"`   // return value decoded by the function signature
+}
+
+type pendingCall struct {
+	id       uint64
+	function string
+	fn       *FunctionEntry
+	retaddr  uintptr
+	cfa      uintptr
+	entered  time.Time
+}
+
+type returnBreakpoint struct {
+	refs  int
+	owned bool // the breakpoint was set for the return only
+}
+
+// SetExitTracing enables reporting the returns of the functions set by SetBreakpointAtFunction.
+// The entry and exit events of a call share the same call ID.
+func (t *Tracer) SetExitTracing(enabled bool) {
+	t.exitTracing = enabled
+}
+
+// traceCall sets the call info of function entry and exit events.
+// Returns true if the event is a return breakpoint hit that doesn't belong to a call of the thread.
+func (t *Tracer) traceCall(evt *TraceEvent) bool {
+	if !evt.IsBreakpoint {
+		return false
+	}
+
+	if _, isReturn := t.retBreakpoints[evt.PC]; isReturn {
+		evt.Call = t.exitCall(evt)
+		if evt.Call == nil {
+			_, isFunction := t.bpFunctions[evt.PC]
+			return !isFunction && !t.exitPaths[evt.PC]
+		}
+		return false
+	}
+
+	if name, isFunction := t.bpFunctions[evt.PC]; isFunction && t.exitTracing {
+		evt.Call = t.enterCall(evt, name)
+	}
+
+	return false
+}
+
+func (t *Tracer) enterCall(evt *TraceEvent, name string) *CallInfo {
+	stack, err := NewStackIterator(evt.TID, t.debugData)
+	if err != nil || !stack.Next() || stack.retaddr == 0 {
+		return nil
+	}
+
+	t.lastCallID++
+	call := &pendingCall{
+		id:       t.lastCallID,
+		function: name,
+		fn:       stack.fn,
+		retaddr:  stack.retaddr,
+		cfa:      uintptr(stack.regs.CFA),
+		entered:  time.Now(),
+	}
+
+	err = t.addReturnBreakpoint(call.retaddr)
+	if err != nil {
+		return nil
+	}
+
+	t.calls[evt.TID] = append(t.calls[evt.TID], call)
+
+	return &CallInfo{
+		ID:       call.id,
+		Function: call.function,
+		Depth:    len(t.calls[evt.TID]),
+	}
+}
+
+// exitCall pops the call of the thread returning to 'evt.PC'.
+// Calls left by longjmp or exceptions are dropped too.
+func (t *Tracer) exitCall(evt *TraceEvent) *CallInfo {
+	sp, err := t.getSP()
+	if err != nil {
+		return nil
+	}
+
+	calls := t.calls[evt.TID]
+	for i := len(calls) - 1; i >= 0; i-- {
+		call := calls[i]
+		if call.cfa > sp {
+			break
+		}
+
+		if call.cfa != sp || call.retaddr != evt.PC {
+			continue
+		}
+
+		for _, abandoned := range calls[i:] {
+			t.removeReturnBreakpoint(abandoned.retaddr)
+		}
+
+		t.calls[evt.TID] = calls[:i]
+		if i == 0 {
+			delete(t.calls, evt.TID)
+		}
+
+		return &CallInfo{
+			ID:       call.id,
+			Function: call.function,
+			Depth:    i + 1,
+			Exit:     true,
+			Duration: time.Since(call.entered),
+			Return:   t.returnValue(call.fn),
+		}
+	}
+
+	return nil
+}
+
+// returnValue decodes the return value of the function that just returned
+func (t *Tracer) returnValue(fn *FunctionEntry) string {
+	if fn == nil {
+		return ""
+	}
+
+	sig, err := fn.Signature()
+	if err != nil {
+		return ""
+	}
+
+	regs, err := t.tid.GetRegs()
+	if err != nil {
+		return ""
+	}
+
+	value, _ := sig.decodeReturnValue(uint64(regs[RetRegNum]))
+	return value
+}
+
+func (t *Tracer) addReturnBreakpoint(addr uintptr) error {
+	if rbp, found := t.retBreakpoints[addr]; found {
+		rbp.refs++
+		return nil
+	}
+
+	rbp := &returnBreakpoint{refs: 1}
+
+	if _, exists := t.breakpoints[addr]; !exists {
+		err := t.SetBreakpoint(addr)
+		if err != nil {
+			return Error(err)
+		}
+		rbp.owned = true
+	}
+
+	t.retBreakpoints[addr] = rbp
+	return nil
+}
+
+func (t *Tracer) removeReturnBreakpoint(addr uintptr) {
+	rbp, found := t.retBreakpoints[addr]
+	if !found {
+		return
+	}
+
+	rbp.refs--
+	if rbp.refs > 0 {
+		return
+	}
+
+	delete(t.retBreakpoints, addr)
+	if rbp.owned {
+		t.RemoveBreakpoint(addr)
+	}
+}
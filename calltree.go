@@ -0,0 +1,129 @@
+package raztracer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CallNode is a function call recorded by a call tree capture
+type CallNode struct {
+	Function     string        `json:"function"`
+	Address      uintptr       `json:"address"`      // first executed instruction of the call
+	Instructions int           `json:"instructions"` // executed instructions including the callees
+	Duration     time.Duration `json:"duration"`     // includes the overhead of single-stepping
+	Returned     bool          `json:"returned"`     // false if the capture stopped before the call returned
+	Calls        []*CallNode   `json:"calls,omitempty"`
+}
+
+// CallTreeConfig contains the settings of a call tree capture
+type CallTreeConfig struct {
+	MaxSteps int // maximum number of executed instructions
+	MaxDepth int // calls deeper than this are executed but not recorded (unlimited if 0)
+}
+
+// CaptureCallTree single-steps the stopped thread until the current function returns
+// and records the tree of calls made in the meantime
+func (t *Tracer) CaptureCallTree(cfg CallTreeConfig) (*CallNode, error) {
+	if t.tid == 0 {
+		return nil, Errorf("no stopped thread")
+	}
+
+	if cfg.MaxSteps <= 0 {
+		return nil, Errorf("invalid number of steps: %d", cfg.MaxSteps)
+	}
+
+	pc, err := t.GetPC()
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	root := t.newCallNode(pc)
+	stack := []*CallNode{root}
+	started := []time.Time{time.Now()}
+	depth := 0 // calls entered below the deepest recorded node
+
+	for steps := 0; steps < cfg.MaxSteps; steps++ {
+		code := make([]byte, maxInstructionSize)
+		err := t.pid.PeekData(pc, code)
+		if err != nil {
+			return root, Error(err)
+		}
+
+		isCall, isReturn := classifyInstruction(code)
+
+		err = t.stepInstruction(pc)
+		if err != nil {
+			return root, Error(err)
+		}
+
+		for _, node := range stack {
+			node.Instructions++
+		}
+
+		pc, err = t.GetPC()
+		if err != nil {
+			return root, Error(err)
+		}
+
+		switch {
+		case isCall && depth == 0 && (cfg.MaxDepth == 0 || len(stack) <= cfg.MaxDepth):
+			node := t.newCallNode(pc)
+			caller := stack[len(stack)-1]
+			caller.Calls = append(caller.Calls, node)
+			stack = append(stack, node)
+			started = append(started, time.Now())
+
+		case isCall:
+			depth++
+
+		case isReturn && depth > 0:
+			depth--
+
+		case isReturn:
+			last := len(stack) - 1
+			stack[last].Returned = true
+			stack[last].Duration = time.Since(started[last])
+			if last == 0 {
+				return root, nil
+			}
+			stack, started = stack[:last], started[:last]
+		}
+	}
+
+	for i, node := range stack {
+		node.Duration = time.Since(started[i])
+	}
+
+	return root, nil
+}
+
+func (t *Tracer) newCallNode(pc uintptr) *CallNode {
+	node := &CallNode{Address: pc}
+	if fn, _ := t.debugData.GetFunctionFromPC(pc); fn != nil {
+		node.Function = fn.Name
+	} else {
+		node.Function = fmt.Sprintf("%#x", pc)
+	}
+	return node
+}
+
+// String returns the call tree as indented lines
+func (node *CallNode) String() string {
+	var sb strings.Builder
+	node.write(&sb, 0)
+	return sb.String()
+}
+
+func (node *CallNode) write(sb *strings.Builder, indent int) {
+	fmt.Fprintf(sb, "%s%s: %d instructions, %v", strings.Repeat("  ", indent),
+		node.Function, node.Instructions, node.Duration)
+	if !node.Returned {
+		sb.WriteString(" (not returned)")
+	}
+	sb.WriteString("\n")
+
+	for _, call := range node.Calls {
+		call.write(sb, indent+1)
+	}
+}
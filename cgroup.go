@@ -0,0 +1,136 @@
+package raztracer
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+// Cgroups returns the cgroup paths of the process (one for each hierarchy)
+func (pid Process) Cgroups() ([]string, error) {
+	file, err := os.Open(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return nil, Error(err)
+	}
+	defer file.Close()
+
+	var cgroups []string
+
+	// hierarchy-ID:controller-list:cgroup-path
+	// 0::/system.slice/nginx.service
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+
+		cgroups = append(cgroups, fields[2])
+	}
+
+	return cgroups, nil
+}
+
+// GetProcessesByCgroup returns the processes in the cgroup (or its descendants)
+func GetProcessesByCgroup(cgroup string) []Process {
+	cgroup = path.Clean("/" + cgroup)
+
+	processes := getCgroupProcs(cgroup)
+	if len(processes) > 0 {
+		return processes
+	}
+
+	return getProcessesByCgroupFunc(func(cg string) bool {
+		return cg == cgroup || strings.HasPrefix(cg, cgroup+"/")
+	})
+}
+
+// GetProcessesByUnit returns the processes that belong to the systemd unit
+// (".service" is assumed if the unit name has no suffix)
+func GetProcessesByUnit(unit string) []Process {
+	if !strings.Contains(unit, ".") {
+		unit += ".service"
+	}
+
+	return getProcessesByCgroupFunc(func(cg string) bool {
+		for _, elem := range strings.Split(cg, "/") {
+			if elem == unit {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// TraceCgroup traces every process in the cgroup (see TraceProcesses for partial failures)
+func TraceCgroup(ctx context.Context, cgroup string, cfg TraceConfig) (<-chan *TraceEvent, error) {
+	processes := GetProcessesByCgroup(cgroup)
+	if len(processes) == 0 {
+		return nil, Errorf("no processes in cgroup: %s", cgroup)
+	}
+
+	events, err := TraceProcesses(ctx, processes, cfg)
+	return events, Error(err)
+}
+
+// TraceUnit traces every process of the systemd unit (see TraceProcesses for partial failures)
+func TraceUnit(ctx context.Context, unit string, cfg TraceConfig) (<-chan *TraceEvent, error) {
+	processes := GetProcessesByUnit(unit)
+	if len(processes) == 0 {
+		return nil, Errorf("no processes in unit: %s", unit)
+	}
+
+	events, err := TraceProcesses(ctx, processes, cfg)
+	return events, Error(err)
+}
+
+func getProcessesByCgroupFunc(match func(cgroup string) bool) (results []Process) {
+	for _, pid := range GetRunningProcesses() {
+		cgroups, _ := pid.Cgroups()
+		for _, cg := range cgroups {
+			if match(cg) {
+				results = append(results, pid)
+				break
+			}
+		}
+	}
+	return
+}
+
+// getCgroupProcs reads the processes of the cgroup from the cgroup filesystem
+// (unified hierarchy first, then the systemd named hierarchy of cgroup v1)
+func getCgroupProcs(cgroup string) (results []Process) {
+	for _, hierarchy := range []string{"", "unified", "systemd"} {
+		dir := filepath.Join(cgroupRoot, hierarchy, cgroup)
+		if _, err := os.Stat(filepath.Join(dir, "cgroup.procs")); err != nil {
+			continue
+		}
+
+		filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || info.Name() != "cgroup.procs" {
+				return nil
+			}
+
+			procs, _ := ioutil.ReadFile(p)
+			for _, line := range strings.Fields(string(procs)) {
+				pid, err := strconv.Atoi(line)
+				if err == nil {
+					results = append(results, Process(pid))
+				}
+			}
+			return nil
+		})
+
+		return
+	}
+
+	return
+}
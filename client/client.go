@@ -0,0 +1,214 @@
+// Package client contains the typed representation of the JSON trace events
+// exported by raztracer. The types follow the versioned schema (see Schema)
+// instead of the tracer's internal structures, and the package has no dependency
+// on the tracer, so consumers can decode traces on any platform.
+package client
+
+//go:generate go run gen.go
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// SchemaVersion is the version of the trace schema understood by this package.
+// Fields are only added within a version, removed or changed fields bump it.
+const SchemaVersion = 1
+
+// Export is a recorded trace session
+type Export struct {
+	SchemaVersion int      `json:"schema_version"`
+	Session       *Session `json:"session"`
+	Events        []*Event `json:"events"`
+}
+
+// Session describes the traced process at the time of attaching
+type Session struct {
+	PID        int       `json:"pid"`
+	ProgName   string    `json:"progname"`
+	Executable string    `json:"exe"`
+	Cmdline    []string  `json:"cmdline"`
+	Environ    []string  `json:"environ"`
+	Cwd        string    `json:"cwd"`
+	BuildID    string    `json:"build_id,omitempty"`
+	MountNS    string    `json:"mount_ns,omitempty"`
+	Root       string    `json:"root,omitempty"`
+	Libraries  []Library `json:"libs"`
+	AttachTime time.Time `json:"attach_time"`
+}
+
+// Library is a shared library loaded by the traced process
+type Library struct {
+	Name       string `json:"name"`
+	Path       string `json:"path,omitempty"`
+	StaticBase uint64 `json:"static_base"`
+}
+
+// Event is a breakpoint hit, signal or other stop of a traced thread
+type Event struct {
+	Seq           uint64                    `json:"seq"`
+	Signal        Signal                    `json:"signal"`
+	Reason        StopReason                `json:"reason"`
+	PID           int                       `json:"pid"`
+	TID           int                       `json:"tid"`
+	IsBreakpoint  bool                      `json:"breakpoint"`
+	IsExitPath    bool                      `json:"exit_path"`
+	IsWatchpoint  bool                      `json:"watchpoint"`
+	WatchAddress  uint64                    `json:"watch_addr,omitempty"`
+	IsNewThread   bool                      `json:"new_thread"`
+	IsStopRequest bool                      `json:"stop_request"`
+	NewTID        int                       `json:"new_tid,omitempty"`
+	PC            uint64                    `json:"pc"`
+	Stack         *StackUsage               `json:"stack,omitempty"`
+	Warnings      []string                  `json:"warnings,omitempty"`
+	Registers     map[string]string         `json:"regs,omitempty"`
+	RegisterDiff  map[string]RegisterChange `json:"reg_diff,omitempty"`
+	Globals       []Reading                 `json:"globals"`
+	Backtrace     []*Frame                  `json:"backtrace"`
+	BacktraceEnd  BacktraceEnd              `json:"backtrace_end,omitempty"`
+	Unwind        *UnwindDiagnostic         `json:"unwind,omitempty"`
+	Step          *StepInfo                 `json:"step,omitempty"`
+	Call          *CallInfo                 `json:"call,omitempty"`
+	Python        []PythonThread            `json:"python,omitempty"`
+	Overhead      *Overhead                 `json:"overhead,omitempty"`
+	Latency       []LatencySample           `json:"latency,omitempty"`
+}
+
+// StopReason is the decoded wait status of the thread
+type StopReason struct {
+	Kind     StopKind    `json:"kind"`
+	Signal   Signal      `json:"signal,omitempty"`
+	Event    PtraceEvent `json:"event,omitempty"`
+	ExitCode int         `json:"exit_code,omitempty"`
+	CoreDump bool        `json:"core_dump,omitempty"`
+}
+
+// StackUsage contains the stack pointer of a thread relative to its stack mapping
+type StackUsage struct {
+	SP        uint64 `json:"sp"`
+	Base      uint64 `json:"base"`
+	Limit     uint64 `json:"limit"`
+	Depth     uint64 `json:"depth"`
+	Remaining uint64 `json:"remaining"`
+}
+
+// RegisterChange contains the old and new value of a changed register
+type RegisterChange struct {
+	Old string `json:"old,omitempty"`
+	New string `json:"new"`
+}
+
+// Reading is the location and value of a variable
+type Reading struct {
+	Name      string    `json:"name"`
+	Type      string    `json:"type,omitempty"`
+	Size      int64     `json:"size,omitempty"`
+	Location  string    `json:"location"`
+	Address   uint64    `json:"address,omitempty"`
+	Value     string    `json:"value"`
+	Raw       []byte    `json:"raw,omitempty"`
+	Error     string    `json:"error"`
+	Truncated bool      `json:"truncated,omitempty"`
+	Children  []Reading `json:"children,omitempty"`
+}
+
+// Frame is a function in the backtrace
+type Frame struct {
+	Function  string    `json:"function"`
+	Source    string    `json:"source"`
+	PC        string    `json:"pc"`
+	Module    string    `json:"module,omitempty"`
+	Offset    string    `json:"offset"`
+	CFA       string    `json:"cfa"`
+	FrameBase string    `json:"framebase"`
+	Variables []Reading `json:"variables"`
+}
+
+// UnwindDiagnostic explains why the unwinding of a stack stopped early
+type UnwindDiagnostic struct {
+	Reason  UnwindFailure `json:"reason"`
+	Frame   int           `json:"frame"`
+	PC      uint64        `json:"pc"`
+	CFA     uint64        `json:"cfa,omitempty"`
+	Address uint64        `json:"address,omitempty"`
+	NoFDE   bool          `json:"no_fde"`
+	Detail  string        `json:"detail,omitempty"`
+}
+
+// StepInfo describes how a step was executed
+type StepInfo struct {
+	Requested       StepMode `json:"requested"`
+	Mode            StepMode `json:"mode"`
+	Instructions    int      `json:"instructions"`
+	BudgetExhausted bool     `json:"budget_exhausted"`
+	From            string   `json:"from,omitempty"`
+	To              string   `json:"to,omitempty"`
+	Reason          string   `json:"reason,omitempty"`
+}
+
+// CallInfo correlates the entry and exit events of a function call
+type CallInfo struct {
+	ID       uint64        `json:"id"`
+	Function string        `json:"function"`
+	Depth    int           `json:"depth"`
+	Exit     bool          `json:"exit"`
+	Duration time.Duration `json:"duration,omitempty"`
+	Return   string        `json:"return,omitempty"`
+}
+
+// PythonThread contains the Python stack of an interpreter thread state
+type PythonThread struct {
+	ThreadState uint64         `json:"thread_state"`
+	Frames      []*PythonFrame `json:"frames"`
+}
+
+// PythonFrame is a frame of the Python interpreter stack
+type PythonFrame struct {
+	Function string `json:"function"`
+	Filename string `json:"filename"`
+	Line     int    `json:"line"`
+}
+
+// Overhead contains the cost of collecting the event
+type Overhead struct {
+	Collection  time.Duration `json:"collection"`
+	Unwind      time.Duration `json:"unwind"`
+	Variables   time.Duration `json:"variables"`
+	PtraceCalls uint64        `json:"ptrace_calls"`
+}
+
+// LatencySample is the duration measured by a latency probe
+type LatencySample struct {
+	Probe    string        `json:"probe"`
+	Key      string        `json:"key,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Decode reads an exported trace. Unknown fields are ignored, so traces written by
+// newer tracers of the same schema version can be decoded.
+func Decode(r io.Reader) (*Export, error) {
+	var export Export
+	err := json.NewDecoder(r).Decode(&export)
+	if err != nil {
+		return nil, err
+	}
+
+	if export.SchemaVersion > SchemaVersion {
+		return nil, fmt.Errorf("unsupported schema version: %d (supported: %d)", export.SchemaVersion, SchemaVersion)
+	}
+
+	return &export, nil
+}
+
+// DecodeEvent decodes a single event, e.g. a line of a JSON event stream
+func DecodeEvent(data []byte) (*Event, error) {
+	var evt Event
+	err := json.Unmarshal(data, &evt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &evt, nil
+}
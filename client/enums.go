@@ -0,0 +1,157 @@
+package client
+
+import (
+	"strconv"
+)
+
+// Signal is a Linux signal number
+type Signal int
+
+// Signals as numbered on Linux x86
+const (
+	SIGHUP    Signal = 1
+	SIGINT    Signal = 2
+	SIGQUIT   Signal = 3
+	SIGILL    Signal = 4
+	SIGTRAP   Signal = 5
+	SIGABRT   Signal = 6
+	SIGBUS    Signal = 7
+	SIGFPE    Signal = 8
+	SIGKILL   Signal = 9
+	SIGUSR1   Signal = 10
+	SIGSEGV   Signal = 11
+	SIGUSR2   Signal = 12
+	SIGPIPE   Signal = 13
+	SIGALRM   Signal = 14
+	SIGTERM   Signal = 15
+	SIGCHLD   Signal = 17
+	SIGCONT   Signal = 18
+	SIGSTOP   Signal = 19
+	SIGTSTP   Signal = 20
+	SIGTTIN   Signal = 21
+	SIGTTOU   Signal = 22
+	SIGURG    Signal = 23
+	SIGXCPU   Signal = 24
+	SIGXFSZ   Signal = 25
+	SIGVTALRM Signal = 26
+	SIGPROF   Signal = 27
+	SIGWINCH  Signal = 28
+	SIGSYS    Signal = 31
+)
+
+var signalNames = map[Signal]string{
+	SIGHUP:    "SIGHUP",
+	SIGINT:    "SIGINT",
+	SIGQUIT:   "SIGQUIT",
+	SIGILL:    "SIGILL",
+	SIGTRAP:   "SIGTRAP",
+	SIGABRT:   "SIGABRT",
+	SIGBUS:    "SIGBUS",
+	SIGFPE:    "SIGFPE",
+	SIGKILL:   "SIGKILL",
+	SIGUSR1:   "SIGUSR1",
+	SIGSEGV:   "SIGSEGV",
+	SIGUSR2:   "SIGUSR2",
+	SIGPIPE:   "SIGPIPE",
+	SIGALRM:   "SIGALRM",
+	SIGTERM:   "SIGTERM",
+	SIGCHLD:   "SIGCHLD",
+	SIGCONT:   "SIGCONT",
+	SIGSTOP:   "SIGSTOP",
+	SIGTSTP:   "SIGTSTP",
+	SIGTTIN:   "SIGTTIN",
+	SIGTTOU:   "SIGTTOU",
+	SIGURG:    "SIGURG",
+	SIGXCPU:   "SIGXCPU",
+	SIGXFSZ:   "SIGXFSZ",
+	SIGVTALRM: "SIGVTALRM",
+	SIGPROF:   "SIGPROF",
+	SIGWINCH:  "SIGWINCH",
+	SIGSYS:    "SIGSYS",
+}
+
+// String returns the name of the signal (e.g. SIGSEGV)
+func (sig Signal) String() string {
+	if name, found := signalNames[sig]; found {
+		return name
+	}
+
+	return "SIG" + strconv.Itoa(int(sig))
+}
+
+// StopKind is the kind of state change of a thread
+type StopKind string
+
+// Stop kinds
+const (
+	StopSignalDelivery StopKind = "signal"
+	StopGroup          StopKind = "group_stop"
+	StopPtraceEvent    StopKind = "ptrace_event"
+	StopExited         StopKind = "exited"
+	StopKilled         StopKind = "killed"
+	StopContinued      StopKind = "continued"
+)
+
+// PtraceEvent is the kind of a ptrace event stop
+type PtraceEvent string
+
+// Ptrace events
+const (
+	EventClone     PtraceEvent = "clone"
+	EventFork      PtraceEvent = "fork"
+	EventVfork     PtraceEvent = "vfork"
+	EventVforkDone PtraceEvent = "vfork_done"
+	EventExec      PtraceEvent = "exec"
+	EventExit      PtraceEvent = "exit"
+	EventSeccomp   PtraceEvent = "seccomp"
+	EventStop      PtraceEvent = "stop"
+)
+
+// BacktraceEnd tells why a backtrace ended
+type BacktraceEnd string
+
+// Backtrace ends
+const (
+	BacktraceComplete      BacktraceEnd = "complete"
+	BacktraceTruncated     BacktraceEnd = "truncated"
+	BacktraceUnwindFailure BacktraceEnd = "unwind_failure"
+)
+
+// UnwindFailure is the reason of an early terminated backtrace
+type UnwindFailure string
+
+// Unwind failures
+const (
+	UnwindNoFunction        UnwindFailure = "no_function"
+	UnwindUndefinedCFA      UnwindFailure = "undefined_cfa"
+	UnwindUndefinedRetAddr  UnwindFailure = "undefined_return_address"
+	UnwindBadReturnAddress  UnwindFailure = "bad_return_address"
+	UnwindUnreadableMemory  UnwindFailure = "unreadable_memory"
+	UnwindUnsupportedRule   UnwindFailure = "unsupported_rule"
+	UnwindExpressionFailure UnwindFailure = "expression_failure"
+)
+
+// StepMode is the granularity of a step
+type StepMode string
+
+// Step modes
+const (
+	StepModeLine        StepMode = "line"
+	StepModeColumn      StepMode = "column"
+	StepModeInstruction StepMode = "instruction"
+)
+
+// enums contains the possible values of the string enum types for the schema
+var enums = map[string][]interface{}{
+	"StopKind":      {StopSignalDelivery, StopGroup, StopPtraceEvent, StopExited, StopKilled, StopContinued},
+	"PtraceEvent":   {EventClone, EventFork, EventVfork, EventVforkDone, EventExec, EventExit, EventSeccomp, EventStop},
+	"BacktraceEnd":  {BacktraceComplete, BacktraceTruncated, BacktraceUnwindFailure},
+	"UnwindFailure": {UnwindNoFunction, UnwindUndefinedCFA, UnwindUndefinedRetAddr, UnwindBadReturnAddress, UnwindUnreadableMemory, UnwindUnsupportedRule, UnwindExpressionFailure},
+	"StepMode":      {StepModeLine, StepModeColumn, StepModeInstruction},
+}
+
+// enumFallbacks are the patterns of the values reported for unknown kernel states
+var enumFallbacks = map[string]string{
+	"StopKind":    "^unknown ",
+	"PtraceEvent": "^event[0-9]+$",
+}
@@ -0,0 +1,23 @@
+// +build ignore
+
+// gen writes the JSON schema of the trace exports to schema.json
+package main
+
+import (
+	"io/ioutil"
+	"log"
+
+	"github.com/razzie/raztracer/client"
+)
+
+func main() {
+	schema, err := client.Schema()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = ioutil.WriteFile("schema.json", append(schema, '\n'), 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,131 @@
+package client
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// SchemaID is the identifier of the JSON schema of the current schema version
+const SchemaID = "https://github.com/razzie/raztracer/client/schema.json"
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	signalType   = reflect.TypeOf(Signal(0))
+	bytesType    = reflect.TypeOf([]byte(nil))
+)
+
+// Schema returns the JSON schema (draft-07) of exported traces generated from the types of the package
+func Schema() ([]byte, error) {
+	gen := &schemaGenerator{definitions: make(map[string]interface{})}
+	root := gen.structSchema(reflect.TypeOf(Export{}))
+	root["$schema"] = "http://json-schema.org/draft-07/schema#"
+	root["$id"] = SchemaID
+	root["title"] = "raztracer trace export"
+	root["properties"].(map[string]interface{})["schema_version"] = map[string]interface{}{
+		"type":    "integer",
+		"minimum": 1,
+		"maximum": SchemaVersion,
+	}
+	root["definitions"] = gen.definitions
+
+	return json.MarshalIndent(root, "", "  ")
+}
+
+type schemaGenerator struct {
+	definitions map[string]interface{}
+}
+
+func (gen *schemaGenerator) typeSchema(t reflect.Type) map[string]interface{} {
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case durationType:
+		return map[string]interface{}{"type": "integer", "description": "duration in nanoseconds"}
+	case signalType:
+		return map[string]interface{}{"type": "integer", "minimum": 0, "description": "Linux signal number, 0 if none"}
+	case bytesType:
+		return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+	}
+
+	if values, isEnum := enums[t.Name()]; isEnum {
+		enum := map[string]interface{}{"type": "string", "enum": values}
+		if pattern, found := enumFallbacks[t.Name()]; found {
+			return map[string]interface{}{"anyOf": []interface{}{enum, map[string]interface{}{"type": "string", "pattern": pattern}}}
+		}
+		return enum
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return gen.typeSchema(t.Elem())
+
+	case reflect.Struct:
+		if _, found := gen.definitions[t.Name()]; !found {
+			gen.definitions[t.Name()] = nil // placeholder for recursive types
+			gen.definitions[t.Name()] = gen.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/definitions/" + t.Name()}
+
+	case reflect.Slice:
+		return map[string]interface{}{
+			"type":  []string{"array", "null"},
+			"items": gen.typeSchema(t.Elem()),
+		}
+
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": gen.typeSchema(t.Elem()),
+		}
+
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	}
+
+	return map[string]interface{}{}
+}
+
+func (gen *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := make([]string, 0)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("json"), ",")
+		if len(tag[0]) == 0 || tag[0] == "-" {
+			continue
+		}
+
+		schema := gen.typeSchema(field.Type)
+		omitempty := len(tag) > 1 && tag[1] == "omitempty"
+		if field.Type.Kind() == reflect.Ptr && !omitempty {
+			schema = map[string]interface{}{"anyOf": []interface{}{schema, map[string]interface{}{"type": "null"}}}
+		}
+
+		properties[tag[0]] = schema
+		if !omitempty {
+			required = append(required, tag[0])
+		}
+	}
+
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
@@ -13,17 +13,22 @@ const (
 // ByteOrder is initialized with the byte order of the current architecture
 var ByteOrder binary.ByteOrder
 
-// ReadAddress reads a pointer from a byte slice
+// ReadAddress reads a pointer from a byte slice in the byte order of the current architecture
 func ReadAddress(data []byte) uintptr {
+	return ReadAddressWithOrder(data, ByteOrder)
+}
+
+// ReadAddressWithOrder reads a pointer from a byte slice in the byte order of the target
+func ReadAddressWithOrder(data []byte, order binary.ByteOrder) uintptr {
 	if len(data) < int(SizeofPtr) {
 		return 0
 	}
 
 	if SizeofPtr == 4 {
-		return uintptr(ByteOrder.Uint32(data))
+		return uintptr(order.Uint32(data))
 	}
 
-	return uintptr(ByteOrder.Uint64(data))
+	return uintptr(order.Uint64(data))
 }
 
 func init() {
@@ -0,0 +1,193 @@
+package raztracer
+
+import (
+	"bufio"
+	"debug/elf"
+	"encoding/binary"
+	"os"
+	"strings"
+)
+
+const (
+	elfHeaderSize  = 64
+	progHeaderSize = 56
+	notePageAlign  = 0x1000
+	coreChunkSize  = 1 << 20 // memory is copied in chunks, so huge mappings are never buffered whole
+)
+
+// WriteCore writes an ELF core file of the stopped process to 'path'
+// containing the registers of every thread and the readable memory regions
+func (t *Tracer) WriteCore(path string) error {
+	threads, err := t.pid.Threads()
+	if err != nil {
+		return Error(err)
+	}
+
+	regions, err := t.pid.MemRegions()
+	if err != nil {
+		return Error(err)
+	}
+
+	var loads []MemRegion
+	for _, region := range regions {
+		if !strings.HasPrefix(region.Permissions, "r") ||
+			region.Pathname == "[vvar]" || region.Pathname == "[vsyscall]" {
+			continue
+		}
+
+		loads = append(loads, region)
+	}
+
+	notes := make([]byte, 0)
+	for _, tid := range threads {
+		regs, err := tid.GetRegs()
+		if err != nil {
+			continue
+		}
+
+		notes = append(notes, newPrstatusNote(tid, regs)...)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return Error(err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	order := ByteOrder
+
+	phnum := 1 + len(loads)
+	noteOffset := uint64(elfHeaderSize + phnum*progHeaderSize)
+	dataOffset := alignUp(noteOffset+uint64(len(notes)), notePageAlign)
+
+	writeCoreHeader(w, order, phnum)
+
+	// PT_NOTE
+	writeProgHeader(w, order, elf.PT_NOTE, 0, noteOffset, 0, uint64(len(notes)), 0, 4)
+
+	// PT_LOAD for every region
+	offset := dataOffset
+	for _, region := range loads {
+		size := uint64(region.Address[1] - region.Address[0])
+		writeProgHeader(w, order, elf.PT_LOAD, regionFlags(region.Permissions),
+			offset, uint64(region.Address[0]), size, size, notePageAlign)
+		offset += size
+	}
+
+	w.Write(notes)
+	w.Write(make([]byte, dataOffset-noteOffset-uint64(len(notes))))
+
+	var errors []error
+	chunk := make([]byte, coreChunkSize)
+	for _, region := range loads {
+		failed := false
+		for addr := region.Address[0]; addr < region.Address[1]; addr += coreChunkSize {
+			data := chunk
+			if remaining := region.Address[1] - addr; remaining < coreChunkSize {
+				data = chunk[:remaining]
+			}
+
+			err := t.pid.ReadMemory(addr, data)
+			if err != nil {
+				// unreadable chunks are written as zeros
+				for i := range data {
+					data[i] = 0
+				}
+				failed = true
+			}
+			w.Write(data)
+		}
+
+		if failed {
+			errors = append(errors, Errorf("failed to read region %#x-%#x", region.Address[0], region.Address[1]))
+		}
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return Error(err)
+	}
+
+	if len(errors) == len(loads) && len(loads) > 0 {
+		return MergeErrors(errors)
+	}
+
+	return nil
+}
+
+func writeCoreHeader(w *bufio.Writer, order binary.ByteOrder, phnum int) {
+	ident := [elf.EI_NIDENT]byte{0x7f, 'E', 'L', 'F',
+		byte(elf.ELFCLASS64), byte(elf.ELFDATA2LSB), byte(elf.EV_CURRENT)}
+	if order == binary.BigEndian {
+		ident[elf.EI_DATA] = byte(elf.ELFDATA2MSB)
+	}
+
+	w.Write(ident[:])
+	binary.Write(w, order, uint16(elf.ET_CORE))
+	binary.Write(w, order, uint16(coreMachine))
+	binary.Write(w, order, uint32(elf.EV_CURRENT))
+	binary.Write(w, order, uint64(0))              // entry
+	binary.Write(w, order, uint64(elfHeaderSize))  // phoff
+	binary.Write(w, order, uint64(0))              // shoff
+	binary.Write(w, order, uint32(0))              // flags
+	binary.Write(w, order, uint16(elfHeaderSize))  // ehsize
+	binary.Write(w, order, uint16(progHeaderSize)) // phentsize
+	binary.Write(w, order, uint16(phnum))
+	binary.Write(w, order, uint16(0)) // shentsize
+	binary.Write(w, order, uint16(0)) // shnum
+	binary.Write(w, order, uint16(0)) // shstrndx
+}
+
+func writeProgHeader(w *bufio.Writer, order binary.ByteOrder, typ elf.ProgType, flags elf.ProgFlag,
+	offset, vaddr, filesz, memsz, align uint64) {
+	binary.Write(w, order, uint32(typ))
+	binary.Write(w, order, uint32(flags))
+	binary.Write(w, order, offset)
+	binary.Write(w, order, vaddr)
+	binary.Write(w, order, uint64(0)) // paddr
+	binary.Write(w, order, filesz)
+	binary.Write(w, order, memsz)
+	binary.Write(w, order, align)
+}
+
+// newPrstatusNote returns an NT_PRSTATUS note of the thread
+func newPrstatusNote(tid Process, regs []uint) []byte {
+	desc := make([]byte, prstatusSize)
+	ByteOrder.PutUint32(desc[prstatusPIDOffset:], uint32(tid))
+
+	for i, reg := range regs {
+		offset := prstatusRegOffset + i*int(SizeofPtr)
+		if offset+int(SizeofPtr) > len(desc) {
+			break
+		}
+		ByteOrder.PutUint64(desc[offset:], uint64(reg))
+	}
+
+	name := []byte("CORE\x00\x00\x00\x00") // padded to 8 bytes
+	note := make([]byte, 12, 12+len(name)+len(desc))
+	ByteOrder.PutUint32(note[0:], 5) // namesz
+	ByteOrder.PutUint32(note[4:], uint32(len(desc)))
+	ByteOrder.PutUint32(note[8:], uint32(elf.NT_PRSTATUS))
+	note = append(note, name...)
+	note = append(note, desc...)
+	return note
+}
+
+func regionFlags(perms string) elf.ProgFlag {
+	var flags elf.ProgFlag
+	if strings.Contains(perms, "r") {
+		flags |= elf.PF_R
+	}
+	if strings.Contains(perms, "w") {
+		flags |= elf.PF_W
+	}
+	if strings.Contains(perms, "x") {
+		flags |= elf.PF_X
+	}
+	return flags
+}
+
+func alignUp(value, align uint64) uint64 {
+	return (value + align - 1) &^ (align - 1)
+}
@@ -0,0 +1,82 @@
+package raztracer
+
+import (
+	"bytes"
+	"debug/elf"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestWriteCore(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	cmd := startTracee(t, 50)
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+
+	dir, err := ioutil.TempDir("", "raztracer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tracer, err := NewTracer(cmd.Process.Pid)
+	skipIfNotPermitted(t, err)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tracer.Detach()
+
+	path := filepath.Join(dir, "core")
+	if err := tracer.WriteCore(path); err != nil {
+		t.Fatal(err)
+	}
+
+	core, err := elf.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer core.Close()
+
+	if core.Type != elf.ET_CORE {
+		t.Fatalf("expected a core file, got %v", core.Type)
+	}
+
+	loads := 0
+	for _, prog := range core.Progs {
+		if prog.Type != elf.PT_LOAD || prog.Filesz == 0 {
+			continue
+		}
+		loads++
+
+		// the end of the region is in the last chunk
+		size := uint64(64)
+		if prog.Filesz < size {
+			size = prog.Filesz
+		}
+		offset := prog.Filesz - size
+
+		expected := make([]byte, size)
+		if tracer.pid.ReadMemory(uintptr(prog.Vaddr+offset), expected) != nil {
+			continue
+		}
+
+		data := make([]byte, size)
+		if _, err := prog.ReadAt(data, int64(offset)); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, expected) {
+			t.Errorf("the end of region %#x differs from the process memory", prog.Vaddr)
+		}
+	}
+
+	if loads == 0 {
+		t.Error("the core file has no memory regions")
+	}
+}
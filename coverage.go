@@ -0,0 +1,237 @@
+package raztracer
+
+import (
+	"context"
+	"regexp"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// CoveredFunction is a function executed during a coverage window
+type CoveredFunction struct {
+	Name     string    `json:"name"`
+	Module   string    `json:"module,omitempty"`
+	Address  uintptr   `json:"address"`
+	Thread   Process   `json:"thread"` // thread of the first call
+	FirstHit time.Time `json:"first_hit"`
+}
+
+// CoverageReport contains the functions executed during a coverage window
+type CoverageReport struct {
+	Start       time.Time         `json:"start"`
+	End         time.Time         `json:"end"`
+	Planted     int               `json:"planted"` // number of functions with coverage breakpoints
+	Failed      int               `json:"failed"`  // functions whose breakpoint could not be set
+	Executed    []CoveredFunction `json:"executed"`
+	NotExecuted []string          `json:"not_executed"`
+}
+
+// Ratio returns the executed fraction of the planted functions
+func (r *CoverageReport) Ratio() float64 {
+	if r.Planted == 0 {
+		return 0
+	}
+
+	return float64(len(r.Executed)) / float64(r.Planted)
+}
+
+// coveragePollInterval is how often MeasureCoverage checks the end of the window
+const coveragePollInterval = 100 * time.Millisecond
+
+type coverageProbe struct {
+	fn    *FunctionEntry
+	owned bool // the breakpoint was set for coverage only
+	hit   *CoveredFunction
+}
+
+type coverage struct {
+	start  time.Time
+	probes map[uintptr]*coverageProbe
+	failed int
+}
+
+// StartCoverage plants one-shot breakpoints at the entry of every function matching
+// 'pattern' (every function if empty). The breakpoints are removed at their first hit
+// and the process is continued automatically. The process has to be stopped.
+func (t *Tracer) StartCoverage(pattern string) (int, error) {
+	if t.coverage != nil {
+		return 0, Errorf("coverage is already running")
+	}
+
+	var re *regexp.Regexp
+	if len(pattern) > 0 {
+		var err error
+		re, err = regexp.Compile(pattern)
+		if err != nil {
+			return 0, Error(err)
+		}
+	}
+
+	t.CheckBinary()
+
+	cov := &coverage{
+		start:  time.Now(),
+		probes: make(map[uintptr]*coverageProbe),
+	}
+
+	for _, fn := range t.debugData.functions {
+		if fn.LowPC == 0 || (re != nil && !re.MatchString(fn.Name)) {
+			continue
+		}
+
+		addr := fn.LowPC + fn.StaticBase
+		if _, planted := cov.probes[addr]; planted {
+			continue
+		}
+
+		probe := &coverageProbe{fn: fn}
+		if _, exists := t.breakpoints[addr]; !exists {
+			if t.SetBreakpoint(addr) != nil {
+				cov.failed++
+				continue
+			}
+			probe.owned = true
+		}
+
+		cov.probes[addr] = probe
+	}
+
+	t.coverage = cov
+	return len(cov.probes), nil
+}
+
+// StopCoverage removes the remaining coverage breakpoints and returns the report.
+// The process has to be stopped.
+func (t *Tracer) StopCoverage() (*CoverageReport, error) {
+	if t.coverage == nil {
+		return nil, Errorf("coverage is not running")
+	}
+
+	report := t.CoverageReport()
+
+	var errors []error
+	for addr, probe := range t.coverage.probes {
+		if probe.owned && probe.hit == nil {
+			err := t.RemoveBreakpoint(addr)
+			if err != nil {
+				errors = append(errors, err)
+			}
+		}
+	}
+
+	t.coverage = nil
+	if len(errors) > 0 {
+		return report, MergeErrors(errors)
+	}
+	return report, nil
+}
+
+// CoverageReport returns the functions executed since StartCoverage (nil if not running)
+func (t *Tracer) CoverageReport() *CoverageReport {
+	cov := t.coverage
+	if cov == nil {
+		return nil
+	}
+
+	report := &CoverageReport{
+		Start:       cov.start,
+		End:         time.Now(),
+		Planted:     len(cov.probes),
+		Failed:      cov.failed,
+		Executed:    make([]CoveredFunction, 0),
+		NotExecuted: make([]string, 0),
+	}
+
+	for _, probe := range cov.probes {
+		if probe.hit != nil {
+			report.Executed = append(report.Executed, *probe.hit)
+		} else {
+			report.NotExecuted = append(report.NotExecuted, probe.fn.Name)
+		}
+	}
+
+	sort.Slice(report.Executed, func(i, j int) bool {
+		return report.Executed[i].FirstHit.Before(report.Executed[j].FirstHit)
+	})
+	sort.Strings(report.NotExecuted)
+
+	return report
+}
+
+// coverageHit records the first hit of a coverage breakpoint.
+// Returns true if the breakpoint was only set for coverage and the event should be skipped.
+func (t *Tracer) coverageHit(evt *TraceEvent) bool {
+	if t.coverage == nil || !evt.IsBreakpoint {
+		return false
+	}
+
+	probe, found := t.coverage.probes[evt.PC]
+	if !found || probe.hit != nil {
+		return false
+	}
+
+	probe.hit = &CoveredFunction{
+		Name:     probe.fn.Name,
+		Module:   probe.fn.modulePath(),
+		Address:  evt.PC,
+		Thread:   evt.TID,
+		FirstHit: time.Now(),
+	}
+
+	if !probe.owned {
+		return false
+	}
+
+	t.RemoveBreakpoint(evt.PC)
+	return true
+}
+
+// MeasureCoverage attaches to the process, records which functions matching 'pattern'
+// are executed during 'window' (or until the context is done), then detaches
+func MeasureCoverage(ctx context.Context, pid int, pattern string, window time.Duration, filter *LibraryFilter) (*CoverageReport, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	t, err := NewTracerWithFilter(pid, filter)
+	if err != nil {
+		return nil, Error(err)
+	}
+	defer t.Detach()
+
+	_, err = t.StartCoverage(pattern)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, window)
+	defer cancel()
+
+	t.Run()
+
+	var errors []error
+	for ctx.Err() == nil {
+		_, err := t.WaitForEvent(coveragePollInterval)
+		if err != nil {
+			errors = append(errors, Error(err))
+			break
+		}
+	}
+
+	// the breakpoints can only be removed from a stopped process
+	err = t.Interrupt()
+	if err != nil {
+		errors = append(errors, Error(err))
+	}
+
+	// the probes are removed even if waiting failed
+	report, err := t.StopCoverage()
+	if err != nil {
+		errors = append(errors, Error(err))
+	}
+
+	if len(errors) > 0 {
+		return report, MergeErrors(errors)
+	}
+	return report, nil
+}
@@ -0,0 +1,29 @@
+package raztracer
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMeasureCoverage(t *testing.T) {
+	cmd := startTracee(t, 100)
+	defer cmd.Process.Kill()
+
+	report, err := MeasureCoverage(context.Background(), cmd.Process.Pid, "^(traced|on_usr1)$", 300*time.Millisecond, nil)
+	skipIfNotPermitted(t, err)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Planted != 2 || len(report.Executed) != 1 || report.Executed[0].Name != "traced" {
+		t.Errorf("expected only traced() to be executed, got %+v", report)
+	}
+	if len(report.NotExecuted) != 1 || report.NotExecuted[0] != "on_usr1" {
+		t.Errorf("expected on_usr1() not to be executed, got %v", report.NotExecuted)
+	}
+
+	if status := waitTracee(t, cmd); status != 0 {
+		t.Errorf("the test program handled %d SIGUSR1 and %d SIGCONT, expected none", status%16, status/16)
+	}
+}
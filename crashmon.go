@@ -0,0 +1,207 @@
+package raztracer
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// DefaultRestartTimeout is the default time to wait for a crashed process to be restarted
+const DefaultRestartTimeout = time.Minute
+
+// DefaultFatalSignals contains the signals reported as crashes if no signals are configured
+var DefaultFatalSignals = []syscall.Signal{
+	syscall.SIGSEGV,
+	syscall.SIGBUS,
+	syscall.SIGILL,
+	syscall.SIGFPE,
+	syscall.SIGABRT,
+	syscall.SIGSYS,
+}
+
+// CrashMonitorConfig contains the settings of a crash monitor
+type CrashMonitorConfig struct {
+	Signals        []syscall.Signal // signals reported as crashes (DefaultFatalSignals if empty)
+	Libraries      *LibraryFilter   // shared libraries to load debug info from (all if nil)
+	Depth          int              // maximum number of backtrace frames (DefaultBacktraceDepth if 0)
+	CoreDir        string           // a core file is written to this directory for every crash if not empty
+	Rearm          bool             // attach to the restarted process after a crash
+	RestartTimeout time.Duration    // time to wait for the restarted process (DefaultRestartTimeout if 0)
+}
+
+// CrashReport contains the snapshot of a crashed process
+type CrashReport struct {
+	Session  *SessionInfo `json:"session"`
+	Event    *TraceEvent  `json:"event"`
+	CoreFile string       `json:"core_file,omitempty"`
+	Time     time.Time    `json:"time"`
+	Errors   []string     `json:"errors,omitempty"` // parts of the snapshot that couldn't be captured
+}
+
+// MonitorCrashes attaches to the process without setting breakpoints and sleeps until
+// a fatal signal arrives. The snapshot of the crash is passed to 'reportFunc', then the
+// signal is delivered to the process. If Rearm is set, the monitor attaches to the
+// restarted process (same name and executable) and continues monitoring.
+// It returns when the context is done, or when the process is gone and isn't re-armed.
+func MonitorCrashes(ctx context.Context, pid int, cfg CrashMonitorConfig, reportFunc func(*CrashReport)) error {
+	if len(cfg.Signals) == 0 {
+		cfg.Signals = DefaultFatalSignals
+	}
+	if cfg.RestartTimeout <= 0 {
+		cfg.RestartTimeout = DefaultRestartTimeout
+	}
+
+	for {
+		session, crashTime, err := monitorProcess(ctx, Process(pid), &cfg, reportFunc)
+		if err != nil || ctx.Err() != nil || crashTime.IsZero() || !cfg.Rearm {
+			return Error(err)
+		}
+
+		restarted, err := waitForRestart(ctx, session, Process(pid), crashTime, cfg.RestartTimeout)
+		if err != nil {
+			return Error(err)
+		}
+
+		pid = int(restarted)
+	}
+}
+
+// monitorProcess monitors the process until it terminates or the context is done.
+// It returns the time of the last crash, or zero time if the process didn't crash.
+func monitorProcess(ctx context.Context, pid Process, cfg *CrashMonitorConfig, reportFunc func(*CrashReport)) (*SessionInfo, time.Time, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	t, err := NewTracerWithFilter(int(pid), cfg.Libraries)
+	if err != nil {
+		return nil, time.Time{}, Error(err)
+	}
+
+	t.SetBacktraceDepth(cfg.Depth)
+	session := t.GetSessionInfo()
+
+	t.Run()
+
+	// the blocking wait is interrupted by a stop request when the context is done
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			t.RequestStop()
+		case <-done:
+		}
+	}()
+
+	var crashTime time.Time
+
+	for {
+		evt, err := t.waitForStop(WaitForever)
+		if err != nil {
+			// the process is gone
+			return session, crashTime, nil
+		} else if evt == nil {
+			continue
+		}
+
+		if evt.IsStopRequest && ctx.Err() != nil {
+			t.Detach()
+			return session, crashTime, nil
+		}
+
+		if !cfg.isFatal(evt) {
+			continue // the signal is delivered to the process
+		}
+
+		report := t.captureCrash(evt, session, cfg.CoreDir)
+		crashTime = report.Time
+		reportFunc(report)
+	}
+}
+
+// captureCrash collects the snapshot of the crashed process
+func (t *Tracer) captureCrash(evt *TraceEvent, session *SessionInfo, coreDir string) *CrashReport {
+	t.stats.Events++
+	evt.Seq = t.stats.Events
+
+	report := &CrashReport{
+		Session: session,
+		Event:   evt,
+		Time:    time.Now(),
+	}
+
+	err := t.readEventData(evt)
+	if err != nil {
+		report.Errors = append(report.Errors, errorMessage(err))
+	}
+
+	if len(coreDir) > 0 {
+		name := fmt.Sprintf("core.%s.%d.%d", session.ProgName, t.pid, report.Time.Unix())
+		path := filepath.Join(coreDir, name)
+
+		err := t.WriteCore(path)
+		if err != nil {
+			report.Errors = append(report.Errors, errorMessage(err))
+		} else {
+			report.CoreFile = path
+		}
+	}
+
+	return report
+}
+
+func (cfg *CrashMonitorConfig) isFatal(evt *TraceEvent) bool {
+	if evt.IsBreakpoint || evt.IsWatchpoint || evt.IsStopRequest || evt.IsNewThread {
+		return false
+	}
+
+	for _, sig := range cfg.Signals {
+		if evt.Reason.IsSignal(sig) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// waitForRestart polls the running processes until a process with the same name
+// and executable as the crashed one is started. Other instances that were already
+// running when the process crashed are ignored.
+func waitForRestart(ctx context.Context, session *SessionInfo, crashed Process, crashTime time.Time, timeout time.Duration) (Process, error) {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	deadline := time.After(timeout)
+
+	// the processes running now may have been started after the crash, so they are checked by start time
+	seen := map[Process]bool{crashed: true}
+	for _, pid := range GetProcessesByName(session.ProgName) {
+		seen[pid] = true
+	}
+
+	for {
+		for _, pid := range GetProcessesByName(session.ProgName) {
+			if seen[pid] {
+				started, err := pid.startTime()
+				if err != nil || started.Before(crashTime) {
+					continue
+				}
+			}
+
+			if exe, err := pid.Executable(); err == nil && exe == session.Executable {
+				return pid, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, Error(ctx.Err())
+		case <-deadline:
+			return 0, Errorf("%s was not restarted in %v", session.ProgName, timeout)
+		case <-ticker.C:
+		}
+	}
+}
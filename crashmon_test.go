@@ -0,0 +1,44 @@
+package raztracer
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWaitForRestart(t *testing.T) {
+	old := startTracee(t, 300)
+	defer func() {
+		old.Process.Kill()
+		old.Wait()
+	}()
+
+	exe, err := Process(old.Process.Pid).Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := &SessionInfo{ProgName: filepath.Base(exe), Executable: exe}
+
+	time.Sleep(50 * time.Millisecond)
+	crashTime := time.Now()
+
+	// the instance that was running before the crash is not the restarted one
+	if pid, err := waitForRestart(context.Background(), session, 0, crashTime, 700*time.Millisecond); err == nil {
+		t.Fatalf("%d was reported as restarted", pid)
+	}
+
+	restarted := startTracee(t, 300)
+	defer func() {
+		restarted.Process.Kill()
+		restarted.Wait()
+	}()
+
+	pid, err := waitForRestart(context.Background(), session, 0, crashTime, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int(pid) != restarted.Process.Pid {
+		t.Errorf("expected %d to be restarted, got %d", restarted.Process.Pid, pid)
+	}
+}
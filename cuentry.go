@@ -88,12 +88,22 @@ func (cu *CUEntry) GetFunctions() ([]*FunctionEntry, error) {
 	var errors []error
 	funcs := make([]*FunctionEntry, 0)
 
+	names, err := cu.qualifiedNames()
+	if err != nil {
+		errors = append(errors, err)
+	}
+
 	for _, de := range children {
 		if de.entry.Tag != dwarf.TagSubprogram {
 			continue
 		}
 
-		_, hasName := de.Val(dwarf.AttrName).(string)
+		// declarations (e.g. methods in class bodies) don't have code
+		if declaration, _ := de.Val(dwarf.AttrDeclaration).(bool); declaration {
+			continue
+		}
+
+		name, hasName := qualifiedName(&de, names)
 		if !hasName {
 			continue
 		}
@@ -104,11 +114,21 @@ func (cu *CUEntry) GetFunctions() ([]*FunctionEntry, error) {
 			continue
 		}
 
+		f.Name = name
+		f.cu = cu
+
+		if isStaticEntry(de) {
+			f.StaticFile = cu.FileName()
+		}
+
 		funcs = append(funcs, f)
 	}
 
 	cu.functions = funcs
-	return funcs, MergeErrors(errors)
+	if len(errors) > 0 {
+		return funcs, MergeErrors(errors)
+	}
+	return funcs, nil
 }
 
 // GetGlobals returns the global variable entries that belong to this CU
@@ -139,7 +159,7 @@ func (cu *CUEntry) GetGlobals() ([]*VariableEntry, error) {
 		loc, _ := de.Location(dwarf.AttrLocation, lowpc)
 		if loc != nil && len(loc.instructions) > 0 {
 			firstOp := op.Opcode(loc.instructions[0])
-			if firstOp != op.DW_OP_addr {
+			if firstOp != op.DW_OP_addr && !isTLSLocation(loc.instructions) {
 				continue
 			}
 		} else {
@@ -156,9 +176,16 @@ func (cu *CUEntry) GetGlobals() ([]*VariableEntry, error) {
 			continue
 		}
 
+		if isStaticEntry(de) {
+			v.Name = cu.FileName() + staticSeparator + v.Name
+		}
+
 		vars = append(vars, v)
 	}
 
 	cu.globals = vars
-	return vars, MergeErrors(errors)
+	if len(errors) > 0 {
+		return vars, MergeErrors(errors)
+	}
+	return vars, nil
 }
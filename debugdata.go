@@ -6,6 +6,8 @@ import (
 	"debug/dwarf"
 	"debug/elf"
 	"encoding/binary"
+	"encoding/hex"
+	"fmt"
 	"io"
 	"os"
 	"regexp"
@@ -16,6 +18,7 @@ import (
 
 // DebugData contains debug information of an application or library
 type DebugData struct {
+	path          string
 	elfData       *elf.File
 	dwarfData     *dwarf.Data
 	dwarfEndian   binary.ByteOrder
@@ -23,10 +26,26 @@ type DebugData struct {
 	staticBase    uintptr
 	loclist       LocList
 	frameEntries  []frame.FrameDescriptionEntries
+	libFrames     map[uintptr]frame.FrameDescriptionEntries // CFI of the loaded libraries by static base
 	compUnits     []*CUEntry
 	functions     []*FunctionEntry
 	functionCache map[uintptr]*FunctionEntry
 	globals       []*VariableEntry
+	jit           *jitSymbols
+	libFilter     *LibraryFilter
+	libs          []SharedLibrary
+	skippedLibs   []SharedLibrary
+	libData       []*DebugData
+	typeIndex     map[string][]dwarf.Offset
+	dwarfVersions []int
+	libHealth     []ModuleHealth
+	isLib         bool
+	symbols       symbolCache
+	unloaded      []unloadedModule
+	budget        *memoryBudget   // shared with the libraries
+	sectionsSize  int64           // size of the debug sections, see memoryBudget.updateFixed
+	callSites     map[uintptr]int // direct call counts by target, computed on demand
+	file          *os.File        // opened by NewDebugDataFromPath, released by Close
 }
 
 // NewDebugData returns a new DebugData instance
@@ -43,14 +62,21 @@ func NewDebugData(file *os.File, staticBase uintptr) (*DebugData, error) {
 
 	entryPoint := uintptr(elfData.Entry)
 
+	path := file.Name()
+	if target, err := os.Readlink(path); err == nil {
+		path = target // e.g. /proc/<pid>/exe
+	}
+
 	d := &DebugData{
+		path:          path,
 		elfData:       elfData,
 		dwarfData:     dwarfData,
-		dwarfEndian:   ByteOrder,
+		dwarfEndian:   elfData.ByteOrder,
 		entryPoint:    entryPoint,
 		staticBase:    staticBase,
 		functionCache: make(map[uintptr]*FunctionEntry),
 	}
+	d.budget = newMemoryBudget(d)
 
 	var errors []error
 
@@ -58,16 +84,15 @@ func NewDebugData(file *os.File, staticBase uintptr) (*DebugData, error) {
 	debugInfoData, _, _ := d.GetElfSection("debug_info")
 	if debugInfoData != nil {
 		d.dwarfEndian = frame.DwarfEndian(debugInfoData)
+		d.dwarfVersions = parseDwarfVersions(debugInfoData, d.dwarfEndian)
 	} else {
 		errors = append(errors, Errorf("failed to determine dwarf endianness"))
 	}
 
-	// reading loclist data
+	// reading loclist data (missing if no variable needs a location list, e.g. without optimization)
 	loclistData, _, _ := d.GetElfSection("debug_loc")
 	if loclistData != nil {
-		d.loclist = NewLocList(loclistData, d.dwarfEndian)
-	} else {
-		errors = append(errors, Errorf("failed to read loclist data"))
+		d.loclist = parseLocList(loclistData, d.dwarfEndian, d.AddressSize())
 	}
 
 	// reading frame data
@@ -119,7 +144,13 @@ func NewDebugData(file *os.File, staticBase uintptr) (*DebugData, error) {
 		d.globals = append(d.globals, globals...)
 	}
 
-	return d, MergeErrors(errors)
+	d.sectionsSize = debugSectionsSize(elfData)
+	d.budget.updateFixed()
+
+	if len(errors) > 0 {
+		return d, MergeErrors(errors)
+	}
+	return d, nil
 }
 
 // GetEntryPoint returns the entry point PC or 0 if not found
@@ -132,6 +163,38 @@ func (d *DebugData) GetStaticBase() uintptr {
 	return d.staticBase
 }
 
+// GetBuildID returns the GNU build-id of the binary as a hex string
+func (d *DebugData) GetBuildID() (string, error) {
+	sec := d.elfData.Section(".note.gnu.build-id")
+	if sec == nil {
+		return "", Errorf("missing build-id section")
+	}
+
+	note, err := sec.Data()
+	if err != nil {
+		return "", Error(err)
+	}
+
+	if len(note) < 12 {
+		return "", Errorf("invalid build-id note")
+	}
+
+	order := d.elfData.ByteOrder
+	namesz := int(order.Uint32(note[0:4]))
+	descsz := int(order.Uint32(note[4:8]))
+	descOffset := 12 + (namesz+3)&^3
+	if descOffset+descsz > len(note) {
+		return "", Errorf("invalid build-id note")
+	}
+
+	return hex.EncodeToString(note[descOffset : descOffset+descsz]), nil
+}
+
+// ByteOrder returns the byte order of the target the debug data was built for
+func (d *DebugData) ByteOrder() binary.ByteOrder {
+	return d.elfData.ByteOrder
+}
+
 // GetElfSection returns the given elf section content as a byte slice
 func (d *DebugData) GetElfSection(name string) ([]byte, uintptr, error) {
 	sec := d.elfData.Section("." + name)
@@ -175,37 +238,108 @@ func decompressMaybe(b []byte) ([]byte, error) {
 	return dbuf, nil
 }
 
-// AddSharedLib loads additional debug data from a shared library
+// AddSharedLib loads additional debug data from a shared library.
+// Libraries rejected by the library filter are skipped and can be loaded later by LoadSharedLib.
 func (d *DebugData) AddSharedLib(lib SharedLibrary) error {
-	file, err := os.Open(lib.Name)
+	if !d.libFilter.Match(lib.Name) {
+		d.skippedLibs = append(d.skippedLibs, lib)
+		return nil
+	}
+
+	return Error(d.loadSharedLib(lib))
+}
+
+func (d *DebugData) loadSharedLib(lib SharedLibrary) error {
+	health := ModuleHealth{
+		Name:   lib.Name,
+		Path:   lib.path(),
+		Status: ModuleUnresolved,
+	}
+	defer func() { d.setLibHealth(health) }()
+
+	file, err := os.Open(lib.path())
 	if err != nil {
+		health.Error = err.Error()
 		return Error(err)
 	}
 
-	data, _ := NewDebugData(file, lib.StaticBase)
+	data, dwarfErr := NewDebugData(file, lib.StaticBase)
 	if data != nil {
+		data.isLib = true
+		data.budget = d.budget
 		d.functions = append(d.functions, data.functions...)
+		for _, frameEntries := range data.frameEntries {
+			d.addLibFrames(lib, frameEntries)
+		}
+		d.libs = append(d.libs, lib)
+		d.libData = append(d.libData, data)
+		health.Status = ModuleDebugInfo
+		health.DwarfVersions = data.dwarfVersions
+		health.Functions = len(data.functions)
+		health.Globals = len(data.globals)
+		d.InvalidateSymbolCache()
+		d.budget.updateFixed()
+		d.budget.enforce()
 		return nil
 	}
 
 	elfData, err := elf.NewFile(file)
 	if err != nil {
+		health.Error = err.Error()
 		return Error(err)
 	}
 
+	// stripped system libraries only have dynamic symbols
 	symbols, _ := elfData.Symbols()
-	for _, symbol := range symbols {
-		if symbol.Size == 0 {
+	dynSymbols, _ := elfData.DynamicSymbols()
+	seen := make(map[string]bool)
+
+	for _, symbol := range append(symbols, dynSymbols...) {
+		if symbol.Size == 0 || elf.ST_TYPE(symbol.Info) != elf.STT_FUNC || symbol.Section == elf.SHN_UNDEF {
+			continue
+		}
+
+		// versioned symbols can appear multiple times at the same address
+		key := fmt.Sprintf("%s@%x", symbol.Name, symbol.Value)
+		if seen[key] {
 			continue
 		}
+		seen[key] = true
 
 		fn, _ := NewLibFunctionEntry(&lib, symbol)
 		d.functions = append(d.functions, fn)
+		health.Functions++
+	}
+
+	health.Status = ModuleSymbolsOnly
+	if health.Functions == 0 {
+		health.Status = ModuleNoSymbols
+	}
+	if tracedErr, ok := dwarfErr.(*TracedError); ok && tracedErr != nil {
+		health.Error = errorMessage(tracedErr)
 	}
 
+	// stripped libraries keep their CFI to make unwinding through them possible
+	if sec := elfData.Section(".eh_frame"); sec != nil {
+		if frameData, err := sec.Data(); err == nil {
+			d.addLibFrames(lib, frame.Parse(frameData, elfData.ByteOrder, sec.Addr, uint64(lib.StaticBase)))
+		}
+	}
+
+	d.libs = append(d.libs, lib)
+	d.budget.updateFixed()
+	d.budget.enforce()
 	return nil
 }
 
+func (d *DebugData) addLibFrames(lib SharedLibrary, frameEntries frame.FrameDescriptionEntries) {
+	if d.libFrames == nil {
+		d.libFrames = make(map[uintptr]frame.FrameDescriptionEntries)
+	}
+
+	d.libFrames[lib.StaticBase] = frameEntries
+}
+
 // GetCompilationUnit returns the CU that belongs to the given PC
 func (d *DebugData) GetCompilationUnit(pc uintptr) (*CUEntry, error) {
 	for _, cu := range d.compUnits {
@@ -232,11 +366,15 @@ func (d *DebugData) GetLoclistEntry(pc uintptr, off int64) ([]byte, error) {
 	return entry.instructions, nil
 }
 
-// GetFunctionsByName returns function entries by name
+// GetFunctionsByName returns function entries by name. In exact mode the name can be
+// partially qualified (e.g. Class::method) and static functions can be qualified
+// by their file (e.g. file.c::func).
 func (d *DebugData) GetFunctionsByName(name string, exact bool) (results []*FunctionEntry) {
+	file, base, qualified := splitStaticName(name)
+
 	for _, fn := range d.functions {
 		if exact {
-			if fn.Name != name {
+			if !matchFunctionName(fn.Name, name) && (!qualified || fn.Name != base || fn.StaticFile != file) {
 				continue
 			}
 		} else {
@@ -273,14 +411,23 @@ func (d *DebugData) GetFunctionFromPC(pc uintptr) (*FunctionEntry, error) {
 	}
 
 	for _, fn := range d.functions {
-		lowpc := fn.LowPC + fn.StaticBase
-		highpc := fn.HighPC + fn.HighPC
-		if pc >= lowpc && pc < highpc {
+		if fn.containsPC(pc) {
 			d.functionCache[pc] = fn
+			d.budget.addCache(functionCacheEntrySize)
 			return fn, nil
 		}
 	}
 
+	if d.jit != nil {
+		if fn := d.jit.lookup(pc); fn != nil {
+			return fn, nil
+		}
+	}
+
+	if module, found := d.unloadedModuleAt(pc); found {
+		return nil, Errorf("pc:%#x is in unloaded module %s", pc, module)
+	}
+
 	return nil, Errorf("function not found for pc:%#x", pc)
 }
 
@@ -305,6 +452,13 @@ func (d *DebugData) getFDEFromPC(pc uintptr) (fde *frame.FrameDescriptionEntry,
 		}
 	}
 
+	for _, frameEntries := range d.libFrames {
+		fde, _ := frameEntries.FDEForPC(uint64(pc))
+		if fde != nil {
+			return fde, nil
+		}
+	}
+
 	return nil, Errorf("FDE not found for pc:%#x", pc)
 }
 
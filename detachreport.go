@@ -0,0 +1,168 @@
+package raztracer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DetachRiskKind is the kind of a residual risk left in the process after detaching
+type DetachRiskKind string
+
+// Detach risk kinds
+const (
+	RiskDetachSkipped         DetachRiskKind = "detach_skipped"          // the tracer stayed attached
+	RiskBreakpointNotRestored DetachRiskKind = "breakpoint_not_restored" // the original instruction couldn't be written back
+	RiskBreakpointUnmapped    DetachRiskKind = "breakpoint_unmapped"     // the page of the breakpoint is no longer mapped
+	RiskBreakpointModified    DetachRiskKind = "breakpoint_modified"     // the restored bytes differ from the saved ones
+	RiskWatchpointNotRestored DetachRiskKind = "watchpoint_not_restored" // the protection of a watched page is not restored
+	RiskThreadNotResumed      DetachRiskKind = "thread_not_resumed"      // the thread could not be detached
+	RiskStepOverFailed        DetachRiskKind = "step_over_failed"        // the thread could not step over a breakpoint
+	RiskThreadNotStopped      DetachRiskKind = "thread_not_stopped"      // the thread could not be interrupted
+)
+
+// DetachRisk is a problem that may affect the process after detaching
+type DetachRisk struct {
+	Kind    DetachRiskKind `json:"kind"`
+	Address uintptr        `json:"address,omitempty"`
+	Thread  Process        `json:"thread,omitempty"`
+	Detail  string         `json:"detail,omitempty"`
+}
+
+// PendingSignals are the signals pending for a thread when it was resumed
+type PendingSignals struct {
+	Thread  Process  `json:"thread"`
+	Signals []string `json:"signals"`
+}
+
+// DetachReport describes what was restored in the process when detaching
+type DetachReport struct {
+	PID                Process          `json:"pid"`
+	Time               time.Time        `json:"time"`
+	Cause              DetachCause      `json:"cause,omitempty"` // set if the tracer detached automatically
+	BreakpointsRemoved []uintptr        `json:"breakpoints_removed"`
+	WatchpointsRemoved int              `json:"watchpoints_removed"`
+	ThreadsResumed     []Process        `json:"threads_resumed"`
+	PendingSignals     []PendingSignals `json:"pending_signals,omitempty"`
+	Risks              []DetachRisk     `json:"risks,omitempty"`
+}
+
+// Clean returns true if the process was left without residual risks
+func (r *DetachReport) Clean() bool {
+	return len(r.Risks) == 0
+}
+
+func (r *DetachReport) addRisk(kind DetachRiskKind, addr uintptr, tid Process, err error) {
+	risk := DetachRisk{Kind: kind, Address: addr, Thread: tid}
+	if err != nil {
+		if tracedErr, ok := err.(*TracedError); ok {
+			err = tracedErr.Err
+		}
+		risk.Detail = err.Error()
+	}
+	r.Risks = append(r.Risks, risk)
+}
+
+// String returns the report in a human readable form
+func (r *DetachReport) String() string {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "detached from %d\n", r.PID)
+	fmt.Fprintf(&buf, "breakpoints removed: %d\n", len(r.BreakpointsRemoved))
+	fmt.Fprintf(&buf, "watchpoints removed: %d\n", r.WatchpointsRemoved)
+	fmt.Fprintf(&buf, "threads resumed: %d\n", len(r.ThreadsResumed))
+
+	for _, pending := range r.PendingSignals {
+		fmt.Fprintf(&buf, "pending signals of %d: %s\n", pending.Thread, strings.Join(pending.Signals, " "))
+	}
+
+	if r.Clean() {
+		buf.WriteString("the process was left clean\n")
+		return buf.String()
+	}
+
+	for _, risk := range r.Risks {
+		fmt.Fprintf(&buf, "risk: %s", risk.Kind)
+		if risk.Address != 0 {
+			fmt.Fprintf(&buf, " at %#x", risk.Address)
+		}
+		if risk.Thread != 0 {
+			fmt.Fprintf(&buf, " thread %d", risk.Thread)
+		}
+		if len(risk.Detail) > 0 {
+			fmt.Fprintf(&buf, " (%s)", risk.Detail)
+		}
+		buf.WriteByte('\n')
+	}
+
+	return buf.String()
+}
+
+// checkRestoredBreakpoint verifies the original instruction of a disabled breakpoint
+func (r *DetachReport) checkRestoredBreakpoint(bp *Breakpoint, regions []MemRegion) {
+	if !isMappedAddress(regions, bp.addr) {
+		r.addRisk(RiskBreakpointUnmapped, bp.addr, 0, nil)
+		return
+	}
+
+	data := make([]byte, len(bp.savedData))
+	err := bp.owner.PeekData(bp.addr, data)
+	if err != nil {
+		r.addRisk(RiskBreakpointNotRestored, bp.addr, 0, err)
+	} else if !bytes.Equal(data, bp.savedData) {
+		r.addRisk(RiskBreakpointModified, bp.addr, 0, Errorf("found %x instead of %x", data, bp.savedData))
+	}
+}
+
+// isMappedAddress returns true if 'addr' is in one of the regions (or the regions are unknown)
+func isMappedAddress(regions []MemRegion, addr uintptr) bool {
+	if len(regions) == 0 {
+		return true
+	}
+
+	for _, region := range regions {
+		if addr >= region.Address[0] && addr < region.Address[1] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pendingSignals returns the signals pending for the thread or the whole process
+func (tid Process) pendingSignals() ([]string, error) {
+	file, err := os.Open(fmt.Sprintf("/proc/%d/task/%d/status", tid, tid))
+	if err != nil {
+		return nil, Error(err)
+	}
+	defer file.Close()
+
+	var mask uint64
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "SigPnd:") && !strings.HasPrefix(line, "ShdPnd:") {
+			continue
+		}
+
+		value, err := strconv.ParseUint(strings.TrimSpace(line[7:]), 16, 64)
+		if err != nil {
+			return nil, Error(err)
+		}
+		mask |= value
+	}
+
+	var signals []string
+	for i := uint(0); i < 64; i++ {
+		if mask&(1<<i) != 0 {
+			signals = append(signals, SignalName(syscall.Signal(i+1)))
+		}
+	}
+
+	return signals, Error(scanner.Err())
+}
@@ -21,8 +21,14 @@ func (err *TracedError) Error() string {
 	return str
 }
 
-// Error creates a new TracedError from 'e' or appends a new frame if 'e' is TracedError
-func Error(e interface{}) *TracedError {
+// Unwrap returns the original error
+func (err *TracedError) Unwrap() error {
+	return err.Err
+}
+
+// Error creates a new TracedError from 'e' or appends a new frame if 'e' is TracedError.
+// Returns an untyped nil if 'e' is nil, so the result can be compared to nil as an error.
+func Error(e interface{}) error {
 	if e == nil {
 		return nil
 	}
@@ -31,6 +37,9 @@ func Error(e interface{}) *TracedError {
 
 	switch err := e.(type) {
 	case *TracedError:
+		if err == nil {
+			return nil
+		}
 		err.Frames = append(err.Frames, frame)
 		return err
 
@@ -56,17 +65,23 @@ func Errorf(format string, args ...interface{}) *TracedError {
 	}
 }
 
-// MergeErrors merges multiple errors into a single TracedError
-func MergeErrors(errors []error) *TracedError {
-	if len(errors) == 0 {
-		return nil
-	}
-
+// MergeErrors merges multiple errors into a single TracedError (nil if there are no errors)
+func MergeErrors(errors []error) error {
 	str := make([]string, 0, len(errors))
 	for _, err := range errors {
+		if err == nil {
+			continue
+		}
+		if tracedErr, ok := err.(*TracedError); ok && tracedErr == nil {
+			continue
+		}
 		str = append(str, fmt.Sprint(err))
 	}
 
+	if len(str) == 0 {
+		return nil
+	}
+
 	return &TracedError{
 		Err:    fmt.Errorf("%s", strings.Join(str, "; ")),
 		Frames: []runtime.Frame{getLastFrame()},
@@ -81,3 +96,12 @@ func getLastFrame() runtime.Frame {
 
 	return frame
 }
+
+// errorMessage returns the message of 'err' without the origin frames
+func errorMessage(err error) string {
+	if tracedErr, ok := err.(*TracedError); ok && tracedErr != nil {
+		return fmt.Sprint(tracedErr.Err)
+	}
+
+	return fmt.Sprint(err)
+}
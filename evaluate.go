@@ -0,0 +1,44 @@
+package raztracer
+
+// EvaluateLocation evaluates the DWARF location expression 'instructions' in the frame
+// at 'frameIndex' of the stopped thread (0 is the innermost frame). The returned location
+// provides the address of the value and can read it through ReadValue.
+func (t *Tracer) EvaluateLocation(frameIndex int, instructions []byte) (*Location, error) {
+	if frameIndex < 0 {
+		return nil, Errorf("invalid frame index: %d", frameIndex)
+	} else if len(instructions) == 0 {
+		return nil, Errorf("no location instructions")
+	}
+
+	stack, err := NewStackIterator(t.tid, t.debugData)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	for i := 0; stack.Next(); i++ {
+		if i < frameIndex {
+			continue
+		}
+
+		regs := stack.Registers()
+		order := t.debugData.dwarfEndian
+		if data := stack.Function().entry.data; data != nil {
+			regs = data.withTLS(regs)
+			order = data.dwarfEndian
+		}
+
+		loc := &Location{instructions: instructions, order: order}
+		err := loc.parse(regs)
+		if err != nil {
+			return nil, Error(err)
+		}
+
+		return loc, nil
+	}
+
+	if stack.Err() != nil {
+		return nil, Error(stack.Err())
+	}
+
+	return nil, Errorf("frame %d is out of range", frameIndex)
+}
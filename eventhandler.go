@@ -0,0 +1,137 @@
+package raztracer
+
+import (
+	"plugin"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// EventHandler processes trace events on the event path of a TraceManager.
+// Handlers can filter (by returning a nil event), enrich or export events.
+// HandleEvent is called in the tracer's thread.
+type EventHandler interface {
+	HandleEvent(t *Tracer, evt *TraceEvent) (*TraceEvent, error)
+}
+
+// EventHandlerFunc is an adapter to use ordinary functions as event handlers
+type EventHandlerFunc func(t *Tracer, evt *TraceEvent) (*TraceEvent, error)
+
+// HandleEvent implements EventHandler
+func (fn EventHandlerFunc) HandleEvent(t *Tracer, evt *TraceEvent) (*TraceEvent, error) {
+	return fn(t, evt)
+}
+
+var (
+	eventHandlersMutex sync.Mutex
+	eventHandlers      = make(map[string]EventHandler)
+)
+
+// RegisterEventHandler registers an event handler by name
+func RegisterEventHandler(name string, handler EventHandler) error {
+	eventHandlersMutex.Lock()
+	defer eventHandlersMutex.Unlock()
+
+	if _, found := eventHandlers[name]; found {
+		return Errorf("event handler already registered: %s", name)
+	}
+
+	eventHandlers[name] = handler
+	return nil
+}
+
+// GetEventHandler returns a registered event handler by name
+func GetEventHandler(name string) (EventHandler, error) {
+	eventHandlersMutex.Lock()
+	defer eventHandlersMutex.Unlock()
+
+	handler, found := eventHandlers[name]
+	if !found {
+		return nil, Errorf("event handler not found: %s", name)
+	}
+
+	return handler, nil
+}
+
+// GetEventHandlerNames returns the names of the registered event handlers
+func GetEventHandlerNames() []string {
+	eventHandlersMutex.Lock()
+	defer eventHandlersMutex.Unlock()
+
+	names := make([]string, 0, len(eventHandlers))
+	for name := range eventHandlers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// LoadEventHandlerPlugin opens a Go plugin (built with -buildmode=plugin).
+// The plugin can either call RegisterEventHandler in its init function, or export
+// a variable named EventHandler (e.g. var EventHandler = &MyHandler{}), which is then
+// registered by the plugin's path.
+func LoadEventHandlerPlugin(path string) error {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return Error(err)
+	}
+
+	sym, err := p.Lookup("EventHandler")
+	if err != nil {
+		return nil // the plugin registered its handlers in init
+	}
+
+	handler, ok := pluginEventHandler(sym)
+	if !ok {
+		return Errorf("%s: EventHandler doesn't implement the EventHandler interface", path)
+	}
+
+	if err := RegisterEventHandler(path, handler); err != nil {
+		return Error(err)
+	}
+	return nil
+}
+
+// pluginEventHandler returns the event handler of an exported plugin variable.
+// Lookup returns a pointer to the variable, so the pointers are followed until
+// a value implementing EventHandler is found.
+func pluginEventHandler(sym interface{}) (EventHandler, bool) {
+	v := reflect.ValueOf(sym)
+	for v.IsValid() {
+		isNil := (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil()
+		if handler, ok := v.Interface().(EventHandler); ok && !isNil {
+			return handler, true
+		}
+
+		if isNil || (v.Kind() != reflect.Ptr && v.Kind() != reflect.Interface) {
+			break
+		}
+		v = v.Elem()
+	}
+
+	return nil, false
+}
+
+// processEvent passes the event through the handler chain
+func processEvent(handlers []EventHandler, t *Tracer, evt *TraceEvent) (*TraceEvent, error) {
+	var errors []error
+
+	for _, handler := range handlers {
+		var err error
+		evt, err = handler.HandleEvent(t, evt)
+		if err != nil {
+			errors = append(errors, err)
+		}
+
+		if evt == nil {
+			break
+		}
+	}
+
+	if len(errors) > 0 {
+		return evt, MergeErrors(errors)
+	}
+
+	return evt, nil
+}
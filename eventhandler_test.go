@@ -0,0 +1,49 @@
+package raztracer
+
+import (
+	"testing"
+)
+
+type testHandler struct {
+	name string
+}
+
+func (h *testHandler) HandleEvent(t *Tracer, evt *TraceEvent) (*TraceEvent, error) {
+	return evt, nil
+}
+
+func TestPluginEventHandler(t *testing.T) {
+	// the values returned by plugin.Lookup for the exported variables
+	ptr := &testHandler{name: "ptr"}
+	var iface EventHandler = &testHandler{name: "iface"}
+	var fn EventHandlerFunc = func(t *Tracer, evt *TraceEvent) (*TraceEvent, error) { return evt, nil }
+	var nilPtr *testHandler
+	notHandler := 42
+
+	valid := map[string]interface{}{
+		"var EventHandler = &MyHandler{}":             &ptr,
+		"var EventHandler MyHandler":                  &testHandler{name: "value"},
+		"var EventHandler raztracer.EventHandler":     &iface,
+		"var EventHandler raztracer.EventHandlerFunc": &fn,
+	}
+	for name, sym := range valid {
+		if handler, ok := pluginEventHandler(sym); !ok || handler == nil {
+			t.Errorf("%s was rejected", name)
+		}
+	}
+
+	if handler, _ := pluginEventHandler(&ptr); handler != ptr {
+		t.Errorf("expected the handler the variable points to, got %v", handler)
+	}
+
+	invalid := map[string]interface{}{
+		"var EventHandler *MyHandler": &nilPtr,
+		"var EventHandler int":        &notHandler,
+		"nil":                         nil,
+	}
+	for name, sym := range invalid {
+		if _, ok := pluginEventHandler(sym); ok {
+			t.Errorf("%s was accepted", name)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package raztracer
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestExitBreakpoints(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	cmd := startTracee(t, 5)
+	defer cmd.Process.Kill()
+
+	tracer, err := NewTracer(cmd.Process.Pid)
+	skipIfNotPermitted(t, err)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the test program doesn't define any of the exit functions, they are in libc
+	addrs, err := tracer.SetExitBreakpoints()
+	if err != nil {
+		tracer.Detach()
+		t.Fatal(err)
+	}
+	if len(addrs) == 0 {
+		tracer.Detach()
+		t.Fatal("no exit breakpoints were set")
+	}
+
+	tracer.Run()
+
+	var exitEvt *TraceEvent
+	for exitEvt == nil {
+		evt, err := tracer.WaitForEvent(5 * time.Second)
+		if err != nil {
+			tracer.Detach()
+			t.Fatal(err)
+		}
+		if evt == nil {
+			tracer.Detach()
+			t.Fatal("the exit breakpoint wasn't hit")
+		}
+
+		if evt.IsExitPath {
+			exitEvt = evt
+		}
+	}
+
+	if !exitEvt.IsBreakpoint || len(exitEvt.Backtrace) == 0 {
+		t.Errorf("unexpected exit event: %v", exitEvt)
+	} else if name := exitEvt.Backtrace[0].fn.Name; name != "exit" {
+		t.Errorf("stopped in %s instead of exit", name)
+	}
+
+	if _, err := tracer.DetachWithReport(); err != nil {
+		t.Fatal(err)
+	}
+
+	if status := waitTracee(t, cmd); status != 0 {
+		t.Errorf("the test program exited with %d", status)
+	}
+}
@@ -0,0 +1,39 @@
+package raztracer
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/razzie/raztracer/client"
+)
+
+// SchemaVersion is the version of the exported JSON format, see the client package
+const SchemaVersion = client.SchemaVersion
+
+// TraceExport contains a recorded trace session
+type TraceExport struct {
+	SchemaVersion int           `json:"schema_version"`
+	Session       *SessionInfo  `json:"session"`
+	Events        []*TraceEvent `json:"events"`
+}
+
+// NewTraceExport returns a new TraceExport of the tracer's session
+func NewTraceExport(t *Tracer) *TraceExport {
+	return &TraceExport{
+		SchemaVersion: SchemaVersion,
+		Session:       t.GetSessionInfo(),
+		Events:        make([]*TraceEvent, 0),
+	}
+}
+
+// Add appends a trace event to the export
+func (e *TraceExport) Add(evt *TraceEvent) {
+	e.Events = append(e.Events, evt)
+}
+
+// WriteJSON writes the export to 'w' in JSON format
+func (e *TraceExport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return Error(enc.Encode(e))
+}
@@ -0,0 +1,247 @@
+package raztracer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/razzie/raztracer/client"
+)
+
+// TestExportMatchesClient checks that the client types decode every field of an
+// export and encode it back unchanged
+func TestExportMatchesClient(t *testing.T) {
+	reading := Reading{
+		Name: "p", Type: "struct point *", Size: 8, Location: "fbreg -24", Address: 0x7ffc1000,
+		Value: "0x5555", Raw: []byte{1, 2}, Error: "", Truncated: true,
+		Children: []Reading{{Name: "x", Type: "int", Size: 4, Location: "0x5555", Value: "1"}},
+	}
+
+	export := &TraceExport{
+		SchemaVersion: SchemaVersion,
+		Session: &SessionInfo{
+			PID: 42, ProgName: "tracee", Executable: "/tmp/tracee", Cmdline: []string{"tracee", "1"},
+			Environ: []string{"HOME=/root"}, Cwd: "/tmp", BuildID: "abcd", MountNS: "mnt:[1]", Root: "/",
+			Libraries:  []SharedLibrary{{Name: "/lib/libc.so.6", Path: "/proc/42/root/lib/libc.so.6", StaticBase: 0x7f00}},
+			AttachTime: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+		Events: []*TraceEvent{{
+			Seq: 1, Signal: syscall.SIGSEGV,
+			Reason: StopReason{Kind: StopKilled, Signal: syscall.SIGSEGV, Event: EventExit, ExitCode: 1, CoreDump: true},
+			PID:    42, TID: 43, IsBreakpoint: true, IsExitPath: true, IsWatchpoint: true, WatchAddress: 0x1000,
+			IsNewThread: true, IsStopRequest: true, NewTID: 44, PC: 0x5555,
+			Stack:        &StackUsage{SP: 1, Base: 2, Limit: 3, Depth: 4, Remaining: 5},
+			Warnings:     []string{"stack overflow"},
+			Registers:    map[string]string{"rip": "0x5555"},
+			RegisterDiff: map[string]RegisterChange{"rax": {Old: "0x1", New: "0x2"}},
+			Globals:      []Reading{reading},
+			Backtrace: []*BacktraceFrame{{
+				Function: "traced (0x1139+0x0)", Source: "tracee.c:12", PC: "0x5555", Module: "/tmp/tracee",
+				Offset: "0x1139", CFA: "0x7ffc", FrameBase: "0x7ff0", Variables: []Reading{reading},
+			}},
+			BacktraceEnd: BacktraceUnwindFailure,
+			Unwind:       &UnwindDiagnostic{Reason: UnwindNoFunction, Frame: 1, PC: 2, CFA: 3, Address: 4, NoFDE: true, Detail: "x"},
+			Step:         &StepInfo{Requested: StepModeLine, Mode: StepModeInstruction, Instructions: 3, BudgetExhausted: true, From: "a", To: "b", Reason: "c"},
+			Call:         &CallInfo{ID: 1, Function: "traced", Depth: 2, Exit: true, Duration: time.Millisecond, Return: "0"},
+			Python:       []PythonThread{{ThreadState: 0x10, Frames: []*PythonFrame{{Function: "f", Filename: "a.py", Line: 3}}}},
+			Overhead:     &EventOverhead{Collection: 1, Unwind: 2, Variables: 3, PtraceCalls: 4},
+			Latency:      []LatencySample{{Probe: "p", Key: "k", Duration: time.Second}},
+		}},
+	}
+
+	data, err := json.Marshal(export)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := readExportSchema(t)
+	var doc interface{}
+	json.Unmarshal(data, &doc)
+	for _, problem := range validateSchema(schema, schema, doc, "$") {
+		t.Errorf("the export doesn't match schema.json: %s", problem)
+	}
+
+	var decoded client.Export
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&decoded); err != nil {
+		t.Fatalf("the client types don't match the export: %v", err)
+	}
+
+	reencoded, err := json.Marshal(&decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected, actual interface{}
+	json.Unmarshal(data, &expected)
+	json.Unmarshal(reencoded, &actual)
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("the client types lost data:\nexport: %s\nclient: %s", data, reencoded)
+	}
+}
+
+// readExportSchema reads client/schema.json and checks that it's up to date
+func readExportSchema(t *testing.T) map[string]interface{} {
+	t.Helper()
+
+	data, err := ioutil.ReadFile(filepath.Join("client", "schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	generated, err := client.Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bytes.TrimSpace(data), generated) {
+		t.Error("client/schema.json is outdated, run go generate in the client package")
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+// validateSchema checks a decoded JSON value against the subset of JSON schema
+// the client package generates, and returns the problems found
+func validateSchema(root, schema map[string]interface{}, value interface{}, path string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/definitions/")
+		def, _ := root["definitions"].(map[string]interface{})[name].(map[string]interface{})
+		if def == nil {
+			return []string{fmt.Sprintf("%s: unknown definition %s", path, ref)}
+		}
+		return validateSchema(root, def, value, path)
+	}
+
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		for _, alt := range anyOf {
+			if len(validateSchema(root, alt.(map[string]interface{}), value, path)) == 0 {
+				return nil
+			}
+		}
+		return []string{fmt.Sprintf("%s: %v matches none of the alternatives", path, value)}
+	}
+
+	if types, found := schema["type"]; found && !matchesSchemaType(types, value) {
+		return []string{fmt.Sprintf("%s: %v is not of type %v", path, value, types)}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, v := range enum {
+			found = found || v == value
+		}
+		if !found {
+			return []string{fmt.Sprintf("%s: %v is not one of %v", path, value, enum)}
+		}
+	}
+
+	if pattern, ok := schema["pattern"].(string); ok {
+		if str, _ := value.(string); !regexp.MustCompile(pattern).MatchString(str) {
+			return []string{fmt.Sprintf("%s: %q doesn't match %s", path, str, pattern)}
+		}
+	}
+
+	if num, ok := value.(float64); ok {
+		if min, ok := schema["minimum"].(float64); ok && num < min {
+			return []string{fmt.Sprintf("%s: %v is less than %v", path, num, min)}
+		}
+		if max, ok := schema["maximum"].(float64); ok && num > max {
+			return []string{fmt.Sprintf("%s: %v is more than %v", path, num, max)}
+		}
+	}
+
+	var problems []string
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		for _, name := range schemaStrings(schema["required"]) {
+			if _, found := v[name]; !found {
+				problems = append(problems, fmt.Sprintf("%s: missing required property %s", path, name))
+			}
+		}
+		for name, field := range v {
+			fieldSchema, _ := properties[name].(map[string]interface{})
+			if fieldSchema == nil {
+				fieldSchema, _ = schema["additionalProperties"].(map[string]interface{})
+			}
+			if fieldSchema == nil {
+				problems = append(problems, fmt.Sprintf("%s: unknown property %s", path, name))
+				continue
+			}
+			problems = append(problems, validateSchema(root, fieldSchema, field, path+"."+name)...)
+		}
+
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				problems = append(problems, validateSchema(root, items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+
+	return problems
+}
+
+func matchesSchemaType(types interface{}, value interface{}) bool {
+	names := schemaStrings(types)
+	if name, ok := types.(string); ok {
+		names = []string{name}
+	}
+
+	for _, name := range names {
+		switch v := value.(type) {
+		case nil:
+			if name == "null" {
+				return true
+			}
+		case bool:
+			if name == "boolean" {
+				return true
+			}
+		case string:
+			if name == "string" {
+				return true
+			}
+		case float64:
+			if name == "number" || (name == "integer" && v == math.Trunc(v)) {
+				return true
+			}
+		case []interface{}:
+			if name == "array" {
+				return true
+			}
+		case map[string]interface{}:
+			if name == "object" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func schemaStrings(value interface{}) []string {
+	list, _ := value.([]interface{})
+	strs := make([]string, 0, len(list))
+	for _, v := range list {
+		if str, ok := v.(string); ok {
+			strs = append(strs, str)
+		}
+	}
+	return strs
+}
@@ -0,0 +1,258 @@
+package raztracer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+)
+
+// maxFormattedString limits the length of strings read by the built-in formatters
+const maxFormattedString = 256
+
+// FormatContext contains the data of a value passed to a Formatter
+type FormatContext struct {
+	Type    string       // type name the formatter was selected by
+	Address uintptr      // address of the value (0 if it isn't in memory)
+	Data    []byte       // raw data of the value
+	Mem     MemoryReader // memory of the process to follow pointers
+}
+
+// Formatter renders a value of a registered type
+type Formatter func(ctx *FormatContext) (string, error)
+
+type formatterRegistry struct {
+	mtx      sync.RWMutex
+	exact    map[string]Formatter
+	patterns []string
+}
+
+var formatters = &formatterRegistry{exact: make(map[string]Formatter)}
+
+// RegisterFormatter registers a formatter for a type name. The name can be a glob pattern
+// (e.g. "std::vector<*>"). Pointer readings are formatted by the formatter of the pointed type.
+func RegisterFormatter(typeName string, formatter Formatter) {
+	formatters.mtx.Lock()
+	defer formatters.mtx.Unlock()
+
+	if _, exists := formatters.exact[typeName]; !exists && strings.ContainsAny(typeName, "*?[") {
+		formatters.patterns = append(formatters.patterns, typeName)
+	}
+
+	formatters.exact[typeName] = formatter
+}
+
+// UnregisterFormatter removes the formatter of a type name
+func UnregisterFormatter(typeName string) {
+	formatters.mtx.Lock()
+	defer formatters.mtx.Unlock()
+
+	delete(formatters.exact, typeName)
+
+	for i, pattern := range formatters.patterns {
+		if pattern == typeName {
+			formatters.patterns = append(formatters.patterns[:i], formatters.patterns[i+1:]...)
+			break
+		}
+	}
+}
+
+// findFormatter returns the formatter of the type or nil if there is none
+func findFormatter(typeName string) Formatter {
+	typeName = strings.TrimPrefix(typeName, "const ")
+	for _, prefix := range []string{"struct ", "class ", "union "} {
+		typeName = strings.TrimPrefix(typeName, prefix)
+	}
+
+	formatters.mtx.RLock()
+	defer formatters.mtx.RUnlock()
+
+	if formatter, found := formatters.exact[typeName]; found {
+		return formatter
+	}
+
+	for _, pattern := range formatters.patterns {
+		if ok, _ := path.Match(pattern, typeName); ok {
+			return formatters.exact[pattern]
+		}
+	}
+
+	return nil
+}
+
+// formatReading replaces the value of the reading by the output of its type's formatter
+// applied to the value at 'addr'. 'size' is the size of the value, which is read again
+// if 'data' is shorter. The pointed data of pointers is formatted after the pointer value.
+func formatReading(mem MemoryReader, r *Reading, typeName string, addr uintptr, data []byte, size int64, isPointer bool) {
+	formatter := findFormatter(typeName)
+	if formatter == nil || len(r.Error) > 0 {
+		return
+	}
+
+	if int64(len(data)) < size && addr != 0 && size <= maxPointeeSize {
+		data = make([]byte, size)
+		if mem.PeekData(addr, data) != nil {
+			return
+		}
+	}
+
+	value, err := formatter(&FormatContext{
+		Type:    typeName,
+		Address: addr,
+		Data:    data,
+		Mem:     mem,
+	})
+	if err != nil {
+		r.Error = fmt.Sprintf("formatter: %v", err)
+		return
+	}
+
+	if isPointer {
+		r.Value = fmt.Sprintf("%#x : %s", addr, value)
+		return
+	}
+
+	r.Value = value
+}
+
+func init() {
+	RegisterFormatter("std::string", formatStdString)
+	RegisterFormatter("string", formatStdString)
+	RegisterFormatter("basic_string<char, std::char_traits<char>, std::allocator<char> >", formatStdString)
+	RegisterFormatter("std::__cxx11::basic_string<char, std::char_traits<char>, std::allocator<char> >", formatStdString)
+	RegisterFormatter("shared_ptr<*>", formatStdSharedPtr)
+	RegisterFormatter("std::shared_ptr<*>", formatStdSharedPtr)
+	RegisterFormatter("unique_ptr<*>", formatStdUniquePtr)
+	RegisterFormatter("std::unique_ptr<*>", formatStdUniquePtr)
+	RegisterFormatter("vector<*>", formatStdVector)
+	RegisterFormatter("std::vector<*>", formatStdVector)
+	RegisterFormatter("timespec", formatTimespec)
+	RegisterFormatter("timeval", formatTimeval)
+	RegisterFormatter("FILE", formatFile)
+	RegisterFormatter("_IO_FILE", formatFile)
+}
+
+// byteOrder returns the byte order of the process memory
+func (ctx *FormatContext) byteOrder() binary.ByteOrder {
+	return byteOrderOf(ctx.Mem)
+}
+
+// readWords reads 'count' pointer sized words from the data
+func readWords(data []byte, count int, order binary.ByteOrder) ([]uintptr, error) {
+	if len(data) < count*int(SizeofPtr) {
+		return nil, Errorf("not enough data: %d bytes", len(data))
+	}
+
+	words := make([]uintptr, count)
+	for i := range words {
+		words[i] = ReadAddressWithOrder(data[i*int(SizeofPtr):], order)
+	}
+	return words, nil
+}
+
+// formatStdString formats libstdc++ strings: { char* _M_p; size_t _M_string_length; ... }
+func formatStdString(ctx *FormatContext) (string, error) {
+	words, err := readWords(ctx.Data, 2, ctx.byteOrder())
+	if err != nil {
+		return "", err
+	}
+
+	length := words[1]
+	truncated := length > maxFormattedString
+	if truncated {
+		length = maxFormattedString
+	}
+
+	str := make([]byte, length)
+	if length > 0 {
+		err := ctx.Mem.PeekData(words[0], str)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if truncated {
+		return fmt.Sprintf("%q... (length=%d)", str, words[1]), nil
+	}
+	return fmt.Sprintf("%q", str), nil
+}
+
+// formatStdSharedPtr formats libstdc++ shared pointers: { T* _M_ptr; _Sp_counted_base* _M_pi; }
+func formatStdSharedPtr(ctx *FormatContext) (string, error) {
+	words, err := readWords(ctx.Data, 2, ctx.byteOrder())
+	if err != nil {
+		return "", err
+	}
+
+	if words[1] == 0 {
+		return fmt.Sprintf("%#x", words[0]), nil
+	}
+
+	// _Sp_counted_base: { vptr; int _M_use_count; int _M_weak_count; }
+	counts := make([]byte, 8)
+	err = ctx.Mem.PeekData(words[1]+SizeofPtr, counts)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%#x (use_count=%d, weak_count=%d)", words[0],
+		int32(ctx.byteOrder().Uint32(counts)), int32(ctx.byteOrder().Uint32(counts[4:]))), nil
+}
+
+// formatStdUniquePtr formats unique pointers as the owned pointer
+func formatStdUniquePtr(ctx *FormatContext) (string, error) {
+	words, err := readWords(ctx.Data, 1, ctx.byteOrder())
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%#x", words[0]), nil
+}
+
+// formatStdVector formats libstdc++ vectors: { T* _M_start; T* _M_finish; T* _M_end_of_storage; }
+func formatStdVector(ctx *FormatContext) (string, error) {
+	words, err := readWords(ctx.Data, 3, ctx.byteOrder())
+	if err != nil {
+		return "", err
+	}
+
+	if words[1] < words[0] || words[2] < words[1] {
+		return "", Errorf("invalid vector pointers")
+	}
+
+	return fmt.Sprintf("data=%#x size=%d bytes capacity=%d bytes",
+		words[0], words[1]-words[0], words[2]-words[0]), nil
+}
+
+// formatTimespec formats struct timespec: { time_t tv_sec; long tv_nsec; }
+func formatTimespec(ctx *FormatContext) (string, error) {
+	words, err := readWords(ctx.Data, 2, ctx.byteOrder())
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%d.%09ds", int64(words[0]), int64(words[1])), nil
+}
+
+// formatTimeval formats struct timeval: { time_t tv_sec; suseconds_t tv_usec; }
+func formatTimeval(ctx *FormatContext) (string, error) {
+	words, err := readWords(ctx.Data, 2, ctx.byteOrder())
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%d.%06ds", int64(words[0]), int64(words[1])), nil
+}
+
+// fileDescriptorOffset is the offset of _fileno in glibc's struct _IO_FILE
+const fileDescriptorOffset = 112
+
+// formatFile formats glibc FILE streams by their file descriptor
+func formatFile(ctx *FormatContext) (string, error) {
+	if len(ctx.Data) < fileDescriptorOffset+4 {
+		return "", Errorf("not enough data: %d bytes", len(ctx.Data))
+	}
+
+	return fmt.Sprintf("FILE(fd=%d)", int32(ctx.byteOrder().Uint32(ctx.Data[fileDescriptorOffset:]))), nil
+}
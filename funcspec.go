@@ -0,0 +1,244 @@
+package raztracer
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// FunctionCandidate is one of the functions matching an ambiguous name
+type FunctionCandidate struct {
+	Index     int     `json:"index"` // selects the candidate with the name#index syntax
+	Name      string  `json:"name"`
+	Signature string  `json:"signature,omitempty"`
+	Source    string  `json:"source,omitempty"` // file:line of the function
+	Library   string  `json:"library,omitempty"`
+	Address   uintptr `json:"address"`
+}
+
+// AmbiguousFunctionError is returned when a name matches multiple functions.
+// A candidate can be selected by index (name#2), by parameter types (name(int, char *))
+// or by module (libfoo.so!name), or every candidate can be selected with name#*.
+type AmbiguousFunctionError struct {
+	Name       string              `json:"name"`
+	Candidates []FunctionCandidate `json:"candidates"`
+}
+
+// Error implements error interface
+func (err *AmbiguousFunctionError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "ambiguous function name %s, candidates:", err.Name)
+
+	for _, c := range err.Candidates {
+		desc := c.Signature
+		if len(desc) == 0 {
+			desc = c.Name
+		}
+
+		fmt.Fprintf(&sb, "\n#%d %s at %#x", c.Index, desc, c.Address)
+		if len(c.Source) > 0 {
+			fmt.Fprintf(&sb, " (%s)", c.Source)
+		}
+		if len(c.Library) > 0 {
+			fmt.Fprintf(&sb, " in %s", c.Library)
+		}
+	}
+
+	return sb.String()
+}
+
+// ResolveFunction returns the functions selected by 'spec', which is a function name
+// optionally prefixed by a module (libfoo.so!name) and followed by a selector:
+// #index, #* or a parameter list.
+// Returns an AmbiguousFunctionError if a name without selector matches multiple functions.
+func (d *DebugData) ResolveFunction(spec string) ([]*FunctionEntry, error) {
+	module, spec := splitModuleSpec(spec)
+	name, selector := splitFunctionSpec(spec)
+
+	funcs := uniqueFunctions(d.GetFunctionsByName(name, true))
+	if len(module) > 0 {
+		funcs = filterModule(funcs, module)
+	}
+
+	if len(funcs) == 0 {
+		if len(module) > 0 {
+			return nil, Errorf("function not found: %s!%s", module, name)
+		}
+		return nil, Errorf("function not found: %s", name)
+	}
+
+	switch {
+	case selector == "#*":
+		return funcs, nil
+
+	case strings.HasPrefix(selector, "#"):
+		index, err := strconv.Atoi(selector[1:])
+		if err != nil || index < 1 || index > len(funcs) {
+			return nil, Errorf("invalid function index %s (%d candidates)", selector, len(funcs))
+		}
+		return funcs[index-1 : index], nil
+
+	case strings.HasPrefix(selector, "("):
+		var selected []*FunctionEntry
+		for _, fn := range funcs {
+			if sig, err := fn.Signature(); err == nil && sig.matchParams(selector) {
+				selected = append(selected, fn)
+			}
+		}
+		if len(selected) == 0 {
+			return nil, Errorf("no function matches %s", spec)
+		}
+		if len(selected) > 1 {
+			return nil, Error(newAmbiguousFunctionError(spec, selected))
+		}
+		return selected, nil
+	}
+
+	if len(funcs) > 1 {
+		return nil, Error(newAmbiguousFunctionError(name, funcs))
+	}
+
+	return funcs, nil
+}
+
+// splitModuleSpec splits the module and the function of a module!function specification
+func splitModuleSpec(spec string) (module, function string) {
+	i := strings.Index(spec, "!")
+	if i <= 0 {
+		return "", spec
+	}
+
+	// operator! and operator!= are function names
+	module = spec[:i]
+	if strings.Contains(module, scopeSeparator) || strings.HasSuffix(module, "operator") {
+		return "", spec
+	}
+
+	return module, spec[i+1:]
+}
+
+// filterModule returns the functions of the module, which is matched by its base name,
+// the base name without version suffix (libfoo.so matches libfoo.so.1) or a glob pattern
+func filterModule(funcs []*FunctionEntry, module string) []*FunctionEntry {
+	filtered := make([]*FunctionEntry, 0, len(funcs))
+
+	for _, fn := range funcs {
+		name := fn.moduleName()
+		if name == module || strings.HasPrefix(name, module+".") {
+			filtered = append(filtered, fn)
+		} else if ok, _ := path.Match(module, name); ok {
+			filtered = append(filtered, fn)
+		}
+	}
+
+	return filtered
+}
+
+// splitFunctionSpec splits the name and the selector of a function specification
+func splitFunctionSpec(spec string) (name, selector string) {
+	if i := strings.LastIndex(spec, "#"); i > 0 {
+		return spec[:i], spec[i:]
+	}
+
+	if strings.HasSuffix(spec, ")") && !strings.HasSuffix(spec, anonymousNamespace) {
+		// find the parenthesis opening the parameter list
+		depth := 0
+		for i := len(spec) - 1; i > 0; i-- {
+			switch spec[i] {
+			case ')':
+				depth++
+			case '(':
+				depth--
+			}
+
+			if depth == 0 {
+				// the parentheses of the call operator are part of the name
+				if strings.HasSuffix(spec[:i], "operator") {
+					break
+				}
+				return spec[:i], spec[i:]
+			}
+		}
+	}
+
+	return spec, ""
+}
+
+// uniqueFunctions filters the functions without code and the duplicates at the same address
+func uniqueFunctions(funcs []*FunctionEntry) []*FunctionEntry {
+	seen := make(map[uintptr]bool)
+	unique := make([]*FunctionEntry, 0, len(funcs))
+
+	for _, fn := range funcs {
+		if fn.LowPC == 0 {
+			continue
+		}
+
+		addr := fn.LowPC + fn.StaticBase
+		if seen[addr] {
+			continue
+		}
+		seen[addr] = true
+
+		unique = append(unique, fn)
+	}
+
+	return unique
+}
+
+func newAmbiguousFunctionError(name string, funcs []*FunctionEntry) *AmbiguousFunctionError {
+	err := &AmbiguousFunctionError{Name: name}
+
+	for i, fn := range funcs {
+		c := FunctionCandidate{
+			Index:   i + 1,
+			Name:    fn.Name,
+			Address: fn.LowPC + fn.StaticBase,
+		}
+
+		if sig, e := fn.Signature(); e == nil {
+			c.Signature = sig.String()
+		}
+
+		if fn.Lib != nil || (fn.entry.data != nil && fn.entry.data.isLib) {
+			c.Library = fn.moduleName()
+		}
+
+		if fn.entry.data != nil {
+			if line, e := NewLineEntry(fn.LowPC, fn.entry.data); e == nil {
+				c.Source = fmt.Sprintf("%s:%d", line.Filename, line.Line)
+			}
+		}
+
+		err.Candidates = append(err.Candidates, c)
+	}
+
+	return err
+}
+
+// matchParams returns whether the parameter types match a parameter list like "(int, char *)"
+func (sig *Signature) matchParams(list string) bool {
+	list = strings.TrimSuffix(strings.TrimPrefix(list, "("), ")")
+
+	types := normalizeTypeName(list)
+	if types == "void" {
+		types = ""
+	}
+
+	params := make([]string, 0, len(sig.Params)+1)
+	for _, param := range sig.Params {
+		params = append(params, normalizeTypeName(param.Type))
+	}
+	if sig.Variadic {
+		params = append(params, "...")
+	}
+
+	// compared as a whole, since template arguments contain commas too
+	return types == strings.Join(params, ",")
+}
+
+// normalizeTypeName removes the whitespace from type names, so "char *" equals "char*"
+func normalizeTypeName(name string) string {
+	return strings.Join(strings.Fields(name), "")
+}
@@ -0,0 +1,282 @@
+package raztracer
+
+import (
+	"errors"
+	"testing"
+)
+
+func newSpecFunction(name string, lowPC uintptr, lib *SharedLibrary, sig *Signature) *FunctionEntry {
+	if sig != nil {
+		sig.Name = name
+	}
+
+	return &FunctionEntry{
+		Name:              name,
+		LowPC:             lowPC,
+		HighPC:            lowPC + 0x10,
+		BreakpointAddress: lowPC,
+		Lib:               lib,
+		signature:         sig,
+	}
+}
+
+func newSpecSignature(variadic bool, types ...string) *Signature {
+	sig := &Signature{Return: "int", Params: make([]Parameter, 0), Variadic: variadic}
+	for _, typ := range types {
+		sig.Params = append(sig.Params, Parameter{Type: typ})
+	}
+	return sig
+}
+
+func TestResolveFunction(t *testing.T) {
+	data := &DebugData{
+		functions: []*FunctionEntry{
+			newSpecFunction("foo", 0x100, nil, newSpecSignature(false, "int")),
+			newSpecFunction("foo", 0x200, nil, newSpecSignature(false, "char *")),
+			newSpecFunction("foo", 0x200, nil, nil), // duplicate at the same address
+			newSpecFunction("Cls::operator!", 0x300, nil, newSpecSignature(false)),
+			newSpecFunction("Cls::operator!=", 0x400, nil, newSpecSignature(false, "const Cls &")),
+			newSpecFunction("Cls::operator()", 0x500, nil, newSpecSignature(false, "int")),
+			newSpecFunction("log", 0x600, nil, newSpecSignature(true, "const char *")),
+			newSpecFunction("vlog", 0x700, nil, newSpecSignature(true)),
+			newSpecFunction("declared", 0, nil, nil), // no code
+		},
+	}
+
+	tests := []struct {
+		spec      string
+		addrs     []uintptr // nil if an error is expected
+		ambiguous bool
+	}{
+		{"foo", nil, true},
+		{"foo#*", []uintptr{0x100, 0x200}, false},
+		{"foo#1", []uintptr{0x100}, false},
+		{"foo#2", []uintptr{0x200}, false},
+		{"foo#0", nil, false},
+		{"foo#3", nil, false},
+		{"foo#-1", nil, false},
+		{"foo#x", nil, false},
+		{"foo(int)", []uintptr{0x100}, false},
+		{"foo(char*)", []uintptr{0x200}, false},
+		{"foo( char * )", []uintptr{0x200}, false},
+		{"foo(long)", nil, false},
+		{"foo()", nil, false},
+		{"operator!", []uintptr{0x300}, false},
+		{"operator!=", []uintptr{0x400}, false},
+		{"Cls::operator!=(const Cls &)", []uintptr{0x400}, false},
+		{"operator!(void)", []uintptr{0x300}, false},
+		{"operator()", []uintptr{0x500}, false},
+		{"operator()(int)", []uintptr{0x500}, false},
+		{"log(const char *, ...)", []uintptr{0x600}, false},
+		{"log(const char *)", nil, false},
+		{"log(...)", nil, false},
+		{"vlog(...)", []uintptr{0x700}, false},
+		{"vlog()", nil, false},
+		{"declared", nil, false},
+		{"missing", nil, false},
+	}
+
+	for _, test := range tests {
+		funcs, err := data.ResolveFunction(test.spec)
+
+		var ambiguous *AmbiguousFunctionError
+		if errors.As(err, &ambiguous) != test.ambiguous {
+			t.Errorf("%s: unexpected error: %v", test.spec, err)
+			continue
+		}
+
+		if test.addrs == nil {
+			if err == nil {
+				t.Errorf("%s: no error, got %d functions", test.spec, len(funcs))
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %v", test.spec, err)
+			continue
+		}
+
+		if len(funcs) != len(test.addrs) {
+			t.Errorf("%s: got %d functions, expected %d", test.spec, len(funcs), len(test.addrs))
+			continue
+		}
+		for i, fn := range funcs {
+			if fn.LowPC != test.addrs[i] {
+				t.Errorf("%s: got %s at %#x, expected %#x", test.spec, fn.Name, fn.LowPC, test.addrs[i])
+			}
+		}
+	}
+}
+
+func TestResolveFunctionAmbiguous(t *testing.T) {
+	data := &DebugData{
+		functions: []*FunctionEntry{
+			newSpecFunction("foo", 0x100, nil, newSpecSignature(false, "int")),
+			newSpecFunction("foo", 0x200, nil, newSpecSignature(false, "int")),
+		},
+	}
+
+	for _, spec := range []string{"foo", "foo(int)"} {
+		_, err := data.ResolveFunction(spec)
+
+		var ambiguous *AmbiguousFunctionError
+		if !errors.As(err, &ambiguous) {
+			t.Errorf("%s: expected an ambiguity error, got %v", spec, err)
+			continue
+		}
+
+		if ambiguous.Name != spec || len(ambiguous.Candidates) != 2 {
+			t.Errorf("%s: unexpected error: %+v", spec, ambiguous)
+			continue
+		}
+		for i, c := range ambiguous.Candidates {
+			if c.Index != i+1 || c.Address != uintptr(i+1)*0x100 || c.Signature != "int foo(int)" {
+				t.Errorf("%s: unexpected candidate: %+v", spec, c)
+			}
+		}
+	}
+}
+
+func TestSplitFunctionSpec(t *testing.T) {
+	tests := []struct {
+		spec, name, selector string
+	}{
+		{"foo", "foo", ""},
+		{"foo#2", "foo", "#2"},
+		{"foo#*", "foo", "#*"},
+		{"foo()", "foo", "()"},
+		{"foo(int, char *)", "foo", "(int, char *)"},
+		{"foo(void (*)(int))", "foo", "(void (*)(int))"},
+		{"operator()", "operator()", ""},
+		{"operator()(int)", "operator()", "(int)"},
+		{"Cls::operator()#2", "Cls::operator()", "#2"},
+		{"(anonymous namespace)::foo", "(anonymous namespace)::foo", ""},
+		{"ns::(anonymous namespace)", "ns::(anonymous namespace)", ""},
+		{"#1", "#1", ""},
+	}
+
+	for _, test := range tests {
+		name, selector := splitFunctionSpec(test.spec)
+		if name != test.name || selector != test.selector {
+			t.Errorf("%s: got %q and %q, expected %q and %q", test.spec, name, selector, test.name, test.selector)
+		}
+	}
+}
+
+func TestMatchParams(t *testing.T) {
+	tests := []struct {
+		sig   *Signature
+		list  string
+		match bool
+	}{
+		{newSpecSignature(false), "()", true},
+		{newSpecSignature(false), "(void)", true},
+		{newSpecSignature(false), "( void )", true},
+		{newSpecSignature(false), "(int)", false},
+		{newSpecSignature(false, "int"), "(int)", true},
+		{newSpecSignature(false, "int"), "()", false},
+		{newSpecSignature(false, "int", "char *"), "(int, char *)", true},
+		{newSpecSignature(false, "int", "char *"), "(int,char*)", true},
+		{newSpecSignature(false, "int", "char *"), "(int)", false},
+		{newSpecSignature(false, "int", "char *"), "(char *, int)", false},
+		{newSpecSignature(false, "std::map<int, int>"), "(std::map<int,int>)", true},
+		{newSpecSignature(true), "(...)", true},
+		{newSpecSignature(true), "()", false},
+		{newSpecSignature(true, "const char *"), "(const char *, ...)", true},
+		{newSpecSignature(true, "const char *"), "(const char *)", false},
+		{newSpecSignature(false, "const char *"), "(const char *, ...)", false},
+	}
+
+	for _, test := range tests {
+		if match := test.sig.matchParams(test.list); match != test.match {
+			t.Errorf("%s matched %s: %v, expected %v", test.list, test.sig, match, test.match)
+		}
+	}
+}
+
+func TestSplitModuleSpec(t *testing.T) {
+	tests := []struct {
+		spec, module, function string
+	}{
+		{"malloc", "", "malloc"},
+		{"libc.so.6!malloc", "libc.so.6", "malloc"},
+		{"libfoo.so!foo#2", "libfoo.so", "foo#2"},
+		{"lib*.so!foo", "lib*.so", "foo"},
+		{"!foo", "", "!foo"},
+		{"operator!", "", "operator!"},
+		{"operator!=", "", "operator!="},
+		{"operator!(void)", "", "operator!(void)"},
+		{"Cls::operator!", "", "Cls::operator!"},
+		{"Cls::operator!=#1", "", "Cls::operator!=#1"},
+		{"libfoo.so!operator!", "libfoo.so", "operator!"},
+		{"libfoo.so!Cls::operator!=", "libfoo.so", "Cls::operator!="},
+	}
+
+	for _, test := range tests {
+		module, function := splitModuleSpec(test.spec)
+		if module != test.module || function != test.function {
+			t.Errorf("%s: got %q and %q, expected %q and %q", test.spec, module, function, test.module, test.function)
+		}
+	}
+}
+
+func TestResolveFunctionInModule(t *testing.T) {
+	libfoo := &SharedLibrary{Name: "/usr/lib/libfoo.so.1", StaticBase: 0x10000}
+	libbar := &SharedLibrary{Name: "/usr/lib/libbar.so", StaticBase: 0x20000}
+
+	data := &DebugData{
+		functions: []*FunctionEntry{
+			newSpecFunction("init", 0x100, libfoo, nil),
+			newSpecFunction("init", 0x100, libbar, nil),
+			newSpecFunction("Cls::operator!", 0x200, libfoo, nil),
+			newSpecFunction("Cls::operator!", 0x200, libbar, nil),
+		},
+	}
+	for _, fn := range data.functions {
+		fn.StaticBase = fn.Lib.StaticBase
+	}
+
+	tests := []struct {
+		spec string
+		lib  *SharedLibrary // nil if an error is expected
+	}{
+		{"libfoo.so.1!init", libfoo},
+		{"libfoo.so!init", libfoo},
+		{"libfoo!init", libfoo},
+		{"libbar.so!init", libbar},
+		{"libbar.so!init#1", libbar},
+		{"libbar.so!init#2", nil},
+		{"lib*r.so!init", libbar},
+		{"libfoo.so!Cls::operator!", libfoo},
+		{"libbar.so!operator!", libbar},
+		{"libbaz.so!init", nil},
+		{"libfo!init", nil},
+	}
+
+	for _, test := range tests {
+		funcs, err := data.ResolveFunction(test.spec)
+		if test.lib == nil {
+			if err == nil {
+				t.Errorf("%s: no error, got %d functions", test.spec, len(funcs))
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %v", test.spec, err)
+		} else if len(funcs) != 1 || funcs[0].Lib != test.lib {
+			t.Errorf("%s: got %d functions, expected the one in %s", test.spec, len(funcs), test.lib.Name)
+		}
+	}
+
+	// the ambiguity error tells the module of the candidates
+	_, err := data.ResolveFunction("init")
+	var ambiguous *AmbiguousFunctionError
+	if !errors.As(err, &ambiguous) || len(ambiguous.Candidates) != 2 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lib := ambiguous.Candidates[0].Library; lib != "libfoo.so.1" {
+		t.Errorf("the first candidate is in %q", lib)
+	}
+}
@@ -3,6 +3,7 @@ package raztracer
 import (
 	"debug/dwarf"
 	"debug/elf"
+	"encoding/hex"
 	"fmt"
 
 	"github.com/razzie/raztracer/internal/dwarf/op"
@@ -13,12 +14,16 @@ type FunctionEntry struct {
 	entry             DebugEntry
 	variables         []*VariableEntry
 	globals           []*VariableEntry
+	signature         *Signature
 	Name              string
 	HighPC            uintptr
 	LowPC             uintptr
+	Ranges            [][2]uintptr // address ranges of split functions (e.g. hot/cold parts)
 	StaticBase        uintptr
 	BreakpointAddress uintptr
 	Lib               *SharedLibrary
+	StaticFile        string // defining source file of static functions
+	cu                *CUEntry
 }
 
 // NewFunctionEntry returns a new FunctionEntry
@@ -37,6 +42,7 @@ func NewFunctionEntry(de DebugEntry) (*FunctionEntry, error) {
 		StaticBase: de.data.staticBase,
 	}
 
+	fn.setRanges()
 	fn.BreakpointAddress, _ = fn.getBreakpointAddress()
 
 	return fn, nil
@@ -64,6 +70,7 @@ func (fn *FunctionEntry) GetVariables() ([]*VariableEntry, error) {
 	}
 
 	if fn.variables != nil {
+		fn.entry.data.budget.useDetail(fn.cu, 0)
 		return fn.variables, nil
 	}
 
@@ -106,9 +113,43 @@ func (fn *FunctionEntry) GetVariables() ([]*VariableEntry, error) {
 	}
 
 	fn.variables = vars
+	fn.entry.data.budget.useDetail(fn.cu, variablesSize(vars))
 	return vars, MergeErrors(errors)
 }
 
+// GetRegisterArgs returns the first 'count' integer arguments of the function according
+// to the calling convention of the platform. The result is only valid at the entry point.
+func (fn *FunctionEntry) GetRegisterArgs(regs *op.DwarfRegisters, count int) []Reading {
+	if count > len(ArgRegNums) {
+		count = len(ArgRegNums)
+	}
+
+	args := make([]Reading, 0, count)
+
+	for i := 0; i < count; i++ {
+		dreg, ok := AsmToDwarfReg(ArgRegNums[i])
+		if !ok {
+			break
+		}
+
+		data := make([]byte, SizeofPtr)
+		if SizeofPtr == 4 {
+			regs.ByteOrder.PutUint32(data, uint32(regs.Uint64Val(dreg)))
+		} else {
+			regs.ByteOrder.PutUint64(data, regs.Uint64Val(dreg))
+		}
+
+		args = append(args, Reading{
+			Name:     fmt.Sprintf("#%d", i+1),
+			Size:     int64(SizeofPtr),
+			Location: fmt.Sprintf("DW_OP_reg%d", dreg),
+			Value:    "0x" + hex.EncodeToString(data),
+		})
+	}
+
+	return args
+}
+
 // GetFrameBase returns the frame base at PC
 func (fn *FunctionEntry) GetFrameBase(pc uintptr, regs *op.DwarfRegisters) (uintptr, error) {
 	if pc > fn.StaticBase {
@@ -128,6 +169,39 @@ func (fn *FunctionEntry) GetFrameBase(pc uintptr, regs *op.DwarfRegisters) (uint
 	return loc.address, Error(err)
 }
 
+// firstLineAddresses returns the addresses of every statement of the first source line
+// after the prologue, which can be split by early-exit branches in optimized code
+func (fn *FunctionEntry) firstLineAddresses() ([]uintptr, error) {
+	if fn.entry.data == nil || fn.BreakpointAddress == fn.LowPC {
+		return nil, Errorf("no line info for %s", fn.Name)
+	}
+
+	first, err := NewLineEntry(fn.BreakpointAddress, fn.entry.data)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	line, err := NewLineEntry(fn.LowPC, fn.entry.data)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	var addrs []uintptr
+	for ; line != nil && fn.containsAddress(line.Address); line, err = line.Next() {
+		if line.IsStmt && line.Line == first.Line && line.Filename == first.Filename {
+			if len(addrs) == 0 || addrs[len(addrs)-1] != line.Address {
+				addrs = append(addrs, line.Address)
+			}
+		}
+	}
+
+	if err != nil && len(addrs) == 0 {
+		return nil, Error(err)
+	}
+
+	return addrs, nil
+}
+
 func (fn *FunctionEntry) getBreakpointAddress() (uintptr, error) {
 	line, err := NewLineEntry(fn.LowPC, fn.entry.data)
 	if err != nil {
@@ -139,6 +213,10 @@ func (fn *FunctionEntry) getBreakpointAddress() (uintptr, error) {
 			return fn.LowPC, Error(err)
 		}
 
+		if !fn.containsAddress(line.Address) {
+			break
+		}
+
 		if line.IsStmt {
 			return line.Address, nil
 		}
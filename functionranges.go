@@ -0,0 +1,47 @@
+package raztracer
+
+import (
+	"debug/dwarf"
+)
+
+// setRanges reads the address ranges of the function. Functions split into multiple
+// ranges (DW_AT_ranges) get the entry point as LowPC and the end of its range as HighPC.
+func (fn *FunctionEntry) setRanges() {
+	ranges, err := fn.entry.Ranges()
+	if err != nil || len(ranges) == 0 {
+		return
+	}
+
+	fn.Ranges = ranges
+
+	if fn.LowPC == 0 {
+		if entryPC, ok := fn.entry.Val(dwarf.AttrEntrypc).(uint64); ok {
+			fn.LowPC = uintptr(entryPC)
+		} else {
+			fn.LowPC = ranges[0][0]
+		}
+	}
+
+	// DWARF 4 encodes the high PC as an offset, which is resolved by the range list
+	for _, rng := range ranges {
+		if fn.LowPC >= rng[0] && fn.LowPC < rng[1] {
+			fn.HighPC = rng[1]
+			break
+		}
+	}
+}
+
+// containsAddress returns whether the address without static base is in the function
+func (fn *FunctionEntry) containsAddress(addr uintptr) bool {
+	if len(fn.Ranges) == 0 {
+		return addr >= fn.LowPC && addr < fn.HighPC
+	}
+
+	for _, rng := range fn.Ranges {
+		if addr >= rng[0] && addr < rng[1] {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,105 @@
+package raztracer
+
+import (
+	"debug/elf"
+	"testing"
+)
+
+func TestContainsAddress(t *testing.T) {
+	contiguous := &FunctionEntry{LowPC: 0x100, HighPC: 0x180}
+	split := &FunctionEntry{LowPC: 0x110, HighPC: 0x180, Ranges: [][2]uintptr{{0x100, 0x180}, {0x400, 0x420}}}
+
+	tests := []struct {
+		fn       *FunctionEntry
+		addr     uintptr
+		contains bool
+	}{
+		{contiguous, 0xff, false},
+		{contiguous, 0x100, true},
+		{contiguous, 0x17f, true},
+		{contiguous, 0x180, false},
+		{contiguous, 0x400, false},
+		{split, 0x100, true}, // before the entry point, but in the range
+		{split, 0x17f, true},
+		{split, 0x180, false},
+		{split, 0x3ff, false},
+		{split, 0x400, true},
+		{split, 0x41f, true},
+		{split, 0x420, false},
+	}
+
+	for _, test := range tests {
+		if contains := test.fn.containsAddress(test.addr); contains != test.contains {
+			t.Errorf("%#x in %v: got %v, expected %v", test.addr, test.fn.Ranges, contains, test.contains)
+		}
+	}
+}
+
+func TestFunctionRanges(t *testing.T) {
+	if len(splitPath) == 0 {
+		t.Skip("the test program could not be compiled")
+	}
+
+	data, err := NewDebugDataFromPath(splitPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	funcs := data.GetFunctionsByName("split", true)
+	if len(funcs) != 1 {
+		t.Fatalf("got %d functions named split", len(funcs))
+	}
+	fn := funcs[0]
+	if len(fn.Ranges) < 2 {
+		t.Skip("the compiler didn't split the function")
+	}
+
+	// the entry point is the address of the symbol, not the start of the first range
+	entry := elfSymbolAddress(t, "split")
+	if fn.LowPC != entry {
+		t.Errorf("LowPC is %#x, the entry point is %#x", fn.LowPC, entry)
+	}
+	for _, rng := range fn.Ranges {
+		if fn.LowPC >= rng[0] && fn.LowPC < rng[1] && fn.HighPC != rng[1] {
+			t.Errorf("HighPC is %#x, the range of the entry point ends at %#x", fn.HighPC, rng[1])
+		}
+	}
+	if fn.BreakpointAddress < fn.LowPC || fn.BreakpointAddress >= fn.HighPC {
+		t.Errorf("the breakpoint address %#x is outside of %#x-%#x", fn.BreakpointAddress, fn.LowPC, fn.HighPC)
+	}
+
+	// the cold part is found by PC
+	cold := elfSymbolAddress(t, "split.cold")
+	if found, err := data.GetFunctionFromPC(cold); err != nil || found != fn {
+		t.Errorf("split.cold at %#x resolves to %v: %v", cold, found, err)
+	}
+	for _, rng := range fn.Ranges {
+		if found, _ := data.GetFunctionFromPC(rng[1] - 1); found != fn {
+			t.Errorf("the end of the range %#x-%#x resolves to %v", rng[0], rng[1], found)
+		}
+	}
+}
+
+func elfSymbolAddress(t *testing.T, name string) uintptr {
+	t.Helper()
+
+	file, err := elf.Open(splitPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	symbols, err := file.Symbols()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, symbol := range symbols {
+		if symbol.Name == name {
+			return uintptr(symbol.Value)
+		}
+	}
+
+	t.Skipf("symbol %s not found", name)
+	return 0
+}
@@ -6,4 +6,6 @@ require (
 	github.com/gdamore/tcell v1.3.0
 	github.com/go-delve/delve v1.3.2
 	github.com/rivo/tview v0.0.0-20191018125527-685bf6da76c2
+	golang.org/x/arch v0.0.0-20190927153633-4e8777c89be4
+	gopkg.in/yaml.v2 v2.2.8
 )
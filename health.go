@@ -0,0 +1,191 @@
+package raztracer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ModuleStatus tells how much debug information was loaded from a module
+type ModuleStatus string
+
+// Module statuses
+const (
+	ModuleDebugInfo   ModuleStatus = "debug_info"   // DWARF debug info loaded
+	ModuleSymbolsOnly ModuleStatus = "symbols_only" // only the ELF symbol table is available
+	ModuleNoSymbols   ModuleStatus = "no_symbols"   // neither debug info nor symbols (stripped)
+	ModuleSkipped     ModuleStatus = "skipped"      // rejected by the library filter
+	ModuleUnresolved  ModuleStatus = "unresolved"   // the file could not be opened or parsed
+)
+
+// supported DWARF versions
+const (
+	minDwarfVersion = 2
+	maxDwarfVersion = 4
+)
+
+// ModuleHealth describes what was loaded from the executable or a shared library
+type ModuleHealth struct {
+	Name          string       `json:"name"`
+	Path          string       `json:"path,omitempty"`
+	Status        ModuleStatus `json:"status"`
+	DwarfVersions []int        `json:"dwarf_versions,omitempty"`
+	Functions     int          `json:"functions"`
+	Globals       int          `json:"globals"`
+	Error         string       `json:"error,omitempty"`
+	Warnings      []string     `json:"warnings,omitempty"`
+}
+
+// HealthReport summarizes the debug information available for the traced process
+type HealthReport struct {
+	Executable ModuleHealth   `json:"exe"`
+	Libraries  []ModuleHealth `json:"libs"`
+	JIT        bool           `json:"jit"`
+	Python     string         `json:"python,omitempty"`
+}
+
+// HealthReport returns what was and wasn't loaded for the traced process,
+// so it is clear up front why some names or variables are missing
+func (t *Tracer) HealthReport() *HealthReport {
+	d := t.debugData
+
+	report := &HealthReport{
+		Executable: ModuleHealth{
+			Name:          t.exe.Path,
+			Status:        ModuleDebugInfo,
+			DwarfVersions: d.dwarfVersions,
+			Globals:       len(d.globals),
+		},
+		JIT: d.jit != nil && len(d.jit.functions) > 0,
+	}
+
+	for _, fn := range d.functions {
+		if fn.entry.data == d {
+			report.Executable.Functions++
+		}
+	}
+
+	report.Executable.checkDwarfVersions()
+
+	for _, health := range d.libHealth {
+		health.checkDwarfVersions()
+		report.Libraries = append(report.Libraries, health)
+	}
+
+	for _, lib := range d.skippedLibs {
+		report.Libraries = append(report.Libraries, ModuleHealth{
+			Name:   lib.Name,
+			Path:   lib.path(),
+			Status: ModuleSkipped,
+		})
+	}
+
+	sort.SliceStable(report.Libraries, func(i, j int) bool {
+		return report.Libraries[i].Name < report.Libraries[j].Name
+	})
+
+	if t.python != nil {
+		report.Python = t.python.Version()
+	}
+
+	return report
+}
+
+// String returns the report in a human readable form
+func (report *HealthReport) String() string {
+	var sb strings.Builder
+
+	modules := append([]ModuleHealth{report.Executable}, report.Libraries...)
+	for _, m := range modules {
+		fmt.Fprintf(&sb, "%s: %s", m.Name, m.Status)
+
+		if len(m.DwarfVersions) > 0 {
+			fmt.Fprintf(&sb, " (DWARF %s)", joinInts(m.DwarfVersions, ", "))
+		}
+
+		if m.Status == ModuleDebugInfo || m.Status == ModuleSymbolsOnly {
+			fmt.Fprintf(&sb, ", %d functions, %d globals", m.Functions, m.Globals)
+		}
+
+		if len(m.Error) > 0 {
+			fmt.Fprintf(&sb, ", error: %s", m.Error)
+		}
+
+		sb.WriteString("\n")
+
+		for _, warning := range m.Warnings {
+			fmt.Fprintf(&sb, "  warning: %s\n", warning)
+		}
+	}
+
+	if report.JIT {
+		sb.WriteString("JIT symbols loaded\n")
+	}
+
+	if len(report.Python) > 0 {
+		fmt.Fprintf(&sb, "Python %s interpreter detected\n", report.Python)
+	}
+
+	return sb.String()
+}
+
+func (health *ModuleHealth) checkDwarfVersions() {
+	for _, version := range health.DwarfVersions {
+		if version < minDwarfVersion || version > maxDwarfVersion {
+			health.Warnings = append(health.Warnings, fmt.Sprintf(
+				"DWARF version %d is not fully supported, variable locations may be missing", version))
+		}
+	}
+}
+
+func (d *DebugData) setLibHealth(health ModuleHealth) {
+	for i := range d.libHealth {
+		if d.libHealth[i].Name == health.Name {
+			d.libHealth[i] = health
+			return
+		}
+	}
+
+	d.libHealth = append(d.libHealth, health)
+}
+
+// parseDwarfVersions returns the distinct versions of the compilation unit headers in .debug_info
+func parseDwarfVersions(data []byte, order binary.ByteOrder) []int {
+	found := make(map[int]bool)
+
+	for len(data) >= 6 {
+		length := uint64(order.Uint32(data))
+		header := 4
+		if length == 0xffffffff { // 64-bit DWARF
+			if len(data) < 14 {
+				break
+			}
+			length = order.Uint64(data[4:])
+			header = 12
+		}
+
+		found[int(order.Uint16(data[header:]))] = true
+
+		if length == 0 || uint64(len(data)-header) < length {
+			break
+		}
+		data = data[uint64(header)+length:]
+	}
+
+	versions := make([]int, 0, len(found))
+	for version := range found {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+
+	return versions
+}
+
+func joinInts(values []int, sep string) string {
+	str := make([]string, len(values))
+	for i, v := range values {
+		str[i] = fmt.Sprint(v)
+	}
+	return strings.Join(str, sep)
+}
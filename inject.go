@@ -0,0 +1,237 @@
+package raztracer
+
+import (
+	"syscall"
+)
+
+// RTLD_NOW flag for dlopen
+const rtldNow = 2
+
+// CallFunction calls the function at 'addr' in the context of the stopped thread
+// using the platform calling convention and returns the integer return value.
+// The registers of the thread are restored after the call.
+func (t *Tracer) CallFunction(addr uintptr, args ...uint) (uint, error) {
+	ret, err := t.callFunction(addr, nil, args...)
+	return ret, Error(err)
+}
+
+// InjectLibrary loads the shared library at 'path' into the traced process
+// by calling dlopen() and returns the library handle
+func (t *Tracer) InjectLibrary(path string) (uintptr, error) {
+	dlopen, err := t.findLibFunction("dlopen", "__libc_dlopen_mode")
+	if err != nil {
+		return 0, Error(err)
+	}
+
+	handle, err := t.callFunction(dlopen, append([]byte(path), 0), rtldNow)
+	if err != nil {
+		return 0, Error(err)
+	}
+
+	if handle == 0 {
+		return 0, Errorf("dlopen failed: %s", t.dlerror())
+	}
+
+	return uintptr(handle), nil
+}
+
+func (t *Tracer) dlerror() string {
+	dlerror, err := t.findLibFunction("dlerror")
+	if err != nil {
+		return "unknown error"
+	}
+
+	msgAddr, err := t.callFunction(dlerror, nil)
+	if err != nil || msgAddr == 0 {
+		return "unknown error"
+	}
+
+	msg, _, err := readString(t.callThread(), uintptr(msgAddr), DefaultMaxStringLen)
+	if err != nil {
+		return "unknown error"
+	}
+
+	return string(msg)
+}
+
+func (t *Tracer) findLibFunction(names ...string) (uintptr, error) {
+	for _, name := range names {
+		funcs := t.debugData.GetFunctionsByName(name, true)
+		for _, fn := range funcs {
+			if fn.Lib != nil {
+				return fn.LowPC + fn.StaticBase, nil
+			}
+		}
+	}
+
+	return 0, Errorf("function not found: %s", names[0])
+}
+
+// callThread returns the stopped thread, or the main thread that is stopped right after attaching
+func (t *Tracer) callThread() Process {
+	if t.tid != 0 {
+		return t.tid
+	}
+
+	return t.pid
+}
+
+// callFunction calls the function at 'addr' in the stopped thread.
+// If 'data' is not nil, it is copied to the stack of the thread and its address
+// is passed as the first argument followed by 'args'.
+func (t *Tracer) callFunction(addr uintptr, data []byte, args ...uint) (uint, error) {
+	tid := t.callThread()
+
+	savedRegs, err := tid.GetRegs()
+	if err != nil {
+		return 0, Error(err)
+	}
+
+	regs := make([]uint, len(savedRegs))
+	copy(regs, savedRegs)
+
+	sp := uintptr(regs[SPRegNum]) - stackRedZone
+
+	if data != nil {
+		sp = (sp - uintptr(len(data))) &^ 15
+		err := tid.PokeData(sp, data)
+		if err != nil {
+			return 0, Error(err)
+		}
+
+		args = append([]uint{uint(sp)}, args...)
+	}
+
+	if len(args) > len(ArgRegNums) {
+		return 0, Errorf("too many arguments: %d", len(args))
+	}
+
+	// the return address is 0, so the thread stops with SIGSEGV when the function returns
+	sp = (sp &^ 15) - SizeofPtr
+	err = tid.PokeData(sp, make([]byte, SizeofPtr))
+	if err != nil {
+		return 0, Error(err)
+	}
+
+	for i, arg := range args {
+		regs[ArgRegNums[i]] = arg
+	}
+
+	regs[SPRegNum] = uint(sp)
+	regs[PCRegNum] = uint(addr)
+	regs[RetRegNum] = 0
+	regs[SyscallRegNum] = ^uint(0) // prevents syscall restart
+
+	err = tid.SetRegs(regs)
+	if err != nil {
+		return 0, Error(err)
+	}
+
+	ret, err := t.waitForCallReturn(tid)
+
+	restoreErr := tid.SetRegs(savedRegs)
+	if err != nil {
+		return 0, Error(err)
+	}
+
+	return ret, Error(restoreErr)
+}
+
+// waitForCallReturn continues the thread until the injected call returns.
+// The breakpoints hit during the call are stepped over, and the signals arriving
+// during the call are sent to the thread again, so they are reported by the next wait.
+func (t *Tracer) waitForCallReturn(tid Process) (uint, error) {
+	var pending []syscall.Signal
+	ret, err := t.continueCall(tid, &pending)
+
+	requeueErr := tid.requeueSignals(pending)
+	if err != nil {
+		return 0, Error(err)
+	}
+	if requeueErr != nil {
+		return ret, Error(requeueErr)
+	}
+
+	return ret, nil
+}
+
+func (t *Tracer) continueCall(tid Process, pending *[]syscall.Signal) (uint, error) {
+	for {
+		err := tid.ContWithSig(0)
+		if err != nil {
+			return 0, Error(err)
+		}
+
+		status, err := tid.waitStop()
+		if err != nil {
+			return 0, Error(err)
+		}
+
+		reason := DecodeWaitStatus(status)
+		if reason.Terminated() {
+			return 0, Errorf("process terminated during call injection")
+		}
+
+		if reason.Kind != StopSignalDelivery {
+			continue // ptrace event
+		}
+
+		if reason.IsTrap() {
+			stepped, err := t.stepOverCallBreakpoint(tid)
+			if err != nil {
+				return 0, Error(err)
+			}
+			if !stepped {
+				*pending = append(*pending, reason.Signal)
+			}
+			continue
+		}
+
+		if !reason.IsSignal(syscall.SIGSEGV) {
+			*pending = append(*pending, reason.Signal)
+			continue
+		}
+
+		regs, err := tid.GetRegs()
+		if err != nil {
+			return 0, Error(err)
+		}
+
+		if regs[PCRegNum] != 0 {
+			return 0, Errorf("segmentation fault during call injection at %#x", regs[PCRegNum])
+		}
+
+		return regs[RetRegNum], nil
+	}
+}
+
+// stepOverCallBreakpoint moves the thread back to the breakpoint it hit during an injected call
+// and steps over it. It returns false if the trap wasn't caused by a breakpoint of the tracer.
+func (t *Tracer) stepOverCallBreakpoint(tid Process) (bool, error) {
+	regs, err := tid.GetRegs()
+	if err != nil {
+		return false, Error(err)
+	}
+
+	addr := uintptr(regs[PCRegNum]) - trapInstructionSize
+	if bp, found := t.breakpoints[addr]; !found || !bp.IsEnabled() {
+		return false, nil
+	}
+
+	regs[PCRegNum] = uint(addr)
+	err = tid.SetRegs(regs)
+	if err != nil {
+		return false, Error(err)
+	}
+
+	// stepOverBreakpoint steps the current thread
+	savedTID := t.tid
+	t.tid = tid
+	err = t.stepOverBreakpoint()
+	t.tid = savedTID
+	if err != nil {
+		return true, Error(err)
+	}
+
+	return true, nil
+}
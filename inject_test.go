@@ -0,0 +1,117 @@
+package raztracer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestInjectLibrary(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if len(injectLibPath) == 0 {
+		t.Skip("the injected library could not be compiled")
+	}
+
+	cmd := startTracee(t, 50)
+	defer cmd.Process.Kill()
+
+	tracer, err := NewTracer(cmd.Process.Pid)
+	skipIfNotPermitted(t, err)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handle, err := tracer.InjectLibrary(injectLibPath)
+	if err != nil {
+		tracer.Detach()
+		t.Fatal(err)
+	}
+	if handle == 0 {
+		t.Error("dlopen returned a null handle")
+	}
+
+	maps, _ := ioutil.ReadFile(fmt.Sprintf("/proc/%d/maps", cmd.Process.Pid))
+	if !strings.Contains(string(maps), injectLibPath) {
+		t.Errorf("%s is not mapped in the test program", injectLibPath)
+	}
+
+	if _, err := tracer.DetachWithReport(); err != nil {
+		t.Fatal(err)
+	}
+
+	if status := waitTracee(t, cmd); status != 0 {
+		t.Errorf("the test program exited with %d after the injection", status)
+	}
+}
+
+func TestCallFunctionKeepsBreakpointsAndSignals(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	cmd := startTracee(t, 100)
+	defer cmd.Process.Kill()
+
+	tracer, err := NewTracer(cmd.Process.Pid)
+	skipIfNotPermitted(t, err)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tracer.SetBreakpointAtFunction("traced"); err != nil {
+		tracer.Detach()
+		t.Fatal(err)
+	}
+
+	fns := tracer.debugData.GetFunctionsByName("traced", true)
+	if len(fns) == 0 {
+		tracer.Detach()
+		t.Fatal("traced() not found")
+	}
+	traced := fns[0].LowPC + fns[0].StaticBase
+
+	// the signal arrives during the call and must not be lost
+	syscall.Kill(cmd.Process.Pid, syscall.SIGUSR1)
+
+	// the call hits the breakpoint at the entry of traced()
+	before, err := tracer.CallFunction(traced, 0)
+	if err != nil {
+		tracer.Detach()
+		t.Fatal(err)
+	}
+	after, err := tracer.CallFunction(traced, 5)
+	if err != nil {
+		tracer.Detach()
+		t.Fatal(err)
+	}
+	if after != before+5 {
+		t.Errorf("traced(5) returned %d after traced(0) returned %d", after, before)
+	}
+
+	tracer.Run()
+	gotSignal := false
+	for deadline := time.Now().Add(time.Second); !gotSignal && time.Now().Before(deadline); {
+		evt, err := tracer.WaitForEvent(100 * time.Millisecond)
+		if err != nil {
+			tracer.Detach()
+			t.Fatal(err)
+		}
+		gotSignal = evt != nil && evt.Signal == syscall.SIGUSR1
+	}
+	if !gotSignal {
+		t.Error("the SIGUSR1 received during the call was dropped")
+	}
+
+	if _, err := tracer.DetachWithReport(); err != nil {
+		t.Fatal(err)
+	}
+
+	if status := waitTracee(t, cmd); status != 1 {
+		t.Errorf("the test program handled %d SIGUSR1 and %d SIGCONT, expected 1 SIGUSR1", status%16, status/16)
+	}
+}
@@ -0,0 +1,162 @@
+package raztracer
+
+import (
+	"fmt"
+)
+
+// InstructionStep is a single step of an instruction trace
+type InstructionStep struct {
+	PC          uintptr           `json:"pc"`
+	Function    string            `json:"function,omitempty"`
+	Instruction string            `json:"instruction"`
+	Registers   map[string]string `json:"regs,omitempty"` // registers changed by the instruction
+}
+
+// InstructionTraceConfig contains the settings of an instruction trace
+type InstructionTraceConfig struct {
+	MaxSteps      int  // maximum number of traced instructions
+	UntilReturn   bool // stop when the thread returns from the current function
+	WithRegisters bool // record the registers changed by each instruction
+}
+
+// String returns the instruction step as a string
+func (step *InstructionStep) String() string {
+	str := fmt.Sprintf("%#x %s", step.PC, step.Instruction)
+	if len(step.Function) > 0 {
+		str = fmt.Sprintf("%#x <%s> %s", step.PC, step.Function, step.Instruction)
+	}
+
+	for reg, val := range step.Registers {
+		str += fmt.Sprintf(" %s=%s", reg, val)
+	}
+
+	return str
+}
+
+// TraceInstructions single-steps the stopped thread and records every executed instruction
+func (t *Tracer) TraceInstructions(cfg InstructionTraceConfig) ([]*InstructionStep, error) {
+	if t.tid == 0 {
+		return nil, Errorf("no stopped thread")
+	}
+
+	if cfg.MaxSteps <= 0 {
+		return nil, Errorf("invalid number of steps: %d", cfg.MaxSteps)
+	}
+
+	// the function returned when the thread is at the return address with the frame popped,
+	// a tail call leaves the function without either of those
+	var retCFA, retAddr uintptr
+	if cfg.UntilReturn {
+		var err error
+		retCFA, retAddr, err = t.returnPoint()
+		if err != nil {
+			return nil, Error(err)
+		}
+	}
+
+	var prevRegs map[string]string
+	if cfg.WithRegisters {
+		prevRegs, _ = t.GetRegisters()
+	}
+
+	steps := make([]*InstructionStep, 0, cfg.MaxSteps)
+
+	for len(steps) < cfg.MaxSteps {
+		pc, err := t.GetPC()
+		if err != nil {
+			return steps, Error(err)
+		}
+
+		if cfg.UntilReturn && pc == retAddr {
+			sp, err := t.getSP()
+			if err != nil {
+				return steps, Error(err)
+			}
+
+			if sp >= retCFA {
+				break
+			}
+		}
+
+		step := &InstructionStep{PC: pc}
+		if fn, _ := t.debugData.GetFunctionFromPC(pc); fn != nil {
+			step.Function = fn.Name
+		}
+
+		code := make([]byte, maxInstructionSize)
+		err = t.pid.PeekData(pc, code)
+		if err == nil {
+			step.Instruction, _, err = Disassemble(code, pc)
+		}
+		if err != nil {
+			step.Instruction = "(bad)"
+		}
+
+		err = t.stepInstruction(pc)
+		if err != nil {
+			return steps, Error(err)
+		}
+
+		if cfg.WithRegisters {
+			regs, err := t.GetRegisters()
+			if err == nil {
+				step.Registers = diffRegisters(prevRegs, regs)
+				prevRegs = regs
+			}
+		}
+
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
+// stepInstruction executes a single instruction even if there is a breakpoint at 'pc'
+func (t *Tracer) stepInstruction(pc uintptr) error {
+	bp, found := t.breakpoints[pc]
+	if found && bp.IsEnabled() {
+		return Error(t.stepOverBreakpoint())
+	}
+
+	return Error(t.tid.SingleStep())
+}
+
+// returnPoint returns the CFA and the return address of the innermost frame of the stopped thread
+func (t *Tracer) returnPoint() (uintptr, uintptr, error) {
+	it, err := NewStackIterator(t.tid, t.debugData)
+	if err != nil {
+		return 0, 0, Error(err)
+	}
+
+	if !it.Next() || it.retaddr == 0 {
+		if err := it.Err(); err != nil {
+			return 0, 0, Error(err)
+		}
+		return 0, 0, Errorf("return address not found at %#x", it.PC())
+	}
+
+	return uintptr(it.Registers().CFA), it.retaddr, nil
+}
+
+func (t *Tracer) getSP() (uintptr, error) {
+	regs, err := t.tid.GetRegs()
+	if err != nil {
+		return 0, Error(err)
+	}
+
+	return uintptr(regs[SPRegNum]), nil
+}
+
+func (fn *FunctionEntry) containsPC(pc uintptr) bool {
+	return pc >= fn.StaticBase && fn.containsAddress(pc-fn.StaticBase)
+}
+
+func diffRegisters(prev, current map[string]string) map[string]string {
+	changed := make(map[string]string)
+	for reg, val := range current {
+		if prev[reg] != val {
+			changed[reg] = val
+		}
+	}
+	return changed
+}
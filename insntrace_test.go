@@ -0,0 +1,72 @@
+package raztracer
+
+import (
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestTraceInstructionsUntilReturn(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	cmd := startTracee(t, 100)
+	defer cmd.Process.Kill()
+
+	tracer, err := NewTracer(cmd.Process.Pid)
+	skipIfNotPermitted(t, err)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tracer.SetBreakpointAtFunction("tail_caller"); err != nil {
+		tracer.Detach()
+		t.Fatal(err)
+	}
+
+	tracer.Run()
+	evt, err := tracer.WaitForEvent(time.Second)
+	if err != nil {
+		tracer.Detach()
+		t.Fatal(err)
+	}
+	if evt == nil || !evt.IsBreakpoint {
+		tracer.Detach()
+		t.Fatalf("expected a breakpoint event, got %v", evt)
+	}
+
+	// the signal arrives while stepping, it must not be mistaken for the end of a step
+	syscall.Kill(cmd.Process.Pid, syscall.SIGUSR1)
+
+	steps, err := tracer.TraceInstructions(InstructionTraceConfig{MaxSteps: 100, UntilReturn: true})
+	if err != nil || len(steps) == 0 {
+		tracer.Detach()
+		t.Fatal(steps, err)
+	}
+
+	// tail_caller jumps to tail_target, which returns to main
+	functions := make(map[string]int)
+	for _, step := range steps {
+		functions[step.Function]++
+	}
+	if functions["tail_caller"] == 0 || functions["tail_target"] == 0 || functions["main"] > 0 {
+		t.Errorf("unexpected steps:\n%v", steps)
+	}
+	if last := steps[len(steps)-1]; last.Function != "tail_target" {
+		t.Errorf("the last step is in %s instead of the return of tail_target", last.Function)
+	}
+
+	pc, _ := tracer.GetPC()
+	if fn, _ := tracer.GetDebugData().GetFunctionFromPC(pc); fn == nil || fn.Name != "main" {
+		t.Errorf("the thread is not back in main at %#x", pc)
+	}
+
+	if _, err := tracer.DetachWithReport(); err != nil {
+		t.Fatal(err)
+	}
+
+	if status := waitTracee(t, cmd); status != 1 {
+		t.Errorf("the test program handled %d SIGUSR1 and %d SIGCONT, expected 1 SIGUSR1", status%16, status/16)
+	}
+}
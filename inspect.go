@@ -0,0 +1,141 @@
+package raztracer
+
+import (
+	"debug/dwarf"
+	"debug/elf"
+	"os"
+)
+
+// FrameRange is the code range covered by a frame description entry (CFI)
+type FrameRange struct {
+	Begin uintptr `json:"begin"`
+	End   uintptr `json:"end"`
+}
+
+// NewDebugDataFromPath loads the debug data of an ELF file without a live process,
+// e.g. for offline inspection tools. The file can be built for any architecture.
+// The debug data is returned even if some sections failed to parse, the error lists them.
+// Close releases the file.
+func NewDebugDataFromPath(path string) (*DebugData, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	d, err := NewDebugData(file, 0)
+	if d == nil {
+		file.Close()
+		return nil, Error(err)
+	}
+
+	d.file = file
+
+	if err != nil {
+		return d, Error(err)
+	}
+	return d, nil
+}
+
+// Close releases the file opened by NewDebugDataFromPath
+func (d *DebugData) Close() error {
+	if d.file == nil {
+		return nil
+	}
+
+	err := d.file.Close()
+	d.file = nil
+	if err != nil {
+		return Error(err)
+	}
+	return nil
+}
+
+// Machine returns the architecture the binary was built for
+func (d *DebugData) Machine() elf.Machine {
+	return d.elfData.Machine
+}
+
+// AddressSize returns the size of pointers in the binary
+func (d *DebugData) AddressSize() int {
+	if d.elfData.Class == elf.ELFCLASS32 {
+		return 4
+	}
+
+	return 8
+}
+
+// IsNativeArch returns true if the binary can be traced on this platform
+func (d *DebugData) IsNativeArch() bool {
+	return d.elfData.Machine == coreMachine && d.AddressSize() == int(SizeofPtr)
+}
+
+// readAddress reads a pointer in the address size and byte order of the binary
+func (d *DebugData) readAddress(data []byte) uintptr {
+	if len(data) < d.AddressSize() {
+		return 0
+	}
+
+	if d.AddressSize() == 4 {
+		return uintptr(d.dwarfEndian.Uint32(data))
+	}
+
+	return uintptr(d.dwarfEndian.Uint64(data))
+}
+
+// GetFunctions returns the function entries of the binary and its loaded libraries
+func (d *DebugData) GetFunctions() []*FunctionEntry {
+	return d.functions
+}
+
+// GetCompilationUnits returns the compilation units of the binary
+func (d *DebugData) GetCompilationUnits() []*CUEntry {
+	return d.compUnits
+}
+
+// GetFrameRanges returns the code ranges covered by CFI,
+// the rules of an address can be retrieved by GetFrameContextFromPC
+func (d *DebugData) GetFrameRanges() []FrameRange {
+	var ranges []FrameRange
+	for _, frameEntries := range d.frameEntries {
+		for _, fde := range frameEntries {
+			ranges = append(ranges, FrameRange{
+				Begin: uintptr(fde.Begin()),
+				End:   uintptr(fde.End()),
+			})
+		}
+	}
+
+	return ranges
+}
+
+// GetLines returns the line table of the compilation unit
+func (cu *CUEntry) GetLines() ([]*LineEntry, error) {
+	lineReader, err := cu.entry.data.dwarfData.LineReader(cu.entry.entry)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	if lineReader == nil {
+		return nil, Errorf("%s CU doesn't have a line table", cu.entry.Name())
+	}
+
+	var lines []*LineEntry
+	var entry dwarf.LineEntry
+	for lineReader.Next(&entry) == nil {
+		if entry.File == nil {
+			continue
+		}
+
+		lines = append(lines, &LineEntry{
+			reader:   lineReader,
+			pos:      lineReader.Tell(),
+			Filename: entry.File.Name,
+			Address:  uintptr(entry.Address),
+			IsStmt:   entry.IsStmt,
+			Line:     uint(entry.Line),
+			Column:   uint(entry.Column),
+		})
+	}
+
+	return lines, nil
+}
@@ -6,6 +6,7 @@ package frame
 import (
 	"bytes"
 	"encoding/binary"
+	"sort"
 	"strings"
 
 	"github.com/razzie/raztracer/internal/dwarf/util"
@@ -47,6 +48,11 @@ func Parse(data []byte, order binary.ByteOrder, offset, staticBase uint64) Frame
 		pctx.entries[i].order = order
 	}
 
+	// FDEForPC does a binary search, but the linker doesn't sort .eh_frame
+	sort.Slice(pctx.entries, func(i, j int) bool {
+		return pctx.entries[i].Begin() < pctx.entries[j].Begin()
+	})
+
 	return pctx.entries
 }
 
@@ -55,7 +61,7 @@ func cieEntry(data []byte) bool {
 }
 
 func parselength(ctx *parseContext) parsefunc {
-	binary.Read(ctx.buf, binary.LittleEndian, &ctx.length)
+	binary.Read(ctx.buf, ctx.order, &ctx.length)
 
 	if ctx.length == 0 {
 		// ZERO terminator
@@ -169,6 +169,77 @@ func consts(opcode Opcode, ctxt *context) error {
 	return nil
 }
 
+func constu(opcode Opcode, ctxt *context) error {
+	num, _ := util.DecodeULEB128(ctxt.buf)
+	ctxt.stack = append(ctxt.stack, int64(num))
+	return nil
+}
+
+// constSize returns the operand size of the fixed size constant opcodes
+func constSize(opcode Opcode) int {
+	switch opcode {
+	case DW_OP_const1u, DW_OP_const1s:
+		return 1
+	case DW_OP_const2u, DW_OP_const2s:
+		return 2
+	case DW_OP_const4u, DW_OP_const4s:
+		return 4
+	default:
+		return 8
+	}
+}
+
+func constnu(opcode Opcode, ctxt *context) error {
+	buf := ctxt.buf.Next(constSize(opcode))
+	var num uint64
+	switch len(buf) {
+	case 1:
+		num = uint64(buf[0])
+	case 2:
+		num = uint64(ctxt.ByteOrder.Uint16(buf))
+	case 4:
+		num = uint64(ctxt.ByteOrder.Uint32(buf))
+	case 8:
+		num = ctxt.ByteOrder.Uint64(buf)
+	default:
+		return errors.New("truncated constant")
+	}
+	ctxt.stack = append(ctxt.stack, int64(num))
+	return nil
+}
+
+func constns(opcode Opcode, ctxt *context) error {
+	buf := ctxt.buf.Next(constSize(opcode))
+	var num int64
+	switch len(buf) {
+	case 1:
+		num = int64(int8(buf[0]))
+	case 2:
+		num = int64(int16(ctxt.ByteOrder.Uint16(buf)))
+	case 4:
+		num = int64(int32(ctxt.ByteOrder.Uint32(buf)))
+	case 8:
+		num = int64(ctxt.ByteOrder.Uint64(buf))
+	default:
+		return errors.New("truncated constant")
+	}
+	ctxt.stack = append(ctxt.stack, num)
+	return nil
+}
+
+// tlsaddress converts the offset on the top of the stack to an address in the TLS block of the module
+func tlsaddress(opcode Opcode, ctxt *context) error {
+	slen := len(ctxt.stack)
+	if slen == 0 {
+		return errors.New("empty OP stack")
+	}
+	if ctxt.TLSBase == 0 {
+		return errors.New("thread-local storage address is unknown")
+	}
+	ctxt.stack[slen-1] = int64(ctxt.TLSBase) + ctxt.stack[slen-1]
+	return nil
+}
+
 func framebase(opcode Opcode, ctxt *context) error {
 	num, _ := util.DecodeSLEB128(ctxt.buf)
 	ctxt.stack = append(ctxt.stack, ctxt.FrameBase+num)
@@ -157,6 +157,8 @@ const (
 	DW_OP_bit_piece           Opcode = 0x9d
 	DW_OP_implicit_value      Opcode = 0x9e
 	DW_OP_stack_value         Opcode = 0x9f
+
+	DW_OP_GNU_push_tls_address Opcode = 0xe0
 )
 
 var opcodeName = map[Opcode]string{
@@ -314,6 +316,8 @@ var opcodeName = map[Opcode]string{
 	DW_OP_bit_piece:           "DW_OP_bit_piece",
 	DW_OP_implicit_value:      "DW_OP_implicit_value",
 	DW_OP_stack_value:         "DW_OP_stack_value",
+
+	DW_OP_GNU_push_tls_address: "DW_OP_GNU_push_tls_address",
 }
 var opcodeArgs = map[Opcode]string{
 	DW_OP_addr:                "8",
@@ -470,9 +474,20 @@ var opcodeArgs = map[Opcode]string{
 	DW_OP_bit_piece:           "uu",
 	DW_OP_implicit_value:      "B",
 	DW_OP_stack_value:         "",
+
+	DW_OP_GNU_push_tls_address: "",
 }
 var oplut = map[Opcode]stackfn{
 	DW_OP_addr:           addr,
+	DW_OP_const1u:        constnu,
+	DW_OP_const2u:        constnu,
+	DW_OP_const4u:        constnu,
+	DW_OP_const8u:        constnu,
+	DW_OP_const1s:        constns,
+	DW_OP_const2s:        constns,
+	DW_OP_const4s:        constns,
+	DW_OP_const8s:        constns,
+	DW_OP_constu:         constu,
 	DW_OP_consts:         consts,
 	DW_OP_plus:           plus,
 	DW_OP_plus_uconst:    plusuconsts,
@@ -545,4 +560,7 @@ var oplut = map[Opcode]stackfn{
 	DW_OP_fbreg:          framebase,
 	DW_OP_piece:          piece,
 	DW_OP_call_frame_cfa: callframecfa,
+
+	DW_OP_form_tls_address:     tlsaddress,
+	DW_OP_GNU_push_tls_address: tlsaddress,
 }
@@ -7,6 +7,7 @@ import (
 
 type DwarfRegisters struct {
 	StaticBase uint64
+	TLSBase    uint64 // address of the module's TLS block in the current thread (0 if unknown)
 
 	CFA       int64
 	FrameBase int64
@@ -0,0 +1,389 @@
+package raztracer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	jitdumpMagic    = 0x4A695444 // "JiTD"
+	jitCodeLoad     = 0
+	jitCodeMove     = 1
+	jitCodeClose    = 3
+	jitRecordHeader = 16
+)
+
+// jitSymbols contains the symbols of JIT compiled code loaded from perf map and jitdump files
+type jitSymbols struct {
+	pid       Process
+	paths     []string            // discovered on demand, reset on library events
+	files     map[string]*jitFile // by path
+	functions []*FunctionEntry    // non-overlapping functions of every file sorted by address
+}
+
+// jitFile is a parsed symbol file, it's parsed again when its modification time or size changes
+type jitFile struct {
+	modTime   time.Time
+	size      int64
+	functions []*FunctionEntry // in load order
+}
+
+func newJITSymbols(pid Process) *jitSymbols {
+	return &jitSymbols{
+		pid:   pid,
+		files: make(map[string]*jitFile),
+	}
+}
+
+// LoadJITSymbols loads the symbols of JIT compiled code from /tmp/perf-<pid>.map
+// and the jitdump files mapped by the process. The symbols are used as a fallback
+// when a PC is not covered by any binary or library, and reloaded when the files change.
+func (d *DebugData) LoadJITSymbols(pid Process) error {
+	jit := newJITSymbols(pid)
+	d.jit = jit
+	return Error(jit.reload())
+}
+
+func (jit *jitSymbols) lookup(pc uintptr) *FunctionEntry {
+	if fn := jit.find(pc); fn != nil {
+		return fn
+	}
+
+	if changed, _ := jit.refresh(); changed {
+		return jit.find(pc)
+	}
+
+	return nil
+}
+
+func (jit *jitSymbols) find(pc uintptr) *FunctionEntry {
+	i := sort.Search(len(jit.functions), func(i int) bool {
+		return jit.functions[i].HighPC > pc
+	})
+
+	if i < len(jit.functions) && jit.functions[i].LowPC <= pc {
+		return jit.functions[i]
+	}
+
+	return nil
+}
+
+// invalidatePaths makes the next refresh look for new jitdump files in the memory map
+func (jit *jitSymbols) invalidatePaths() {
+	jit.paths = nil
+}
+
+func (jit *jitSymbols) discoverPaths() []string {
+	root := jit.pid.RootPath()
+	nspid := jit.pid.namespacePID()
+	paths := []string{fmt.Sprintf("%s/tmp/perf-%d.map", root, nspid)}
+
+	// jitdump files are mmapped by the JIT runtime as a marker for profilers
+	regions, _ := jit.pid.MemRegions()
+	for _, region := range regions {
+		if strings.HasSuffix(region.Pathname, fmt.Sprintf("jit-%d.dump", nspid)) {
+			paths = append(paths, root+region.Pathname)
+			break
+		}
+	}
+
+	return paths
+}
+
+// refresh parses the new and modified symbol files and drops the removed ones,
+// returns true if the symbols changed
+func (jit *jitSymbols) refresh() (bool, error) {
+	if jit.paths == nil {
+		jit.paths = jit.discoverPaths()
+	}
+
+	var errors []error
+	changed := false
+	files := make(map[string]*jitFile, len(jit.paths))
+
+	for _, path := range jit.paths {
+		stat, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+
+		if file, found := jit.files[path]; found && file.modTime.Equal(stat.ModTime()) && file.size == stat.Size() {
+			files[path] = file
+			continue
+		}
+
+		var funcs []*FunctionEntry
+		if strings.HasSuffix(path, ".map") {
+			funcs, err = parsePerfMap(path)
+		} else {
+			funcs, err = parseJitdump(path)
+		}
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+
+		files[path] = &jitFile{
+			modTime:   stat.ModTime(),
+			size:      stat.Size(),
+			functions: funcs,
+		}
+		changed = true
+	}
+
+	if len(files) != len(jit.files) {
+		changed = true
+	}
+
+	jit.files = files
+
+	if changed {
+		var functions []*FunctionEntry
+		for _, path := range jit.paths {
+			if file, found := files[path]; found {
+				functions = append(functions, file.functions...)
+			}
+		}
+
+		jit.functions = dedupJITFunctions(functions)
+	}
+
+	return changed, MergeErrors(errors)
+}
+
+// dedupJITFunctions sorts the functions by address and drops the ones overlapped
+// by a function loaded later, as code is often recompiled to a freed address
+// and perf maps are never cleaned up. 'functions' must be in load order.
+func dedupJITFunctions(functions []*FunctionEntry) []*FunctionEntry {
+	entries := make([]jitEntry, len(functions))
+	for i, fn := range functions {
+		entries[i] = jitEntry{fn: fn, seq: i}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].fn.LowPC < entries[j].fn.LowPC
+	})
+
+	results := make([]*FunctionEntry, 0, len(functions))
+	for _, entry := range dedupJITEntries(entries) {
+		results = append(results, entry.fn)
+	}
+	return results
+}
+
+type jitEntry struct {
+	fn  *FunctionEntry
+	seq int // position in load order
+}
+
+// dedupJITEntries keeps the latest entry of every group of overlapping entries
+// sorted by address, then does the same with the entries it doesn't overlap
+func dedupJITEntries(entries []jitEntry) []jitEntry {
+	var results []jitEntry
+
+	for start := 0; start < len(entries); {
+		end := start + 1
+		highPC := entries[start].fn.HighPC
+		latest := start
+		for ; end < len(entries) && entries[end].fn.LowPC < highPC; end++ {
+			if entries[end].fn.HighPC > highPC {
+				highPC = entries[end].fn.HighPC
+			}
+			if entries[end].seq > entries[latest].seq {
+				latest = end
+			}
+		}
+
+		if end-start == 1 {
+			results = append(results, entries[start])
+			start = end
+			continue
+		}
+
+		keep := entries[latest].fn
+		var before, after []jitEntry
+		for _, entry := range entries[start:end] {
+			switch {
+			case entry.fn.HighPC <= keep.LowPC:
+				before = append(before, entry)
+			case entry.fn.LowPC >= keep.HighPC:
+				after = append(after, entry)
+			}
+		}
+
+		results = append(results, dedupJITEntries(before)...)
+		results = append(results, entries[latest])
+		results = append(results, dedupJITEntries(after)...)
+		start = end
+	}
+
+	return results
+}
+
+func (jit *jitSymbols) reload() error {
+	_, err := jit.refresh()
+
+	if len(jit.files) == 0 {
+		return Errorf("no JIT symbol files found for process %d", jit.pid)
+	}
+
+	return Error(err)
+}
+
+func newJITFunctionEntry(lib *SharedLibrary, name string, addr, size uint64) *FunctionEntry {
+	return &FunctionEntry{
+		Name:              name,
+		LowPC:             uintptr(addr),
+		HighPC:            uintptr(addr + size),
+		BreakpointAddress: uintptr(addr),
+		Lib:               lib,
+	}
+}
+
+// parsePerfMap parses a perf map file where each line looks like:
+// START SIZE symbolname
+func parsePerfMap(path string) ([]*FunctionEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, Error(err)
+	}
+	defer file.Close()
+
+	lib := &SharedLibrary{Name: path}
+	var functions []*FunctionEntry
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), " ", 3)
+		if len(fields) != 3 {
+			continue
+		}
+
+		addr, err := strconv.ParseUint(strings.TrimPrefix(fields[0], "0x"), 16, 64)
+		if err != nil {
+			continue
+		}
+
+		size, err := strconv.ParseUint(strings.TrimPrefix(fields[1], "0x"), 16, 64)
+		if err != nil || size == 0 {
+			continue
+		}
+
+		functions = append(functions, newJITFunctionEntry(lib, fields[2], addr, size))
+	}
+
+	return functions, Error(scanner.Err())
+}
+
+// parseJitdump parses the code load and move records of a jitdump file
+func parseJitdump(path string) ([]*FunctionEntry, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	if len(data) < 40 {
+		return nil, Errorf("invalid jitdump file: %s", path)
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	switch {
+	case binary.LittleEndian.Uint32(data) == jitdumpMagic:
+	case binary.BigEndian.Uint32(data) == jitdumpMagic:
+		order = binary.BigEndian
+	default:
+		return nil, Errorf("invalid jitdump magic: %s", path)
+	}
+
+	lib := &SharedLibrary{Name: path}
+	functions := make(map[uint64]*FunctionEntry) // by code index
+	var loadOrder []uint64
+	offset := int(order.Uint32(data[8:]))
+
+	for offset+jitRecordHeader <= len(data) {
+		id := order.Uint32(data[offset:])
+		size := int(order.Uint32(data[offset+4:]))
+		if size < jitRecordHeader || offset+size > len(data) {
+			break // incomplete record at the end of the file
+		}
+
+		rec := data[offset+jitRecordHeader : offset+size]
+		offset += size
+
+		switch id {
+		case jitCodeLoad:
+			if len(rec) < 40 {
+				continue
+			}
+
+			codeAddr := order.Uint64(rec[16:])
+			codeSize := order.Uint64(rec[24:])
+			codeIndex := order.Uint64(rec[32:])
+			name := rec[40:]
+			if end := bytes.IndexByte(name, 0); end >= 0 {
+				name = name[:end]
+			}
+
+			if _, found := functions[codeIndex]; !found {
+				loadOrder = append(loadOrder, codeIndex)
+			}
+			functions[codeIndex] = newJITFunctionEntry(lib, string(name), codeAddr, codeSize)
+
+		case jitCodeMove:
+			if len(rec) < 48 {
+				continue
+			}
+
+			newAddr := order.Uint64(rec[24:])
+			codeIndex := order.Uint64(rec[40:])
+			if fn, found := functions[codeIndex]; found {
+				size := fn.HighPC - fn.LowPC
+				fn.LowPC = uintptr(newAddr)
+				fn.HighPC = fn.LowPC + size
+				fn.BreakpointAddress = fn.LowPC
+			}
+
+		case jitCodeClose:
+			offset = len(data)
+		}
+	}
+
+	results := make([]*FunctionEntry, 0, len(functions))
+	for _, codeIndex := range loadOrder {
+		results = append(results, functions[codeIndex])
+	}
+
+	return results, nil
+}
+
+// namespacePID returns the PID of the process in its own PID namespace
+func (pid Process) namespacePID() Process {
+	status, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return pid
+	}
+
+	for _, line := range strings.Split(string(status), "\n") {
+		if !strings.HasPrefix(line, "NSpid:") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		nspid, err := strconv.Atoi(fields[len(fields)-1])
+		if err != nil {
+			break
+		}
+
+		return Process(nspid)
+	}
+
+	return pid
+}
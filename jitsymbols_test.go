@@ -0,0 +1,236 @@
+package raztracer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTempFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "raztracer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestParsePerfMap(t *testing.T) {
+	path := writeTempFile(t, "perf-1.map", "1000 20 jitted_a\n0x2000 0x10 jitted b with spaces\nbroken line\n3000 0 empty\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	funcs, err := parsePerfMap(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(funcs) != 2 {
+		t.Fatalf("got %d functions, expected 2", len(funcs))
+	}
+
+	expected := []struct {
+		name      string
+		low, high uintptr
+	}{
+		{"jitted_a", 0x1000, 0x1020},
+		{"jitted b with spaces", 0x2000, 0x2010},
+	}
+	for i, e := range expected {
+		if fn := funcs[i]; fn.Name != e.name || fn.LowPC != e.low || fn.HighPC != e.high {
+			t.Errorf("got %s %#x-%#x, expected %s %#x-%#x", fn.Name, fn.LowPC, fn.HighPC, e.name, e.low, e.high)
+		}
+	}
+}
+
+func TestDedupJITFunctions(t *testing.T) {
+	fn := func(name string, low, high uintptr) *FunctionEntry {
+		return &FunctionEntry{Name: name, LowPC: low, HighPC: high}
+	}
+
+	tests := []struct {
+		name      string
+		functions []*FunctionEntry // in load order
+		expected  []string         // sorted by address
+	}{
+		{"disjoint", []*FunctionEntry{fn("b", 0x20, 0x30), fn("a", 0x10, 0x20)}, []string{"a", "b"}},
+		{"stale duplicate", []*FunctionEntry{fn("old", 0x10, 0x20), fn("new", 0x10, 0x20)}, []string{"new"}},
+		{"partial overlap", []*FunctionEntry{fn("old", 0x10, 0x20), fn("new", 0x18, 0x28)}, []string{"new"}},
+		{"older inside newer", []*FunctionEntry{fn("a", 0x10, 0x14), fn("b", 0x18, 0x1c), fn("new", 0x10, 0x20)}, []string{"new"}},
+		{"newer inside older", []*FunctionEntry{fn("old", 0x10, 0x40), fn("new", 0x20, 0x28)}, []string{"new"}},
+		{"chain", []*FunctionEntry{fn("a", 0x10, 0x20), fn("c", 0x28, 0x38), fn("b", 0x18, 0x2c)}, []string{"b"}},
+		{"uncovered neighbours", []*FunctionEntry{fn("a", 0x10, 0x20), fn("b", 0x18, 0x30), fn("c", 0x40, 0x50), fn("new", 0x20, 0x48)}, []string{"a", "new"}},
+	}
+
+	for _, test := range tests {
+		var names []string
+		for _, fn := range dedupJITFunctions(test.functions) {
+			names = append(names, fn.Name)
+		}
+
+		if fmt.Sprint(names) != fmt.Sprint(test.expected) {
+			t.Errorf("%s: got %v, expected %v", test.name, names, test.expected)
+		}
+	}
+}
+
+type jitdumpWriter struct {
+	buf bytes.Buffer
+}
+
+func newJitdumpWriter() *jitdumpWriter {
+	w := &jitdumpWriter{}
+	header := make([]byte, 40)
+	binary.LittleEndian.PutUint32(header, jitdumpMagic)
+	binary.LittleEndian.PutUint32(header[4:], 1)
+	binary.LittleEndian.PutUint32(header[8:], 40)
+	w.buf.Write(header)
+	return w
+}
+
+func (w *jitdumpWriter) record(id uint32, body []byte) {
+	header := make([]byte, jitRecordHeader)
+	binary.LittleEndian.PutUint32(header, id)
+	binary.LittleEndian.PutUint32(header[4:], uint32(jitRecordHeader+len(body)))
+	w.buf.Write(header)
+	w.buf.Write(body)
+}
+
+func (w *jitdumpWriter) codeLoad(name string, addr, size, index uint64) {
+	body := make([]byte, 40)
+	binary.LittleEndian.PutUint64(body[16:], addr)
+	binary.LittleEndian.PutUint64(body[24:], size)
+	binary.LittleEndian.PutUint64(body[32:], index)
+	body = append(body, name...)
+	body = append(body, 0)
+	body = append(body, make([]byte, size)...) // the code
+	w.record(jitCodeLoad, body)
+}
+
+func (w *jitdumpWriter) codeMove(newAddr, index uint64) {
+	body := make([]byte, 48)
+	binary.LittleEndian.PutUint64(body[24:], newAddr)
+	binary.LittleEndian.PutUint64(body[40:], index)
+	w.record(jitCodeMove, body)
+}
+
+func TestParseJitdump(t *testing.T) {
+	w := newJitdumpWriter()
+	w.codeLoad("first", 0x1000, 0x10, 1)
+	w.codeLoad("second", 0x2000, 0x20, 2)
+	w.codeMove(0x3000, 2)
+	w.record(jitCodeClose, nil)
+	w.codeLoad("after_close", 0x4000, 0x10, 3)
+	w.buf.Write([]byte{1, 2, 3}) // incomplete record
+
+	path := writeTempFile(t, "jit-1.dump", w.buf.String())
+	defer os.RemoveAll(filepath.Dir(path))
+
+	funcs, err := parseJitdump(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byName := make(map[string]*FunctionEntry)
+	for _, fn := range funcs {
+		byName[fn.Name] = fn
+	}
+
+	if len(byName) != 2 || byName["first"] == nil || byName["second"] == nil {
+		t.Fatalf("unexpected functions: %v", byName)
+	}
+	if fn := byName["first"]; fn.LowPC != 0x1000 || fn.HighPC != 0x1010 {
+		t.Errorf("first is at %#x-%#x", fn.LowPC, fn.HighPC)
+	}
+	if fn := byName["second"]; fn.LowPC != 0x3000 || fn.HighPC != 0x3020 || fn.BreakpointAddress != 0x3000 {
+		t.Errorf("second wasn't moved: %#x-%#x", fn.LowPC, fn.HighPC)
+	}
+
+	invalid := writeTempFile(t, "jit-2.dump", "not a jitdump file, but long enough to have a header")
+	defer os.RemoveAll(filepath.Dir(invalid))
+
+	if _, err := parseJitdump(invalid); err == nil {
+		t.Error("no error for an invalid magic")
+	}
+}
+
+func TestJITSymbolsRefresh(t *testing.T) {
+	pid := Process(os.Getpid())
+	path := fmt.Sprintf("/tmp/perf-%d.map", pid.namespacePID())
+	if err := ioutil.WriteFile(path, []byte("1000 10 first\n"), 0644); err != nil {
+		t.Skip(err)
+	}
+	defer os.Remove(path)
+
+	jit := newJITSymbols(pid)
+	if err := jit.reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if fn := jit.lookup(0x1008); fn == nil || fn.Name != "first" {
+		t.Fatalf("first not found: %v", fn)
+	}
+
+	// an unchanged file is not parsed again on a miss
+	file := jit.files[path]
+	if fn := jit.lookup(0x5000); fn != nil {
+		t.Errorf("unexpected function at 0x5000: %s", fn.Name)
+	}
+	if jit.files[path] != file {
+		t.Error("the unchanged perf map was parsed again")
+	}
+
+	// the JIT runtime appends new symbols
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("5000 10 second\n")
+	f.Close()
+
+	if fn := jit.lookup(0x5000); fn == nil || fn.Name != "second" {
+		t.Fatalf("second not found after the perf map changed: %v", fn)
+	}
+	if fn := jit.lookup(0x1000); fn == nil || fn.Name != "first" {
+		t.Errorf("first not found after the perf map changed: %v", fn)
+	}
+
+	// the code of first is freed and the address is reused
+	f, err = os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("1000 8 recompiled\n")
+	f.Close()
+
+	// hits don't refresh the symbols
+	if _, err := jit.refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	if fn := jit.lookup(0x1004); fn == nil || fn.Name != "recompiled" {
+		t.Errorf("got %v at 0x1004 instead of the recompiled function", fn)
+	}
+	if fn := jit.lookup(0x100c); fn != nil {
+		t.Errorf("the stale %s is found at 0x100c", fn.Name)
+	}
+
+	// removed files take their symbols with them
+	os.Remove(path)
+	if fn := jit.lookup(0x9000); fn != nil {
+		t.Errorf("unexpected function at 0x9000: %s", fn.Name)
+	}
+	if fn := jit.find(0x1000); fn != nil {
+		t.Errorf("first is still found after the perf map was removed")
+	}
+}
@@ -0,0 +1,244 @@
+package raztracer
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// MaxPendingLatencies is the maximum number of started but not yet ended intervals of a probe
+const MaxPendingLatencies = 4096
+
+// LatencyProbe measures the time between the hits of a start and an end breakpoint.
+// Without a key the hits are paired on the same thread, otherwise by the value of the
+// key variable in the breakpoint's frame (e.g. a request pointer argument), which can
+// also pair hits on different threads.
+type LatencyProbe struct {
+	Name  string `yaml:"name" json:"name"`
+	Start string `yaml:"start" json:"start"`                 // function name of the start breakpoint
+	End   string `yaml:"end" json:"end"`                     // function name of the end breakpoint
+	Key   string `yaml:"key,omitempty" json:"key,omitempty"` // variable name, members are separated by dots
+
+	pending map[string][]time.Time
+	stats   LatencyStats
+}
+
+// LatencyStats contains the aggregated durations of a probe
+type LatencyStats struct {
+	Count     uint64        `json:"count"`
+	Total     time.Duration `json:"total"`
+	Min       time.Duration `json:"min"`
+	Max       time.Duration `json:"max"`
+	Mean      time.Duration `json:"mean"`
+	Unmatched uint64        `json:"unmatched"` // end hits without a start
+	Dropped   uint64        `json:"dropped"`   // start hits over MaxPendingLatencies
+}
+
+// LatencySample is the duration of an interval attached to the event of its end
+type LatencySample struct {
+	Probe    string        `json:"probe"`
+	Key      string        `json:"key,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// LatencyRecorder is an EventHandler that measures the durations of latency probes.
+// It can be shared by the tracers of multiple processes.
+type LatencyRecorder struct {
+	mutex  sync.Mutex
+	probes []*LatencyProbe
+}
+
+// NewLatencyRecorder returns a new LatencyRecorder
+func NewLatencyRecorder(probes ...*LatencyProbe) (*LatencyRecorder, error) {
+	r := &LatencyRecorder{}
+
+	for _, probe := range probes {
+		err := r.AddProbe(probe)
+		if err != nil {
+			return nil, Error(err)
+		}
+	}
+
+	return r, nil
+}
+
+// LoadLatencyProbes returns a LatencyRecorder with the probes read from YAML:
+//
+//	probes:
+//	- name: request
+//	  start: handle_request
+//	  end: send_response
+//	  key: req
+func LoadLatencyProbes(r io.Reader) (*LatencyRecorder, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	var config struct {
+		Probes []*LatencyProbe `yaml:"probes"`
+	}
+
+	err = yaml.UnmarshalStrict(data, &config)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	rec, err := NewLatencyRecorder(config.Probes...)
+	return rec, Error(err)
+}
+
+// AddProbe validates and adds a probe to the recorder
+func (r *LatencyRecorder) AddProbe(probe *LatencyProbe) error {
+	if len(probe.Start) == 0 || len(probe.End) == 0 {
+		return Errorf("probe %s: missing start or end function", probe.Name)
+	} else if probe.Start == probe.End {
+		return Errorf("probe %s: start and end functions are the same", probe.Name)
+	}
+
+	probe.pending = make(map[string][]time.Time)
+
+	r.mutex.Lock()
+	r.probes = append(r.probes, probe)
+	r.mutex.Unlock()
+	return nil
+}
+
+// Functions returns the start and end functions of the probes, which need breakpoints
+func (r *LatencyRecorder) Functions() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var functions []string
+	for _, probe := range r.probes {
+		functions = append(functions, probe.Start, probe.End)
+	}
+	return functions
+}
+
+// Stats returns the aggregated durations of the probes by name
+func (r *LatencyRecorder) Stats() map[string]LatencyStats {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	stats := make(map[string]LatencyStats, len(r.probes))
+	for _, probe := range r.probes {
+		stats[probe.Name] = probe.stats
+	}
+	return stats
+}
+
+// HandleEvent implements EventHandler. The measured durations are attached to the end events.
+func (r *LatencyRecorder) HandleEvent(t *Tracer, evt *TraceEvent) (*TraceEvent, error) {
+	if !evt.IsBreakpoint || len(evt.Backtrace) == 0 {
+		return evt, nil
+	}
+
+	frame := evt.Backtrace[0]
+	now := time.Now()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, probe := range r.probes {
+		isStart := matchFunctionName(frame.fn.Name, probe.Start)
+		isEnd := matchFunctionName(frame.fn.Name, probe.End)
+		if !isStart && !isEnd {
+			continue
+		}
+
+		key, ok := probe.key(evt, frame)
+		if !ok {
+			continue
+		}
+
+		if isStart {
+			probe.start(key, now)
+		} else if sample := probe.end(key, now); sample != nil {
+			evt.Latency = append(evt.Latency, *sample)
+		}
+	}
+
+	return evt, nil
+}
+
+// key returns the value pairing the start and end hits of the probe
+func (probe *LatencyProbe) key(evt *TraceEvent, frame *BacktraceFrame) (string, bool) {
+	if len(probe.Key) == 0 {
+		return fmt.Sprintf("%d/%d", evt.PID, evt.TID), true
+	}
+
+	readings := frame.Variables
+	var value *Reading
+	for _, name := range strings.Split(probe.Key, ".") {
+		value = nil
+		for i := range readings {
+			if readings[i].Name == name {
+				value = &readings[i]
+				break
+			}
+		}
+
+		if value == nil {
+			return "", false
+		}
+		readings = value.Children
+	}
+
+	if len(value.Error) > 0 {
+		return "", false
+	}
+
+	return fmt.Sprintf("%d/%s", evt.PID, value.Value), true
+}
+
+func (probe *LatencyProbe) start(key string, now time.Time) {
+	if _, found := probe.pending[key]; !found && len(probe.pending) >= MaxPendingLatencies {
+		probe.stats.Dropped++
+		return
+	}
+
+	probe.pending[key] = append(probe.pending[key], now)
+}
+
+// end pops the latest start of the key and accounts its duration
+func (probe *LatencyProbe) end(key string, now time.Time) *LatencySample {
+	starts := probe.pending[key]
+	if len(starts) == 0 {
+		probe.stats.Unmatched++
+		return nil
+	}
+
+	started := starts[len(starts)-1]
+	if len(starts) == 1 {
+		delete(probe.pending, key)
+	} else {
+		probe.pending[key] = starts[:len(starts)-1]
+	}
+
+	duration := now.Sub(started)
+	stats := &probe.stats
+	if stats.Count == 0 || duration < stats.Min {
+		stats.Min = duration
+	}
+	if duration > stats.Max {
+		stats.Max = duration
+	}
+	stats.Count++
+	stats.Total += duration
+	stats.Mean = stats.Total / time.Duration(stats.Count)
+
+	sample := &LatencySample{
+		Probe:    probe.Name,
+		Duration: duration,
+	}
+	if len(probe.Key) > 0 {
+		sample.Key = key[strings.IndexByte(key, '/')+1:]
+	}
+	return sample
+}
@@ -0,0 +1,175 @@
+package raztracer
+
+import (
+	"debug/elf"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Layout is the address space layout of the traced process
+type Layout struct {
+	Regions []MemRegion    `json:"regions"`
+	Modules []LayoutModule `json:"modules"`
+}
+
+// LayoutModule is the executable or a shared library mapped to the process
+type LayoutModule struct {
+	Name       string          `json:"name"`
+	StaticBase uintptr         `json:"static_base"`
+	DebugInfo  bool            `json:"debug_info"`
+	Sections   []LayoutSection `json:"sections"`
+}
+
+// LayoutSection is an ELF section of a module at its runtime address
+type LayoutSection struct {
+	Name    string  `json:"name"`
+	Address uintptr `json:"address"`
+	Size    uint64  `json:"size"`
+}
+
+// LayoutMatch describes what is at an address of the process
+type LayoutMatch struct {
+	Address uintptr        `json:"address"`
+	Region  *MemRegion     `json:"region,omitempty"`
+	Module  *LayoutModule  `json:"module,omitempty"`
+	Section *LayoutSection `json:"section,omitempty"`
+}
+
+// Layout returns the memory regions, loaded modules, their static bases
+// and section addresses of the traced process
+func (t *Tracer) Layout() (*Layout, error) {
+	regions, err := t.pid.MemRegions()
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	layout := &Layout{Regions: regions}
+
+	exe, _ := t.pid.Executable()
+	exeBase := uintptr(0)
+	if t.debugData.elfData.Type == elf.ET_DYN {
+		exeBase = moduleBase(regions, exe)
+	}
+
+	layout.Modules = append(layout.Modules, LayoutModule{
+		Name:       exe,
+		StaticBase: exeBase,
+		DebugInfo:  true,
+		Sections:   elfSections(t.debugData.elfData, exeBase),
+	})
+
+	loaded := make(map[string]bool)
+	for _, lib := range t.debugData.GetSharedLibs() {
+		loaded[lib.Name] = true
+	}
+
+	libs, _ := t.pid.SharedLibs()
+	for _, lib := range libs {
+		module := LayoutModule{
+			Name:       lib.Name,
+			StaticBase: lib.StaticBase,
+			DebugInfo:  loaded[lib.Name],
+		}
+
+		if file, err := os.Open(lib.path()); err == nil {
+			if elfData, err := elf.NewFile(file); err == nil {
+				module.Sections = elfSections(elfData, lib.StaticBase)
+			}
+			file.Close()
+		}
+
+		layout.Modules = append(layout.Modules, module)
+	}
+
+	return layout, nil
+}
+
+// Lookup returns the region, module and section at 'addr'
+func (layout *Layout) Lookup(addr uintptr) *LayoutMatch {
+	match := &LayoutMatch{Address: addr}
+
+	for i := range layout.Regions {
+		region := &layout.Regions[i]
+		if addr >= region.Address[0] && addr < region.Address[1] {
+			match.Region = region
+			break
+		}
+	}
+
+	for i := range layout.Modules {
+		module := &layout.Modules[i]
+		for j := range module.Sections {
+			sec := &module.Sections[j]
+			if addr >= sec.Address && addr < sec.Address+uintptr(sec.Size) {
+				match.Module = module
+				match.Section = sec
+				return match
+			}
+		}
+	}
+
+	if match.Region != nil {
+		for i := range layout.Modules {
+			if layout.Modules[i].Name == match.Region.Pathname {
+				match.Module = &layout.Modules[i]
+				break
+			}
+		}
+	}
+
+	return match
+}
+
+// String returns the match as a human readable description
+func (match *LayoutMatch) String() string {
+	if match.Region == nil {
+		return fmt.Sprintf("%#x: unmapped", match.Address)
+	}
+
+	str := fmt.Sprintf("%#x: %#x-%#x %s", match.Address,
+		match.Region.Address[0], match.Region.Address[1], match.Region.Permissions)
+
+	if match.Module != nil {
+		str += " " + match.Module.Name
+	} else if len(match.Region.Pathname) > 0 {
+		str += " " + match.Region.Pathname
+	}
+
+	if match.Section != nil {
+		str += fmt.Sprintf(" %s+%#x", match.Section.Name, match.Address-match.Section.Address)
+	}
+
+	return str
+}
+
+func elfSections(elfData *elf.File, base uintptr) []LayoutSection {
+	var sections []LayoutSection
+	for _, sec := range elfData.Sections {
+		if sec.Flags&elf.SHF_ALLOC == 0 || sec.Addr == 0 {
+			continue
+		}
+
+		sections = append(sections, LayoutSection{
+			Name:    sec.Name,
+			Address: uintptr(sec.Addr) + base,
+			Size:    sec.Size,
+		})
+	}
+
+	sort.Slice(sections, func(i, j int) bool {
+		return sections[i].Address < sections[j].Address
+	})
+
+	return sections
+}
+
+// moduleBase returns the lowest address the module at 'path' is mapped to
+func moduleBase(regions []MemRegion, path string) uintptr {
+	for _, region := range regions {
+		if region.Pathname == path && region.Offset == 0 {
+			return region.Address[0]
+		}
+	}
+	return 0
+}
@@ -0,0 +1,86 @@
+package raztracer
+
+import (
+	"path"
+)
+
+// LibraryFilter selects the shared libraries whose debug info is loaded.
+// Patterns are path globs matched against the full path and the base name of the library.
+type LibraryFilter struct {
+	Include []string `json:"include,omitempty" yaml:"include"` // load only the matching libraries if not empty
+	Exclude []string `json:"exclude,omitempty" yaml:"exclude"` // never load the matching libraries
+}
+
+// Match returns whether the library should be loaded (a nil filter matches every library)
+func (f *LibraryFilter) Match(name string) bool {
+	if f == nil {
+		return true
+	}
+
+	if len(f.Include) > 0 && !matchLibraryPattern(f.Include, name) {
+		return false
+	}
+
+	return !matchLibraryPattern(f.Exclude, name)
+}
+
+func matchLibraryPattern(patterns []string, name string) bool {
+	base := path.Base(name)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, base); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// SetLibraryFilter sets the filter applied to the libraries added by AddSharedLib
+func (d *DebugData) SetLibraryFilter(filter *LibraryFilter) {
+	d.libFilter = filter
+}
+
+// GetSharedLibs returns the libraries whose debug info is loaded
+func (d *DebugData) GetSharedLibs() []SharedLibrary {
+	return d.libs
+}
+
+// GetSkippedLibs returns the libraries that were rejected by the library filter
+// and can be loaded on demand by LoadSharedLib
+func (d *DebugData) GetSkippedLibs() []SharedLibrary {
+	return d.skippedLibs
+}
+
+// LoadSharedLib loads the debug info of the skipped libraries matching the 'pattern' glob
+// regardless of the library filter. It returns the number of loaded libraries.
+func (d *DebugData) LoadSharedLib(pattern string) (int, error) {
+	var remaining []SharedLibrary
+	var errors []error
+	var loaded int
+
+	for _, lib := range d.skippedLibs {
+		if !matchLibraryPattern([]string{pattern}, lib.Name) {
+			remaining = append(remaining, lib)
+			continue
+		}
+
+		err := d.loadSharedLib(lib)
+		if err != nil {
+			errors = append(errors, Error(err))
+			remaining = append(remaining, lib)
+			continue
+		}
+
+		loaded++
+	}
+
+	d.skippedLibs = remaining
+
+	if loaded == 0 && len(errors) == 0 {
+		return 0, Errorf("no skipped library matches: %s", pattern)
+	}
+
+	return loaded, MergeErrors(errors)
+}
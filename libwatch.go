@@ -0,0 +1,252 @@
+package raztracer
+
+import (
+	"debug/elf"
+	"path"
+	"strings"
+)
+
+// rendezvousFunction is called by the dynamic linker after adding or removing libraries
+const rendezvousFunction = "_dl_debug_state"
+
+// LibraryChanges contains the libraries loaded and unloaded since the last refresh
+type LibraryChanges struct {
+	Loaded   []SharedLibrary `json:"loaded,omitempty"`
+	Unloaded []SharedLibrary `json:"unloaded,omitempty"`
+}
+
+// unloadedModule is the address range of an unloaded library
+type unloadedModule struct {
+	name      string
+	low, high uintptr
+}
+
+// SetLibraryTracking enables refreshing the shared libraries whenever the dynamic linker
+// loads or unloads libraries (e.g. dlopen/dlclose). A breakpoint is set at the rendezvous
+// function of the dynamic linker, its hits are handled internally and not reported.
+func (t *Tracer) SetLibraryTracking(enabled bool) error {
+	if !enabled {
+		addr := t.rendezvous
+		t.rendezvous = 0
+
+		// the breakpoint is kept if the function is traced too
+		if _, isFunction := t.bpFunctions[addr]; addr != 0 && !isFunction {
+			return Error(t.RemoveBreakpoint(addr))
+		}
+		return nil
+	}
+
+	if t.rendezvous != 0 {
+		return nil
+	}
+
+	addr, err := t.findRendezvous()
+	if err != nil {
+		return Error(err)
+	}
+
+	if _, exists := t.breakpoints[addr]; !exists {
+		err = t.SetBreakpoint(addr)
+		if err != nil {
+			return Error(err)
+		}
+	}
+
+	t.rendezvous = addr
+	return nil
+}
+
+// RefreshSharedLibs compares the libraries mapped by the process with the known ones.
+// The functions of unloaded libraries are removed, so their addresses are reported
+// as an unloaded module instead of being attributed to stale functions.
+func (t *Tracer) RefreshSharedLibs() (*LibraryChanges, error) {
+	libs, err := t.pid.SharedLibs()
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	changes := t.debugData.refreshSharedLibs(libs)
+	t.session.Libraries = libs
+	return changes, nil
+}
+
+// handleLibraryEvent refreshes the libraries on rendezvous breakpoint hits.
+// Returns true if the event is a rendezvous breakpoint hit that isn't reported.
+func (t *Tracer) handleLibraryEvent(evt *TraceEvent) bool {
+	if !evt.IsBreakpoint || t.rendezvous == 0 || evt.PC != t.rendezvous {
+		return false
+	}
+
+	t.RefreshSharedLibs()
+
+	_, isFunction := t.bpFunctions[evt.PC]
+	return !isFunction
+}
+
+// findRendezvous returns the address of the rendezvous function of the dynamic linker
+func (t *Tracer) findRendezvous() (uintptr, error) {
+	interp := t.debugData.interpreter()
+	if len(interp) == 0 {
+		return 0, Errorf("the executable is not dynamically linked")
+	}
+
+	regions, err := t.pid.MemRegions()
+	if err != nil {
+		return 0, Error(err)
+	}
+
+	for _, region := range regions {
+		if path.Base(region.Pathname) != path.Base(interp) {
+			continue
+		}
+
+		ld, err := elf.Open(t.pid.ResolvePath(region.Pathname))
+		if err != nil {
+			return 0, Error(err)
+		}
+		defer ld.Close()
+
+		symbols, _ := ld.DynamicSymbols()
+		if staticSymbols, err := ld.Symbols(); err == nil {
+			symbols = append(symbols, staticSymbols...)
+		}
+
+		for _, symbol := range symbols {
+			if symbol.Name == rendezvousFunction && symbol.Value != 0 {
+				return region.Address[0] + uintptr(symbol.Value), nil
+			}
+		}
+
+		return 0, Errorf("%s not found in %s", rendezvousFunction, region.Pathname)
+	}
+
+	return 0, Errorf("dynamic linker %s is not mapped", interp)
+}
+
+// interpreter returns the dynamic linker requested by the executable (PT_INTERP)
+func (d *DebugData) interpreter() string {
+	for _, prog := range d.elfData.Progs {
+		if prog.Type != elf.PT_INTERP {
+			continue
+		}
+
+		data := make([]byte, prog.Filesz)
+		_, err := prog.ReadAt(data, 0)
+		if err != nil {
+			return ""
+		}
+
+		return strings.TrimRight(string(data), "\x00")
+	}
+
+	return ""
+}
+
+// refreshSharedLibs loads the new libraries and removes the unloaded ones
+func (d *DebugData) refreshSharedLibs(libs []SharedLibrary) *LibraryChanges {
+	changes := &LibraryChanges{}
+
+	mapped := make(map[SharedLibrary]bool)
+	for _, lib := range libs {
+		mapped[lib] = true
+	}
+
+	known := make(map[SharedLibrary]bool)
+	for _, lib := range append(d.GetSharedLibs(), d.GetSkippedLibs()...) {
+		known[lib] = true
+		if !mapped[lib] {
+			changes.Unloaded = append(changes.Unloaded, lib)
+		}
+	}
+
+	for _, lib := range changes.Unloaded {
+		d.removeSharedLib(lib)
+	}
+
+	for _, lib := range libs {
+		if known[lib] {
+			continue
+		}
+
+		changes.Loaded = append(changes.Loaded, lib)
+		d.AddSharedLib(lib)
+	}
+
+	if len(changes.Loaded) > 0 || len(changes.Unloaded) > 0 {
+		d.InvalidateSymbolCache()
+	}
+
+	// a JIT runtime might have mapped its jitdump file since the last library event
+	if d.jit != nil {
+		d.jit.invalidatePaths()
+	}
+
+	return changes
+}
+
+// removeSharedLib removes the functions and debug data of an unloaded library
+// and remembers its address range
+func (d *DebugData) removeSharedLib(lib SharedLibrary) {
+	var libData *DebugData
+	for i, data := range d.libData {
+		if data.staticBase == lib.StaticBase {
+			libData = data
+			d.libData = append(d.libData[:i], d.libData[i+1:]...)
+			break
+		}
+	}
+
+	module := unloadedModule{name: lib.Name}
+	functions := make([]*FunctionEntry, 0, len(d.functions))
+
+	for _, fn := range d.functions {
+		isLibFunction := (libData != nil && fn.entry.data == libData) ||
+			(fn.Lib != nil && fn.Lib.Name == lib.Name && fn.Lib.StaticBase == lib.StaticBase)
+		if !isLibFunction {
+			functions = append(functions, fn)
+			continue
+		}
+
+		low, high := fn.LowPC+fn.StaticBase, fn.HighPC+fn.StaticBase
+		if module.low == 0 || low < module.low {
+			module.low = low
+		}
+		if high > module.high {
+			module.high = high
+		}
+	}
+	d.functions = functions
+
+	delete(d.libFrames, lib.StaticBase)
+	d.libs = removeLib(d.libs, lib)
+	d.skippedLibs = removeLib(d.skippedLibs, lib)
+
+	if module.high > module.low {
+		d.unloaded = append(d.unloaded, module)
+	}
+
+	d.budget.updateFixed()
+}
+
+// unloadedModuleAt returns the name of the unloaded library that contained 'pc'
+func (d *DebugData) unloadedModuleAt(pc uintptr) (string, bool) {
+	// the newest unload wins, the range might have been reused since
+	for i := len(d.unloaded) - 1; i >= 0; i-- {
+		module := &d.unloaded[i]
+		if pc >= module.low && pc < module.high {
+			return module.name, true
+		}
+	}
+
+	return "", false
+}
+
+func removeLib(libs []SharedLibrary, lib SharedLibrary) []SharedLibrary {
+	remaining := make([]SharedLibrary, 0, len(libs))
+	for _, l := range libs {
+		if l != lib {
+			remaining = append(remaining, l)
+		}
+	}
+	return remaining
+}
@@ -2,6 +2,7 @@ package raztracer
 
 import (
 	"debug/dwarf"
+	"fmt"
 )
 
 // LineEntry contains debug information about a line in the source code
@@ -67,3 +68,22 @@ func (line *LineEntry) Next() (*LineEntry, error) {
 		Column:   uint(entry.Column),
 	}, nil
 }
+
+// sameStatement returns true if the entries belong to the same line
+// (and to the same column in column step mode)
+func (line *LineEntry) sameStatement(other *LineEntry, mode StepMode) bool {
+	if line.Line != other.Line || line.Filename != other.Filename {
+		return false
+	}
+
+	return mode != StepModeColumn || line.Column == other.Column
+}
+
+// position returns the source position of the line entry as file:line (or file:line:column)
+func (line *LineEntry) position(mode StepMode) string {
+	if mode == StepModeColumn {
+		return fmt.Sprintf("%s:%d:%d", line.Filename, line.Line, line.Column)
+	}
+
+	return fmt.Sprintf("%s:%d", line.Filename, line.Line)
+}
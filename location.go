@@ -3,6 +3,7 @@ package raztracer
 import (
 	"bytes"
 	"debug/dwarf"
+	"encoding/binary"
 	"fmt"
 
 	"github.com/razzie/raztracer/internal/dwarf/op"
@@ -14,6 +15,7 @@ type Location struct {
 	address      uintptr
 	pieces       []op.Piece
 	regs         *op.DwarfRegisters
+	order        binary.ByteOrder // byte order of the operands and register pieces
 }
 
 // NewLocation returns a new Location
@@ -27,11 +29,11 @@ func NewLocation(de *DebugEntry, attr dwarf.Attr, pc uintptr) (*Location, error)
 
 	switch a.(type) {
 	case []byte:
-		return &Location{instructions: a.([]byte)}, nil
+		return &Location{instructions: a.([]byte), order: de.data.dwarfEndian}, nil
 
 	case int64: // loclist offset
 		instr, err := de.data.GetLoclistEntry(pc, a.(int64))
-		return &Location{instructions: instr}, Error(err)
+		return &Location{instructions: instr, order: de.data.dwarfEndian}, Error(err)
 
 	default:
 		return nil, Errorf("%s: could not interpret location for %v", name, attr)
@@ -39,7 +41,12 @@ func NewLocation(de *DebugEntry, attr dwarf.Attr, pc uintptr) (*Location, error)
 }
 
 func (loc *Location) parse(regs *op.DwarfRegisters) error {
-	addr, pieces, err := op.ExecuteStackProgram(*regs, loc.instructions)
+	progRegs := *regs
+	if loc.order != nil {
+		progRegs.ByteOrder = loc.order
+	}
+
+	addr, pieces, err := op.ExecuteStackProgram(progRegs, loc.instructions)
 	loc.address = uintptr(addr)
 	loc.pieces = pieces
 	loc.regs = regs
@@ -48,6 +55,12 @@ func (loc *Location) parse(regs *op.DwarfRegisters) error {
 
 // Read reads and returns the data in binary form at the location
 func (loc *Location) Read(pid int, regs *op.DwarfRegisters) ([]byte, error) {
+	data, err := loc.ReadFrom(Process(pid), regs)
+	return data, Error(err)
+}
+
+// ReadFrom reads and returns the data in binary form at the location using 'mem'
+func (loc *Location) ReadFrom(mem MemoryReader, regs *op.DwarfRegisters) ([]byte, error) {
 	if len(loc.instructions) == 0 {
 		return nil, Errorf("no location instructions")
 	}
@@ -57,11 +70,19 @@ func (loc *Location) Read(pid int, regs *op.DwarfRegisters) ([]byte, error) {
 		return nil, Error(err)
 	}
 
-	proc := Process(pid)
+	data, err := loc.ReadValue(mem)
+	return data, Error(err)
+}
+
+// ReadValue reads the data at a location that was already evaluated
+func (loc *Location) ReadValue(mem MemoryReader) ([]byte, error) {
+	if loc.regs == nil {
+		return nil, Errorf("location is not evaluated")
+	}
 
 	if len(loc.pieces) == 0 {
 		data := make([]byte, SizeofPtr)
-		err := proc.PeekData(uintptr(loc.address), data)
+		err := mem.PeekData(uintptr(loc.address), data)
 		return data, Error(err)
 	}
 
@@ -72,15 +93,15 @@ func (loc *Location) Read(pid int, regs *op.DwarfRegisters) ([]byte, error) {
 			buf := make([]byte, SizeofPtr)
 
 			if SizeofPtr == 4 {
-				ByteOrder.PutUint32(buf, uint32(val))
+				loc.byteOrder().PutUint32(buf, uint32(val))
 			} else {
-				ByteOrder.PutUint64(buf, val)
+				loc.byteOrder().PutUint64(buf, val)
 			}
 
 			data = append(data, buf...)
 		} else {
 			buf := make([]byte, piece.Size)
-			err := proc.PeekData(uintptr(piece.Addr), buf)
+			err := mem.PeekData(uintptr(piece.Addr), buf)
 			if err != nil {
 				return data, Error(err)
 			}
@@ -92,10 +113,26 @@ func (loc *Location) Read(pid int, regs *op.DwarfRegisters) ([]byte, error) {
 	return data, nil
 }
 
+// Address returns the memory address of the location after it was read
+// (0 if the value is in registers)
+func (loc *Location) Address() uintptr {
+	if len(loc.pieces) == 0 {
+		return loc.address
+	}
+
+	for _, piece := range loc.pieces {
+		if !piece.IsRegister {
+			return uintptr(piece.Addr)
+		}
+	}
+
+	return 0
+}
+
 // String returns the location as a string
 func (loc *Location) String() (ret string) {
 	if loc.instructions[0] == byte(op.DW_OP_addr) {
-		addr := ReadAddress(loc.instructions[1:])
+		addr := ReadAddressWithOrder(loc.instructions[1:], loc.byteOrder())
 		return fmt.Sprintf("%#x", addr)
 	}
 
@@ -109,3 +146,11 @@ func (loc *Location) String() (ret string) {
 	op.PrettyPrint(&buf, loc.instructions)
 	return buf.String()
 }
+
+func (loc *Location) byteOrder() binary.ByteOrder {
+	if loc.order != nil {
+		return loc.order
+	}
+
+	return ByteOrder
+}
@@ -17,9 +17,13 @@ type LocList map[int64][]LocEntry
 
 // NewLocList returns a new LocList
 func NewLocList(data []byte, order binary.ByteOrder) LocList {
+	return parseLocList(data, order, int(SizeofPtr))
+}
+
+// parseLocList parses the loclist data of a target with the given address size
+func parseLocList(data []byte, order binary.ByteOrder, ptrSize int) LocList {
 	loclist := make(LocList)
 	rdr := bytes.NewBuffer(data)
-	ptrSize := int(SizeofPtr)
 
 	readAddr := func() uint64 {
 		data := rdr.Next(ptrSize)
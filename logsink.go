@@ -0,0 +1,264 @@
+package raztracer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"log/syslog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// JournalSocket is the path of the native protocol socket of systemd-journald
+const JournalSocket = "/run/systemd/journal/socket"
+
+// DefaultSummaryReadings is the number of top frame variables in event summaries
+// if no readings are selected
+const DefaultSummaryReadings = 3
+
+// LogSink is an EventHandler that writes a one-line summary of every event
+// to syslog or the systemd journal. Events are passed on unchanged.
+type LogSink struct {
+	mutex      sync.Mutex
+	identifier string
+	readings   []string
+	syslog     *syslog.Writer
+	journal    *net.UnixConn
+}
+
+// NewSyslogSink returns a LogSink writing to the local syslog daemon
+func NewSyslogSink(tag string) (*LogSink, error) {
+	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	return &LogSink{identifier: tag, syslog: w}, nil
+}
+
+// NewJournalSink returns a LogSink writing to the systemd journal.
+// The event details are also sent as RAZTRACER_* structured fields.
+func NewJournalSink(identifier string) (*LogSink, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: JournalSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	return &LogSink{identifier: identifier, journal: conn}, nil
+}
+
+// SetReadings selects the variables of the top frame or globals included in the summaries
+func (s *LogSink) SetReadings(names ...string) {
+	s.mutex.Lock()
+	s.readings = names
+	s.mutex.Unlock()
+}
+
+// Close closes the connection to the log daemon
+func (s *LogSink) Close() error {
+	if s.syslog != nil {
+		return s.syslog.Close()
+	}
+
+	return s.journal.Close()
+}
+
+// HandleEvent implements EventHandler
+func (s *LogSink) HandleEvent(t *Tracer, evt *TraceEvent) (*TraceEvent, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	summary := newEventSummary(evt, s.readings)
+
+	var err error
+	if s.syslog != nil {
+		err = s.writeSyslog(summary)
+	} else {
+		err = s.writeJournal(summary)
+	}
+
+	if err != nil {
+		return evt, Error(err)
+	}
+	return evt, nil
+}
+
+func (s *LogSink) writeSyslog(summary *eventSummary) error {
+	msg := summary.String()
+
+	switch summary.priority {
+	case syslog.LOG_CRIT:
+		return s.syslog.Crit(msg)
+	case syslog.LOG_WARNING:
+		return s.syslog.Warning(msg)
+	case syslog.LOG_NOTICE:
+		return s.syslog.Notice(msg)
+	default:
+		return s.syslog.Info(msg)
+	}
+}
+
+func (s *LogSink) writeJournal(summary *eventSummary) error {
+	var buf bytes.Buffer
+
+	writeJournalField(&buf, "MESSAGE", summary.String())
+	writeJournalField(&buf, "PRIORITY", strconv.Itoa(int(summary.priority)))
+	writeJournalField(&buf, "SYSLOG_IDENTIFIER", s.identifier)
+	for _, field := range summary.fields {
+		writeJournalField(&buf, "RAZTRACER_"+field.key, field.value)
+	}
+
+	_, err := s.journal.Write(buf.Bytes())
+	return err
+}
+
+// writeJournalField encodes a field in the journal's native protocol
+func writeJournalField(buf *bytes.Buffer, key, value string) {
+	if !strings.ContainsRune(value, '\n') {
+		fmt.Fprintf(buf, "%s=%s\n", key, value)
+		return
+	}
+
+	// multi-line values are prefixed by their length
+	buf.WriteString(key)
+	buf.WriteByte('\n')
+	binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// eventSummary is the condensed form of an event
+type eventSummary struct {
+	priority syslog.Priority
+	fields   []summaryField
+}
+
+type summaryField struct {
+	name  string // name in the message
+	key   string // name of the journal field
+	value string
+}
+
+func newEventSummary(evt *TraceEvent, readings []string) *eventSummary {
+	s := &eventSummary{priority: syslog.LOG_INFO}
+
+	s.add("SEQ", strconv.FormatUint(evt.Seq, 10))
+	s.add("PID", strconv.Itoa(int(evt.PID)))
+	s.add("TID", strconv.Itoa(int(evt.TID)))
+
+	switch {
+	case evt.IsBreakpoint:
+		s.add("EVENT", "breakpoint")
+	case evt.IsWatchpoint:
+		s.add("EVENT", "watchpoint")
+		s.priority = syslog.LOG_NOTICE
+	case evt.IsNewThread:
+		s.add("EVENT", "new_thread")
+	case evt.Reason.Terminated():
+		s.add("EVENT", string(evt.Reason.Kind))
+		s.priority = syslog.LOG_WARNING
+	default:
+		s.add("EVENT", "signal")
+		s.add("SIGNAL", SignalName(evt.Signal))
+		s.priority = signalPriority(evt.Signal)
+	}
+
+	if len(evt.Backtrace) > 0 {
+		frame := evt.Backtrace[0]
+		s.add("FUNCTION", frame.Function)
+		if len(frame.Source) > 0 {
+			s.add("SOURCE", frame.Source)
+		}
+		s.addReadings(frame.Variables, evt.Globals, readings)
+	} else {
+		s.add("PC", fmt.Sprintf("%#x", evt.PC))
+	}
+
+	if evt.Call != nil && evt.Call.Exit {
+		s.add("DURATION", evt.Call.Duration.String())
+	}
+
+	return s
+}
+
+func (s *eventSummary) add(key, value string) {
+	s.fields = append(s.fields, summaryField{name: strings.ToLower(key), key: key, value: value})
+}
+
+// addReadings adds the selected variables or the first few variables of the frame
+func (s *eventSummary) addReadings(vars, globals []Reading, names []string) {
+	if len(names) == 0 {
+		for i := 0; i < len(vars) && i < DefaultSummaryReadings; i++ {
+			s.addReading(&vars[i])
+		}
+		return
+	}
+
+	for _, name := range names {
+		if r := findReading(vars, name); r != nil {
+			s.addReading(r)
+		} else if r := findReading(globals, name); r != nil {
+			s.addReading(r)
+		}
+	}
+}
+
+func (s *eventSummary) addReading(r *Reading) {
+	value := r.Value
+	if len(r.Error) > 0 {
+		value = "<" + r.Error + ">"
+	}
+
+	s.fields = append(s.fields, summaryField{name: r.Name, key: "VAR_" + journalFieldName(r.Name), value: value})
+}
+
+// journalFieldName converts 'name' to the character set allowed in journal field names
+func journalFieldName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+func findReading(readings []Reading, name string) *Reading {
+	for i := range readings {
+		if readings[i].Name == name {
+			return &readings[i]
+		}
+	}
+
+	return nil
+}
+
+// String returns the summary as key=value pairs
+func (s *eventSummary) String() string {
+	parts := make([]string, 0, len(s.fields))
+	for _, field := range s.fields {
+		value := field.value
+		if strings.ContainsAny(value, " \t\n\"") {
+			value = strconv.Quote(value)
+		}
+
+		parts = append(parts, field.name+"="+value)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func signalPriority(sig syscall.Signal) syslog.Priority {
+	switch sig {
+	case syscall.SIGSEGV, syscall.SIGBUS, syscall.SIGILL, syscall.SIGFPE, syscall.SIGABRT, syscall.SIGSYS:
+		return syslog.LOG_CRIT
+	default:
+		return syslog.LOG_NOTICE
+	}
+}
@@ -0,0 +1,194 @@
+package raztracer
+
+import (
+	"container/list"
+	"debug/elf"
+	"strings"
+)
+
+// estimated sizes of the cached debug data
+const (
+	functionCacheEntrySize = 48
+	symbolCacheEntrySize   = 64
+	variableEntrySize      = 256
+	functionEntrySize      = 192
+	typeIndexEntrySize     = 64
+)
+
+// MemoryUsage contains the estimated memory usage of the debug data
+type MemoryUsage struct {
+	Budget    int64  `json:"budget"`    // 0 if unlimited
+	Unmet     bool   `json:"unmet"`     // the fixed part alone exceeds the budget, nothing is evicted
+	Fixed     int64  `json:"fixed"`     // section data and function/global indices
+	Caches    int64  `json:"caches"`    // PC lookup, symbol and type caches
+	CUDetail  int64  `json:"cu_detail"` // variables of functions of recently used compilation units
+	Total     int64  `json:"total"`
+	Evictions uint64 `json:"evictions"` // evicted compilation units and caches
+}
+
+// memoryBudget tracks the estimated memory usage of the evictable parts of the debug data
+// of an executable and its libraries. Per-CU detail is evicted in LRU order, then the caches.
+type memoryBudget struct {
+	owner     *DebugData
+	limit     int64
+	fixed     int64 // cached by updateFixed, so cache inserts don't walk the sections
+	unmet     bool  // the fixed part alone exceeds the limit
+	caches    int64
+	detail    int64
+	lru       *list.List // of *cuDetail, most recently used first
+	cus       map[*CUEntry]*list.Element
+	evictions uint64
+}
+
+type cuDetail struct {
+	cu   *CUEntry
+	size int64
+}
+
+func newMemoryBudget(owner *DebugData) *memoryBudget {
+	return &memoryBudget{
+		owner: owner,
+		lru:   list.New(),
+		cus:   make(map[*CUEntry]*list.Element),
+	}
+}
+
+// SetMemoryBudget limits the estimated memory usage of the debug data including the loaded
+// libraries. Cached detail is evicted when the limit is exceeded (0 means unlimited).
+func (d *DebugData) SetMemoryBudget(limit int64) {
+	d.budget.limit = limit
+	d.budget.enforce()
+}
+
+// MemoryUsage returns the estimated memory usage of the debug data including the loaded libraries
+func (d *DebugData) MemoryUsage() MemoryUsage {
+	fixed := d.budget.fixed
+
+	return MemoryUsage{
+		Budget:    d.budget.limit,
+		Unmet:     d.budget.unmet,
+		Fixed:     fixed,
+		Caches:    d.budget.caches,
+		CUDetail:  d.budget.detail,
+		Total:     fixed + d.budget.caches + d.budget.detail,
+		Evictions: d.budget.evictions,
+	}
+}
+
+// debugSectionsSize returns the size of the debug sections of the binary
+func debugSectionsSize(elfData *elf.File) int64 {
+	var size int64
+
+	for _, sec := range elfData.Sections {
+		if strings.HasPrefix(sec.Name, ".debug_") || strings.HasPrefix(sec.Name, ".zdebug_") || sec.Name == ".eh_frame" {
+			size += int64(sec.Size)
+		}
+	}
+
+	return size
+}
+
+// updateFixed recalculates the estimated size of the parts of the debug data that can't be evicted.
+// It's called when the debug data or a library is loaded or unloaded.
+func (budget *memoryBudget) updateFixed() {
+	owner := budget.owner
+
+	// the functions of the libraries are also in the list of the owner
+	size := owner.sectionsSize + int64(len(owner.functions))*functionEntrySize + int64(len(owner.globals))*variableEntrySize
+	for _, lib := range owner.libData {
+		size += lib.sectionsSize + int64(len(lib.globals))*variableEntrySize
+	}
+
+	budget.fixed = size
+}
+
+// addCache accounts a new cache entry
+func (budget *memoryBudget) addCache(size int64) {
+	budget.caches += size
+	budget.enforce()
+}
+
+// useDetail marks the compilation unit as recently used and accounts its new detail
+func (budget *memoryBudget) useDetail(cu *CUEntry, size int64) {
+	if cu == nil {
+		return
+	}
+
+	elem, found := budget.cus[cu]
+	if !found {
+		if size == 0 {
+			return
+		}
+		elem = budget.lru.PushFront(&cuDetail{cu: cu})
+		budget.cus[cu] = elem
+	}
+
+	budget.lru.MoveToFront(elem)
+	elem.Value.(*cuDetail).size += size
+	budget.detail += size
+
+	if size > 0 {
+		budget.enforce()
+	}
+}
+
+// enforce evicts the least recently used CU detail, then the caches until the usage fits the budget
+func (budget *memoryBudget) enforce() {
+	budget.unmet = budget.limit > 0 && budget.fixed >= budget.limit
+	if budget.limit <= 0 || budget.unmet {
+		// evicting can't make the usage fit the budget, it would only thrash the caches
+		return
+	}
+
+	fixed := budget.fixed
+
+	// the most recently used CU is kept, its detail is being used
+	for budget.lru.Len() > 1 && fixed+budget.caches+budget.detail > budget.limit {
+		budget.evictDetail(budget.lru.Back())
+	}
+
+	if fixed+budget.caches+budget.detail > budget.limit && budget.caches > 0 {
+		budget.owner.clearCaches()
+		budget.caches = 0
+		budget.evictions++
+	}
+}
+
+func (budget *memoryBudget) evictDetail(elem *list.Element) {
+	detail := budget.lru.Remove(elem).(*cuDetail)
+	delete(budget.cus, detail.cu)
+
+	for _, fn := range detail.cu.functions {
+		fn.variables = nil
+		fn.signature = nil
+	}
+
+	budget.detail -= detail.size
+	budget.evictions++
+}
+
+// SetMemoryBudget limits the estimated memory usage of the debug data (0 means unlimited).
+// The budget is kept when the debug data is reloaded.
+func (t *Tracer) SetMemoryBudget(limit int64) {
+	t.memoryBudget = limit
+	t.debugData.SetMemoryBudget(limit)
+}
+
+// clearCaches drops the PC lookup, symbol and type caches of the debug data and its libraries
+func (d *DebugData) clearCaches() {
+	d.InvalidateSymbolCache()
+	d.typeIndex = nil
+
+	for _, lib := range d.libData {
+		lib.typeIndex = nil
+	}
+}
+
+// variablesSize returns the estimated size of the variables
+func variablesSize(vars []*VariableEntry) int64 {
+	size := int64(0)
+	for _, v := range vars {
+		size += variableEntrySize + int64(len(v.Name)+len(v.Type))
+	}
+	return size
+}
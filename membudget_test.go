@@ -0,0 +1,51 @@
+package raztracer
+
+import (
+	"testing"
+)
+
+func TestMemoryBudget(t *testing.T) {
+	if len(traceePath) == 0 {
+		t.Skip("the test program could not be compiled")
+	}
+
+	d, err := NewDebugDataFromPath(traceePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	fns := d.GetFunctionsByName("traced", true)
+	if len(fns) == 0 {
+		t.Fatal("traced() not found")
+	}
+	pc := fns[0].LowPC
+
+	fixed := d.MemoryUsage().Fixed
+	if fixed == 0 {
+		t.Fatal("the fixed size is not accounted")
+	}
+
+	// the fixed part alone exceeds the budget, the caches must survive
+	d.SetMemoryBudget(fixed / 2)
+	for i := uintptr(0); i < 4; i++ {
+		d.GetFunctionFromPC(pc + i)
+		d.lineSource(pc + i)
+	}
+
+	usage := d.MemoryUsage()
+	if !usage.Unmet || usage.Evictions != 0 || usage.Caches == 0 {
+		t.Errorf("the caches were thrashed by an unmeetable budget: %+v", usage)
+	}
+
+	// a budget that only fits a few cache entries evicts them
+	d.SetMemoryBudget(fixed + functionCacheEntrySize)
+	for i := uintptr(4); i < 8; i++ {
+		d.GetFunctionFromPC(pc + i)
+	}
+
+	usage = d.MemoryUsage()
+	if usage.Unmet || usage.Evictions == 0 || usage.Total > usage.Budget {
+		t.Errorf("the budget was not enforced: %+v", usage)
+	}
+}
@@ -0,0 +1,171 @@
+package raztracer
+
+import (
+	"debug/dwarf"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/razzie/raztracer/internal/dwarf/op"
+)
+
+// maxBatchGap is the largest gap between two address ranges that are still read together
+const maxBatchGap = 64
+
+// maxBatchVariableSize is the largest variable read in the batch, bigger ones are read in parts
+// by the reading limits anyway
+const maxBatchVariableSize = 64 * 1024
+
+// MemoryReader reads the memory of a process
+type MemoryReader interface {
+	PeekData(addr uintptr, out []byte) error
+}
+
+// ByteOrderReader is implemented by memory readers that know the byte order of the target
+type ByteOrderReader interface {
+	ByteOrder() binary.ByteOrder
+}
+
+// byteOrderOf returns the byte order of the memory or the tracer's byte order if it's unknown
+func byteOrderOf(mem MemoryReader) binary.ByteOrder {
+	if reader, ok := mem.(ByteOrderReader); ok {
+		return reader.ByteOrder()
+	}
+
+	return ByteOrder
+}
+
+type memoryBlock struct {
+	addr uintptr
+	data []byte
+}
+
+// memoryBatch serves reads from memory blocks fetched in bulk
+// and falls back to the process for addresses outside of the blocks
+type memoryBatch struct {
+	proc   Process
+	blocks []memoryBlock
+}
+
+// newMemoryBatch reads the coalesced address ranges of the process through /proc/pid/mem
+func newMemoryBatch(proc Process, ranges [][2]uintptr) *memoryBatch {
+	batch := &memoryBatch{proc: proc}
+
+	ranges = coalesceRanges(ranges, maxBatchGap)
+	if len(ranges) == 0 {
+		return batch
+	}
+
+	mem, err := os.Open(fmt.Sprintf("/proc/%d/mem", proc))
+	if err != nil {
+		return batch
+	}
+	defer mem.Close()
+
+	for _, rng := range ranges {
+		data := make([]byte, rng[1]-rng[0])
+		_, err := mem.ReadAt(data, int64(rng[0]))
+		if err != nil {
+			continue // the pieces of this range are read one by one
+		}
+
+		maskBreakpoints(proc, rng[0], data)
+		batch.blocks = append(batch.blocks, memoryBlock{addr: rng[0], data: data})
+	}
+
+	return batch
+}
+
+// PeekData implements MemoryReader
+func (batch *memoryBatch) PeekData(addr uintptr, out []byte) error {
+	i := sort.Search(len(batch.blocks), func(i int) bool {
+		block := &batch.blocks[i]
+		return block.addr+uintptr(len(block.data)) > addr
+	})
+
+	if i < len(batch.blocks) {
+		block := &batch.blocks[i]
+		if addr >= block.addr && addr+uintptr(len(out)) <= block.addr+uintptr(len(block.data)) {
+			copy(out, block.data[addr-block.addr:])
+			return nil
+		}
+	}
+
+	if err := batch.proc.PeekData(addr, out); err != nil {
+		return Error(err)
+	}
+	return nil
+}
+
+// ByteOrder implements ByteOrderReader
+func (batch *memoryBatch) ByteOrder() binary.ByteOrder {
+	return batch.proc.ByteOrder()
+}
+
+// coalesceRanges merges the overlapping and close address ranges
+func coalesceRanges(ranges [][2]uintptr, gap uintptr) [][2]uintptr {
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	sorted := make([][2]uintptr, len(ranges))
+	copy(sorted, ranges)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i][0] < sorted[j][0]
+	})
+
+	merged := [][2]uintptr{sorted[0]}
+	for _, rng := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if rng[0] <= last[1]+gap {
+			if rng[1] > last[1] {
+				last[1] = rng[1]
+			}
+			continue
+		}
+
+		merged = append(merged, rng)
+	}
+
+	return merged
+}
+
+// locationRanges returns the memory ranges read by the locations of the variables
+func locationRanges(pc uintptr, regs *op.DwarfRegisters, vars []*VariableEntry) [][2]uintptr {
+	var ranges [][2]uintptr
+
+	for _, v := range vars {
+		if v.Size == 0 && !v.IsPointer {
+			continue
+		}
+
+		loc, err := v.entry.Location(dwarf.AttrLocation, pc)
+		if err != nil || len(loc.instructions) == 0 || loc.parse(v.entry.data.withTLS(regs)) != nil {
+			continue
+		}
+
+		if len(loc.pieces) == 0 {
+			// structs and arrays are read whole, pointers are dereferenced by their address
+			size := uintptr(SizeofPtr)
+			if v.Size > int64(size) {
+				size = uintptr(v.Size)
+			}
+			if size > maxBatchVariableSize {
+				size = maxBatchVariableSize
+			}
+
+			ranges = append(ranges, [2]uintptr{loc.address, loc.address + size})
+			continue
+		}
+
+		for _, piece := range loc.pieces {
+			if !piece.IsRegister && piece.Size > 0 {
+				addr := uintptr(piece.Addr)
+				ranges = append(ranges, [2]uintptr{addr, addr + uintptr(piece.Size)})
+			}
+		}
+	}
+
+	return ranges
+}
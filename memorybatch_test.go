@@ -0,0 +1,68 @@
+package raztracer
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestCoalesceRanges(t *testing.T) {
+	tests := []struct {
+		ranges   [][2]uintptr
+		gap      uintptr
+		expected [][2]uintptr
+	}{
+		{nil, 64, nil},
+		{[][2]uintptr{{0x100, 0x108}}, 64, [][2]uintptr{{0x100, 0x108}}},
+		// unsorted, overlapping and contained ranges
+		{[][2]uintptr{{0x200, 0x210}, {0x100, 0x180}, {0x120, 0x130}, {0x170, 0x190}}, 0, [][2]uintptr{{0x100, 0x190}, {0x200, 0x210}}},
+		// ranges closer than the gap are merged
+		{[][2]uintptr{{0x100, 0x108}, {0x140, 0x148}, {0x200, 0x208}}, 64, [][2]uintptr{{0x100, 0x148}, {0x200, 0x208}}},
+		{[][2]uintptr{{0x100, 0x108}, {0x149, 0x150}}, 64, [][2]uintptr{{0x100, 0x108}, {0x149, 0x150}}},
+	}
+
+	for _, test := range tests {
+		if merged := coalesceRanges(test.ranges, test.gap); !reflect.DeepEqual(merged, test.expected) {
+			t.Errorf("%x (gap %d): expected %x, got %x", test.ranges, test.gap, test.expected, merged)
+		}
+	}
+}
+
+func TestMemoryBatchPeekData(t *testing.T) {
+	// the process doesn't exist, so only the blocks can serve the reads
+	batch := &memoryBatch{
+		proc: Process(-1),
+		blocks: []memoryBlock{
+			{addr: 0x1000, data: []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+			{addr: 0x2000, data: []byte{9, 10, 11, 12}},
+		},
+	}
+
+	tests := []struct {
+		addr     uintptr
+		size     int
+		expected []byte
+	}{
+		{0x1000, 8, []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+		{0x1002, 3, []byte{3, 4, 5}},
+		{0x2003, 1, []byte{12}},
+		{0x1006, 4, nil}, // past the end of the block
+		{0x1ffe, 4, nil}, // before the start of the block
+		{0x3000, 1, nil},
+	}
+
+	for _, test := range tests {
+		out := make([]byte, test.size)
+		err := batch.PeekData(test.addr, out)
+		if test.expected == nil {
+			if err == nil {
+				t.Errorf("%#x: the read outside of the blocks didn't reach the process", test.addr)
+			}
+			continue
+		}
+
+		if err != nil || !bytes.Equal(out, test.expected) {
+			t.Errorf("%#x: expected %v, got %v (%v)", test.addr, test.expected, out, err)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package raztracer
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Thresholds of the static call site counts used to estimate event rates
+const (
+	MediumRateCallSites = 5
+	HighRateCallSites   = 50
+)
+
+// EventRate is the estimated event rate of a planned breakpoint
+type EventRate string
+
+// Event rate estimates
+const (
+	RateUnknown EventRate = "unknown" // no direct calls found, e.g. only called indirectly or from other modules
+	RateLow     EventRate = "low"
+	RateMedium  EventRate = "medium"
+	RateHigh    EventRate = "high"
+)
+
+// PlanRequest contains the breakpoints and watchpoints to be planned
+type PlanRequest struct {
+	Functions   []string            `json:"functions"`   // function specs as accepted by SetBreakpointAtFunction
+	Watchpoints []string            `json:"watchpoints"` // global variable names or address:size pairs
+	Placement   BreakpointPlacement `json:"placement"`   // after prologue if empty
+}
+
+// BreakpointPlan is a resolved but not armed function breakpoint
+type BreakpointPlan struct {
+	Request   string               `json:"request"`
+	Locations []BreakpointLocation `json:"locations"`
+	CallSites int                  `json:"call_sites"` // static direct call sites in the module of the functions
+	Rate      EventRate            `json:"rate"`
+	Error     string               `json:"error,omitempty"`
+}
+
+// WatchpointPlan is a resolved but not armed watchpoint
+type WatchpointPlan struct {
+	Request    string     `json:"request"`
+	Address    uintptr    `json:"address"`
+	Size       uintptr    `json:"size"`
+	Pages      [2]uintptr `json:"pages"`                 // the write protected page range
+	SharedWith []string   `json:"shared_with,omitempty"` // globals on the same pages, their writes stop the process too
+	Error      string     `json:"error,omitempty"`
+}
+
+// TracePlan is the dry-run result of a PlanRequest
+type TracePlan struct {
+	Breakpoints   []BreakpointPlan `json:"breakpoints"`
+	Watchpoints   []WatchpointPlan `json:"watchpoints"`
+	HardwareSlots int              `json:"hardware_slots"` // watchpoints use page protection, no debug registers
+	Warnings      []string         `json:"warnings,omitempty"`
+}
+
+// Plan resolves the requested breakpoints and watchpoints using only the debug data,
+// without reading or modifying the process, so the session can be reviewed before arming it
+func (t *Tracer) Plan(req PlanRequest) *TracePlan {
+	if len(req.Placement) == 0 {
+		req.Placement = t.placement
+	}
+
+	return t.debugData.Plan(req)
+}
+
+// Plan resolves the requested breakpoints and watchpoints using only the debug data
+func (d *DebugData) Plan(req PlanRequest) *TracePlan {
+	if len(req.Placement) == 0 {
+		req.Placement = PlaceAfterPrologue
+	}
+
+	plan := &TracePlan{
+		Breakpoints: make([]BreakpointPlan, 0, len(req.Functions)),
+		Watchpoints: make([]WatchpointPlan, 0, len(req.Watchpoints)),
+	}
+
+	addresses := make(map[uintptr]string)
+
+	for _, spec := range req.Functions {
+		bp := d.planBreakpoint(spec, req.Placement)
+		for _, loc := range bp.Locations {
+			if other, found := addresses[loc.Address]; found && other != spec {
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s and %s share the breakpoint at %#x", other, spec, loc.Address))
+			}
+			addresses[loc.Address] = spec
+		}
+
+		if bp.Rate == RateHigh {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s has %d call sites, expect a high event rate", spec, bp.CallSites))
+		}
+
+		plan.Breakpoints = append(plan.Breakpoints, bp)
+	}
+
+	for _, spec := range req.Watchpoints {
+		wp := d.planWatchpoint(spec)
+		if len(wp.SharedWith) > 0 {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("watchpoint %s shares pages with %d other globals", spec, len(wp.SharedWith)))
+		}
+
+		plan.Watchpoints = append(plan.Watchpoints, wp)
+	}
+
+	return plan
+}
+
+func (d *DebugData) planBreakpoint(spec string, placement BreakpointPlacement) BreakpointPlan {
+	bp := BreakpointPlan{
+		Request:   spec,
+		Locations: make([]BreakpointLocation, 0),
+		Rate:      RateUnknown,
+	}
+
+	funcs, err := d.ResolveFunction(spec)
+	if err != nil {
+		bp.Error = errorMessage(err)
+		return bp
+	}
+
+	for _, fn := range funcs {
+		bp.Locations = append(bp.Locations, newBreakpointLocations(fn, d, placement)...)
+
+		module := fn.entry.data
+		if module == nil {
+			continue
+		}
+		bp.CallSites += module.directCallSites()[fn.LowPC]
+	}
+
+	switch {
+	case bp.CallSites >= HighRateCallSites:
+		bp.Rate = RateHigh
+	case bp.CallSites >= MediumRateCallSites:
+		bp.Rate = RateMedium
+	case bp.CallSites > 0:
+		bp.Rate = RateLow
+	}
+
+	return bp
+}
+
+func (d *DebugData) planWatchpoint(spec string) WatchpointPlan {
+	wp := WatchpointPlan{Request: spec}
+
+	if i := strings.LastIndexByte(spec, ':'); i > 0 && strings.HasPrefix(spec, "0x") {
+		addr, err := strconv.ParseUint(spec[:i], 0, 64)
+		if err == nil {
+			var size uint64
+			size, err = strconv.ParseUint(spec[i+1:], 0, 64)
+			wp.Address, wp.Size = uintptr(addr), uintptr(size)
+		}
+		if err != nil {
+			wp.Error = errorMessage(err)
+			return wp
+		}
+	} else {
+		v, err := d.GetGlobal(spec)
+		if err != nil {
+			wp.Error = errorMessage(err)
+			return wp
+		}
+
+		addr, ok := v.staticAddress()
+		if !ok {
+			wp.Error = fmt.Sprintf("%s has no static address", spec)
+			return wp
+		}
+		wp.Address, wp.Size = addr, uintptr(v.Size)
+	}
+
+	if wp.Size == 0 {
+		wp.Error = "invalid watchpoint size: 0"
+		return wp
+	}
+
+	pageSize := uintptr(os.Getpagesize())
+	wp.Pages = [2]uintptr{wp.Address &^ (pageSize - 1), (wp.Address + wp.Size + pageSize - 1) &^ (pageSize - 1)}
+
+	for _, v := range d.GetGlobals() {
+		addr, ok := v.staticAddress()
+		if !ok || (addr == wp.Address && v.Name == spec) {
+			continue
+		}
+
+		if addr+uintptr(v.Size) > wp.Pages[0] && addr < wp.Pages[1] {
+			wp.SharedWith = append(wp.SharedWith, v.Name)
+		}
+	}
+
+	return wp
+}
+
+// directCallSites returns the number of direct calls to each address of the module's code
+func (d *DebugData) directCallSites() map[uintptr]int {
+	if d.callSites != nil {
+		return d.callSites
+	}
+
+	d.callSites = make(map[uintptr]int)
+
+	code, addr, _ := d.GetElfSection("text")
+
+	for off := 0; off < len(code); {
+		target, length, isCall := directCallTarget(code[off:], addr+uintptr(off))
+		if isCall {
+			d.callSites[target]++
+		}
+		off += length
+	}
+
+	return d.callSites
+}
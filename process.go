@@ -1,14 +1,26 @@
 package raztracer
 
 import (
+	"encoding/binary"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"reflect"
 	"runtime"
 	"strconv"
 	"strings"
 	"syscall"
 	"time"
+	"unsafe"
+)
+
+const (
+	ptraceGetSigInfo  = 0x4202
+	sizeofSigInfo     = 128
+	sigInfoCodeOffset = 8
+	sigInfoAddrOffset = 16
+	sigInfoPIDOffset  = 16 // si_pid of signals sent by kill or tgkill
+	siTKill           = -6 // si_code of signals sent by tkill or tgkill
 )
 
 // Process is a wrapper around Linux's ptrace API
@@ -79,6 +91,7 @@ func (pid Process) Threads() ([]Process, error) {
 
 // Attach starts tracing the process and all of its threads
 func (pid Process) Attach() error {
+	countPtrace(1)
 	err := syscall.PtraceAttach(int(int(pid)))
 	if err == syscall.EPERM {
 		_, err := syscall.PtraceGetEventMsg(int(pid))
@@ -97,24 +110,47 @@ func (pid Process) Attach() error {
 
 // Detach stops the tracing the process
 func (pid Process) Detach() error {
-	return Error(syscall.PtraceDetach(int(pid)))
+	return Error(pid.DetachWithSig(0))
+}
+
+// DetachWithSig stops tracing the thread and delivers a signal to it (0 means no signal)
+func (pid Process) DetachWithSig(sig syscall.Signal) error {
+	countPtrace(1)
+	_, _, errno := syscall.Syscall6(syscall.SYS_PTRACE, syscall.PTRACE_DETACH, uintptr(pid), 0, uintptr(sig), 0, 0)
+	if errno != 0 {
+		return Error(errno)
+	}
+
+	return nil
 }
 
+// WaitForever is a timeout that makes Wait sleep in the kernel until the next event instead of polling
+const WaitForever time.Duration = -1
+
 // Wait waits for a trace event (signal or breakpoint stop)
 func (pid Process) Wait(status *syscall.WaitStatus, timeout time.Duration) (Process, error) {
 	pgid, _ := syscall.Getpgid(int(pid))
-	timer := time.NewTimer(timeout)
+	flags := syscall.WALL | syscall.WUNTRACED | syscall.WNOHANG
+
+	var timer <-chan time.Time
+	if timeout == WaitForever {
+		flags &^= syscall.WNOHANG
+	} else {
+		timer = time.NewTimer(timeout).C
+	}
 
 	for {
 		select {
-		case <-timer.C:
+		case <-timer:
 			return 0, nil
 
 		default:
 		}
 
-		wpid, err := syscall.Wait4(-int(pgid), status, syscall.WALL|syscall.WUNTRACED|syscall.WNOHANG, nil)
-		if err != nil {
+		wpid, err := syscall.Wait4(-int(pgid), status, flags, nil)
+		if err == syscall.EINTR {
+			continue
+		} else if err != nil {
 			return 0, Error(err)
 		}
 
@@ -123,36 +159,36 @@ func (pid Process) Wait(status *syscall.WaitStatus, timeout time.Duration) (Proc
 			continue
 		}
 
-		if status.Exited() || status.Continued() {
+		reason := DecodeWaitStatus(*status)
+		switch reason.Kind {
+		case StopExited, StopContinued:
 			continue
-		}
 
-		if status.Stopped() {
-			sig := status.StopSignal()
-			trapCause := status.TrapCause()
-
-			if sig == syscall.SIGTRAP {
-				switch trapCause {
-				case 0:
-					return Process(wpid), nil
+		case StopPtraceEvent:
+			if reason.Signal != syscall.SIGTRAP {
+				return Process(wpid), nil
+			}
 
-				case syscall.PTRACE_EVENT_CLONE, syscall.PTRACE_EVENT_FORK:
-					newpid, err := syscall.PtraceGetEventMsg(wpid)
-					if err != nil {
-						return 0, Error(err)
-					}
-					Process(newpid).Attach()
-					Process(newpid).Cont()
+			if reason.Event == EventClone || reason.Event == EventFork {
+				countPtrace(1)
+				newpid, err := syscall.PtraceGetEventMsg(wpid)
+				if err != nil {
+					return 0, Error(err)
 				}
+				Process(newpid).Attach()
+				Process(newpid).Cont()
 
-				syscall.PtraceCont(wpid, 0)
-				continue
+				// thread creation is reported to the caller
+				if reason.Event == EventClone {
+					return Process(wpid), nil
+				}
 			}
 
-			return Process(wpid), nil
-		}
+			countPtrace(1)
+			syscall.PtraceCont(wpid, 0)
+			continue
 
-		if status.Signaled() {
+		default:
 			return Process(wpid), nil
 		}
 	}
@@ -186,18 +222,30 @@ func (pid Process) simpleWait(timeout time.Duration) error {
 	return nil
 }
 
-// Cont continues the traced process
+// waitStop blocks until the given thread stops
+func (pid Process) waitStop() (syscall.WaitStatus, error) {
+	var status syscall.WaitStatus
+	_, err := syscall.Wait4(int(pid), &status, syscall.WALL, nil)
+	return status, Error(err)
+}
+
+// Cont continues the traced process without delivering a signal
 func (pid Process) Cont() error {
-	return Error(pid.ContWithSig(syscall.SIGCONT))
+	return Error(pid.ContWithSig(0))
 }
 
-// ContWithSig continues the traced process and delivers a signal
+// ContWithSig continues the traced process and delivers a signal (0 means no signal)
 func (pid Process) ContWithSig(sig syscall.Signal) error {
+	countPtrace(1)
 	return Error(syscall.PtraceCont(int(pid), int(sig)))
 }
 
-// Interrupt interrupts the traced process
+// Interrupt interrupts the traced process (nothing to do if it's already stopped)
 func (pid Process) Interrupt() error {
+	if pid.isTraceStopped() {
+		return nil
+	}
+
 	err := syscall.Kill(int(pid), syscall.SIGSTOP)
 	if err != nil {
 		return Error(err)
@@ -206,14 +254,93 @@ func (pid Process) Interrupt() error {
 	return Error(pid.simpleWait(time.Second))
 }
 
+// stat returns the fields of /proc/<pid>/stat following the command name
+func (pid Process) stat() ([]string, error) {
+	data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	// the command name is in parentheses and may contain spaces
+	end := strings.LastIndexByte(string(data), ')')
+	if end < 0 {
+		return nil, Errorf("invalid stat of %d", pid)
+	}
+
+	return strings.Fields(string(data[end+1:])), nil
+}
+
+// clockTicks is the unit of the times in /proc/<pid>/stat (USER_HZ)
+const clockTicks = 100
+
+// startTime returns when the process was started
+func (pid Process) startTime() (time.Time, error) {
+	fields, err := pid.stat()
+	if err != nil {
+		return time.Time{}, Error(err)
+	}
+
+	// starttime is the 22nd field of the stat, in clock ticks since boot
+	if len(fields) < 20 {
+		return time.Time{}, Errorf("invalid stat of %d", pid)
+	}
+	ticks, err := strconv.ParseUint(fields[19], 10, 64)
+	if err != nil {
+		return time.Time{}, Error(err)
+	}
+
+	data, err := ioutil.ReadFile("/proc/uptime")
+	if err != nil {
+		return time.Time{}, Error(err)
+	}
+	var uptime float64
+	if _, err := fmt.Sscan(string(data), &uptime); err != nil {
+		return time.Time{}, Error(err)
+	}
+
+	age := time.Duration(uptime*float64(time.Second)) - time.Duration(ticks)*time.Second/clockTicks
+	return time.Now().Add(-age), nil
+}
+
+// isTraceStopped returns true if the thread is in a ptrace stop
+func (pid Process) isTraceStopped() bool {
+	fields, err := pid.stat()
+	return err == nil && len(fields) > 0 && fields[0] == "t"
+}
+
 func (pid Process) getEventMsg() (uint, error) {
+	countPtrace(1)
 	rv, err := syscall.PtraceGetEventMsg(int(pid))
 	return rv, Error(err)
 }
 
+// getSigInfo returns the raw siginfo of the signal that stopped the thread
+func (pid Process) getSigInfo() ([sizeofSigInfo]byte, error) {
+	var info [sizeofSigInfo]byte
+	countPtrace(1)
+	_, _, errno := syscall.Syscall6(syscall.SYS_PTRACE, ptraceGetSigInfo,
+		uintptr(pid), 0, uintptr(unsafe.Pointer(&info[0])), 0, 0)
+	if errno != 0 {
+		return info, Error(errno)
+	}
+
+	return info, nil
+}
+
+// getFaultAddress returns the faulting memory address of the last SIGSEGV or SIGBUS
+func (pid Process) getFaultAddress() (uintptr, error) {
+	info, err := pid.getSigInfo()
+	if err != nil {
+		return 0, Error(err)
+	}
+
+	return ReadAddress(info[sigInfoAddrOffset : sigInfoAddrOffset+SizeofPtr]), nil
+}
+
 // GetRegs returns the register values of the process as a slice
 func (pid Process) GetRegs() ([]uint, error) {
 	var pregs syscall.PtraceRegs
+	countPtrace(1)
 	err := syscall.PtraceGetRegs(int(pid), &pregs)
 	if err != nil {
 		return nil, Error(err)
@@ -225,34 +352,66 @@ func (pid Process) GetRegs() ([]uint, error) {
 		regs[i] = uint(val.Field(i).Uint())
 	}
 
-	return nil, nil
+	return regs, nil
 }
 
 // SetRegs sets the registers of the process from the given slice of values
 func (pid Process) SetRegs(regs []uint) error {
 	var pregs syscall.PtraceRegs
 
-	val := reflect.ValueOf(pregs)
+	val := reflect.ValueOf(&pregs).Elem()
 	regs = regs[:val.NumField()]
 	for i := 0; i < len(regs); i++ {
 		val.Field(i).SetUint(uint64(regs[i]))
 	}
 
+	countPtrace(1)
 	return Error(syscall.PtraceSetRegs(int(pid), &pregs))
 }
 
-// PeekData reads arbitrary length data from the process' memory
+// PeekData reads arbitrary length data from the process' memory.
+// The trap instructions of enabled breakpoints are replaced by the original bytes.
 func (pid Process) PeekData(addr uintptr, out []byte) error {
+	countPtrace(peekCount(addr, len(out)))
 	_, err := syscall.PtracePeekData(int(pid), addr, out)
-	return Error(err)
+	if err != nil {
+		return Error(err)
+	}
+
+	maskBreakpoints(pid, addr, out)
+	return nil
+}
+
+// ReadMemory reads a memory range of the process in bulk through /proc/pid/mem.
+// The trap instructions of enabled breakpoints are replaced by the original bytes.
+func (pid Process) ReadMemory(addr uintptr, out []byte) error {
+	mem, err := os.Open(fmt.Sprintf("/proc/%d/mem", pid))
+	if err != nil {
+		return Error(err)
+	}
+	defer mem.Close()
+
+	_, err = mem.ReadAt(out, int64(addr))
+	if err != nil {
+		return Error(err)
+	}
+
+	maskBreakpoints(pid, addr, out)
+	return nil
 }
 
 // PokeData writes arbitrary length data to the process' memory
 func (pid Process) PokeData(addr uintptr, data []byte) error {
+	countPtrace(peekCount(addr, len(data)))
 	_, err := syscall.PtracePokeData(int(pid), addr, data)
 	return Error(err)
 }
 
+// ByteOrder implements ByteOrderReader. Traced processes run on the tracer's architecture.
+func (pid Process) ByteOrder() binary.ByteOrder {
+	return ByteOrder
+}
+
 // ReadAddressAt reads an address from the pointed location
 func (pid Process) ReadAddressAt(addr uintptr) (uintptr, error) {
 	data := make([]byte, SizeofPtr)
@@ -261,19 +420,83 @@ func (pid Process) ReadAddressAt(addr uintptr) (uintptr, error) {
 		return 0, Error(err)
 	}
 
-	return ReadAddress(data), nil
+	return ReadAddressWithOrder(data, pid.ByteOrder()), nil
 }
 
 func (pid Process) setOptions(options int) error {
+	countPtrace(1)
 	return Error(syscall.PtraceSetOptions(int(pid), options))
 }
 
-// SingleStep makes the process execute a single instruction and stop again
+// SingleStep makes the thread execute a single instruction and stop again.
+// Signals arriving before the step completes are sent to the thread again
+// after the step, so they are reported by the next wait.
 func (pid Process) SingleStep() error {
-	err := syscall.PtraceSingleStep(int(pid))
+	var pending []syscall.Signal
+
+	for {
+		countPtrace(1)
+		err := syscall.PtraceSingleStep(int(pid))
+		if err != nil {
+			return Error(err)
+		}
+
+		status, err := pid.waitStop()
+		if err != nil {
+			return Error(err)
+		}
+
+		if !status.Stopped() {
+			return Errorf("thread %d terminated during single step: %s", pid, pid.DecodeStop(status))
+		}
+
+		sig := status.StopSignal()
+		if sig == syscall.SIGTRAP {
+			if status.TrapCause() > 0 {
+				continue // ptrace event of a syscall, the step is not over yet
+			}
+			break
+		}
+
+		pending = append(pending, sig)
+	}
+
+	return pid.requeueSignals(pending)
+}
+
+// requeueSignals sends the signals to the thread again, so they are reported by the next wait
+func (pid Process) requeueSignals(signals []syscall.Signal) error {
+	var errors []error
+	tgid := pid.threadGroup()
+	for _, sig := range signals {
+		err := syscall.Tgkill(int(tgid), int(pid), sig)
+		if err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	return MergeErrors(errors)
+}
+
+// threadGroup returns the process the thread belongs to
+func (pid Process) threadGroup() Process {
+	status, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
 	if err != nil {
-		return Error(err)
+		return pid
 	}
 
-	return Error(pid.simpleWait(time.Second))
+	for _, line := range strings.Split(string(status), "\n") {
+		if !strings.HasPrefix(line, "Tgid:") {
+			continue
+		}
+
+		tgid, err := strconv.Atoi(strings.TrimSpace(line[len("Tgid:"):]))
+		if err != nil {
+			break
+		}
+
+		return Process(tgid)
+	}
+
+	return pid
 }
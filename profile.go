@@ -0,0 +1,134 @@
+package raztracer
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// ptraceCalls counts the ptrace requests issued by every tracer of the process
+var ptraceCalls uint64
+
+func countPtrace(n int) {
+	atomic.AddUint64(&ptraceCalls, uint64(n))
+}
+
+// peekCount returns the number of word-sized PTRACE_PEEKDATA requests needed to read 'size' bytes at 'addr'
+func peekCount(addr uintptr, size int) int {
+	if size <= 0 {
+		return 0
+	}
+
+	start := addr &^ (SizeofPtr - 1)
+	end := addr + uintptr(size)
+	return int((end - start + SizeofPtr - 1) / SizeofPtr)
+}
+
+// EventOverhead contains the cost of collecting the data of a single event
+type EventOverhead struct {
+	Collection  time.Duration `json:"collection"` // time from the stop until the event was ready
+	Unwind      time.Duration `json:"unwind"`
+	Variables   time.Duration `json:"variables"` // registers, frame variables and globals
+	PtraceCalls uint64        `json:"ptrace_calls"`
+}
+
+// OverheadStats contains the overhead the tracer imposed on the target since attaching
+type OverheadStats struct {
+	WallTime       time.Duration `json:"wall_time"`
+	StoppedTime    time.Duration `json:"stopped_time"` // time the target spent stopped by the tracer
+	StoppedPercent float64       `json:"stopped_percent"`
+	Stops          uint64        `json:"stops"`
+	Unwind         time.Duration `json:"unwind"`
+	Variables      time.Duration `json:"variables"`
+	PtraceCalls    uint64        `json:"ptrace_calls"` // process-wide, includes the calls of other tracers
+}
+
+// profiler measures where the tracer spends the time while the target is stopped
+type profiler struct {
+	start     time.Time
+	stoppedAt time.Time
+	stats     OverheadStats
+}
+
+func newProfiler() profiler {
+	return profiler{start: time.Now()}
+}
+
+// SetProfiling enables or disables attaching the collection overhead to each event.
+// The overall overhead is always available in GetStats().Overhead.
+func (t *Tracer) SetProfiling(enabled bool) {
+	t.profiling = enabled
+}
+
+// stopped records that the target was just found stopped
+func (p *profiler) stopped() {
+	p.stoppedAt = time.Now()
+	p.stats.Stops++
+}
+
+// resumed accounts the time the target was stopped since the last stop
+func (p *profiler) resumed() {
+	if p.stoppedAt.IsZero() {
+		return
+	}
+
+	p.stats.StoppedTime += time.Since(p.stoppedAt)
+	p.stoppedAt = time.Time{}
+}
+
+// overhead returns the statistics including the ongoing stop
+func (p *profiler) overhead() OverheadStats {
+	stats := p.stats
+	stats.WallTime = time.Since(p.start)
+	if !p.stoppedAt.IsZero() {
+		stats.StoppedTime += time.Since(p.stoppedAt)
+	}
+	if stats.WallTime > 0 {
+		stats.StoppedPercent = 100 * float64(stats.StoppedTime) / float64(stats.WallTime)
+	}
+	stats.PtraceCalls = atomic.LoadUint64(&ptraceCalls)
+	return stats
+}
+
+// eventProfile measures the collection of a single event
+type eventProfile struct {
+	profiler *profiler
+	overhead EventOverhead
+	calls    uint64
+}
+
+func (p *profiler) beginEvent() *eventProfile {
+	return &eventProfile{
+		profiler: p,
+		calls:    atomic.LoadUint64(&ptraceCalls),
+	}
+}
+
+// measure adds the time elapsed since 'start' to 'dst' and to the overall statistics in 'total'
+func (e *eventProfile) measure(start time.Time, dst, total *time.Duration) {
+	elapsed := time.Since(start)
+	*dst += elapsed
+	*total += elapsed
+}
+
+func (e *eventProfile) unwind(start time.Time) {
+	if e == nil {
+		return
+	}
+	e.measure(start, &e.overhead.Unwind, &e.profiler.stats.Unwind)
+}
+
+func (e *eventProfile) variables(start time.Time) {
+	if e == nil {
+		return
+	}
+	e.measure(start, &e.overhead.Variables, &e.profiler.stats.Variables)
+}
+
+// end returns the overhead of the event
+func (e *eventProfile) end() *EventOverhead {
+	if !e.profiler.stoppedAt.IsZero() {
+		e.overhead.Collection = time.Since(e.profiler.stoppedAt)
+	}
+	e.overhead.PtraceCalls = atomic.LoadUint64(&ptraceCalls) - e.calls
+	return &e.overhead
+}
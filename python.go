@@ -0,0 +1,386 @@
+package raztracer
+
+import (
+	"debug/elf"
+	"fmt"
+	"regexp"
+	"unicode/utf16"
+)
+
+// PythonFrame is a frame of the Python interpreter stack
+type PythonFrame struct {
+	Function string `json:"function"`
+	Filename string `json:"filename"`
+	Line     int    `json:"line"`
+}
+
+// PythonThread contains the Python stack of an interpreter thread state
+type PythonThread struct {
+	ThreadState uintptr        `json:"thread_state"`
+	Frames      []*PythonFrame `json:"frames"`
+}
+
+// String returns the python frame as a string
+func (f *PythonFrame) String() string {
+	return fmt.Sprintf("%s (%s:%d)", f.Function, f.Filename, f.Line)
+}
+
+// pythonOffsets contains the structure offsets of a CPython version (x86-64)
+type pythonOffsets struct {
+	interpHead      uintptr // _PyRuntimeState.interpreters.head
+	interpTstate    uintptr // PyInterpreterState.tstate_head
+	tstateNext      uintptr // PyThreadState.next
+	tstateFrame     uintptr // PyThreadState.frame
+	frameBack       uintptr // PyFrameObject.f_back
+	frameCode       uintptr // PyFrameObject.f_code
+	frameLasti      uintptr // PyFrameObject.f_lasti
+	codeFirstLineNo uintptr // PyCodeObject.co_firstlineno
+	codeFilename    uintptr // PyCodeObject.co_filename
+	codeName        uintptr // PyCodeObject.co_name
+	codeLineTable   uintptr // PyCodeObject.co_lnotab or co_linetable
+	wordCodeLasti   bool    // f_lasti counts instructions instead of bytes (3.10)
+}
+
+var pythonVersions = map[string]*pythonOffsets{
+	"3.7": {
+		interpHead: 24, interpTstate: 8, tstateNext: 8, tstateFrame: 24,
+		frameBack: 24, frameCode: 32, frameLasti: 104,
+		codeFirstLineNo: 36, codeFilename: 96, codeName: 104, codeLineTable: 112,
+	},
+	"3.8": {
+		interpHead: 32, interpTstate: 8, tstateNext: 8, tstateFrame: 24,
+		frameBack: 24, frameCode: 32, frameLasti: 104,
+		codeFirstLineNo: 40, codeFilename: 104, codeName: 112, codeLineTable: 120,
+	},
+	"3.9": {
+		interpHead: 32, interpTstate: 8, tstateNext: 8, tstateFrame: 24,
+		frameBack: 24, frameCode: 32, frameLasti: 104,
+		codeFirstLineNo: 40, codeFilename: 104, codeName: 112, codeLineTable: 120,
+	},
+	"3.10": {
+		interpHead: 32, interpTstate: 8, tstateNext: 8, tstateFrame: 24,
+		frameBack: 24, frameCode: 32, frameLasti: 96,
+		codeFirstLineNo: 40, codeFilename: 104, codeName: 112, codeLineTable: 120,
+		wordCodeLasti: true,
+	},
+}
+
+var pythonLibRegexp = regexp.MustCompile(`python(\d+\.\d+)`)
+
+// maximum number of frames and threads walked to protect against corrupt lists
+const (
+	maxPythonFrames  = 1024
+	maxPythonThreads = 256
+)
+
+// PythonInterpreter reads the interpreter state of a CPython process
+type PythonInterpreter struct {
+	pid     Process
+	version string
+	offsets *pythonOffsets
+	runtime uintptr // address of _PyRuntime
+}
+
+// NewPythonInterpreter detects a CPython interpreter in the process
+// by looking for the _PyRuntime symbol in the executable and libpython
+func NewPythonInterpreter(pid Process) (*PythonInterpreter, error) {
+	regions, err := pid.MemRegions()
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	root := pid.RootPath()
+	checked := make(map[string]bool)
+
+	for _, region := range regions {
+		if checked[region.Pathname] || region.Offset != 0 {
+			continue
+		}
+		checked[region.Pathname] = true
+
+		match := pythonLibRegexp.FindStringSubmatch(region.Pathname)
+		if match == nil {
+			continue
+		}
+
+		offsets, supported := pythonVersions[match[1]]
+		if !supported {
+			return nil, Errorf("unsupported python version: %s", match[1])
+		}
+
+		addr, err := findSymbolAddress(root+region.Pathname, "_PyRuntime", region.Address[0])
+		if err != nil {
+			continue
+		}
+
+		return &PythonInterpreter{
+			pid:     pid,
+			version: match[1],
+			offsets: offsets,
+			runtime: addr,
+		}, nil
+	}
+
+	return nil, Errorf("python interpreter not found in process %d", pid)
+}
+
+// Version returns the Python version of the interpreter
+func (py *PythonInterpreter) Version() string {
+	return py.version
+}
+
+// Threads returns the Python stacks of every thread state of the main interpreter
+func (py *PythonInterpreter) Threads() ([]PythonThread, error) {
+	off := py.offsets
+
+	interp, err := py.pid.ReadAddressAt(py.runtime + off.interpHead)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	tstate, err := py.pid.ReadAddressAt(interp + off.interpTstate)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	var threads []PythonThread
+
+	for i := 0; tstate != 0 && i < maxPythonThreads; i++ {
+		frames, err := py.frames(tstate)
+		if err != nil {
+			return threads, Error(err)
+		}
+
+		threads = append(threads, PythonThread{ThreadState: tstate, Frames: frames})
+
+		tstate, err = py.pid.ReadAddressAt(tstate + off.tstateNext)
+		if err != nil {
+			return threads, Error(err)
+		}
+	}
+
+	return threads, nil
+}
+
+func (py *PythonInterpreter) frames(tstate uintptr) ([]*PythonFrame, error) {
+	off := py.offsets
+
+	frame, err := py.pid.ReadAddressAt(tstate + off.tstateFrame)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	frames := make([]*PythonFrame, 0)
+
+	for i := 0; frame != 0 && i < maxPythonFrames; i++ {
+		f, err := py.frame(frame)
+		if err != nil {
+			return frames, Error(err)
+		}
+
+		frames = append(frames, f)
+
+		frame, err = py.pid.ReadAddressAt(frame + off.frameBack)
+		if err != nil {
+			return frames, Error(err)
+		}
+	}
+
+	return frames, nil
+}
+
+func (py *PythonInterpreter) frame(frame uintptr) (*PythonFrame, error) {
+	off := py.offsets
+
+	code, err := py.pid.ReadAddressAt(frame + off.frameCode)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	lasti, err := py.readInt32(frame + off.frameLasti)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	firstLine, err := py.readInt32(code + off.codeFirstLineNo)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	f := &PythonFrame{Line: int(firstLine)}
+
+	if addr, err := py.pid.ReadAddressAt(code + off.codeName); err == nil {
+		f.Function, _ = py.readUnicode(addr)
+	}
+
+	if addr, err := py.pid.ReadAddressAt(code + off.codeFilename); err == nil {
+		f.Filename, _ = py.readUnicode(addr)
+	}
+
+	if addr, err := py.pid.ReadAddressAt(code + off.codeLineTable); err == nil {
+		if table, err := py.readBytes(addr); err == nil {
+			if off.wordCodeLasti {
+				f.Line = lineFromLineTable(table, int(firstLine), int(lasti)*2)
+			} else {
+				f.Line = lineFromLnotab(table, int(firstLine), int(lasti))
+			}
+		}
+	}
+
+	return f, nil
+}
+
+// lineFromLnotab decodes co_lnotab (3.7 - 3.9)
+func lineFromLnotab(lnotab []byte, firstLine, lasti int) int {
+	line := firstLine
+	addr := 0
+
+	for i := 0; i+1 < len(lnotab); i += 2 {
+		addr += int(lnotab[i])
+		if addr > lasti {
+			break
+		}
+		line += int(int8(lnotab[i+1]))
+	}
+
+	return line
+}
+
+// lineFromLineTable decodes co_linetable (3.10)
+func lineFromLineTable(table []byte, firstLine, lasti int) int {
+	line := firstLine
+	addr := 0
+
+	for i := 0; i+1 < len(table); i += 2 {
+		start := addr
+		addr += int(table[i])
+		delta := int8(table[i+1])
+		if delta != -128 {
+			line += int(delta)
+		}
+		if lasti >= start && lasti < addr {
+			break
+		}
+	}
+
+	return line
+}
+
+func (py *PythonInterpreter) readInt32(addr uintptr) (int32, error) {
+	data := make([]byte, 4)
+	err := py.pid.PeekData(addr, data)
+	return int32(py.pid.ByteOrder().Uint32(data)), Error(err)
+}
+
+// readBytes reads the content of a PyBytesObject
+func (py *PythonInterpreter) readBytes(obj uintptr) ([]byte, error) {
+	size, err := py.pid.ReadAddressAt(obj + 16) // ob_size
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	if size > 1<<20 {
+		return nil, Errorf("bytes object too large: %d", size)
+	}
+
+	data := make([]byte, size)
+	err = py.pid.PeekData(obj+32, data) // ob_sval
+	return data, Error(err)
+}
+
+// readUnicode reads the content of a compact PyUnicodeObject
+func (py *PythonInterpreter) readUnicode(obj uintptr) (string, error) {
+	length, err := py.pid.ReadAddressAt(obj + 16)
+	if err != nil {
+		return "", Error(err)
+	}
+
+	if length > 1<<16 {
+		return "", Errorf("string object too large: %d", length)
+	}
+
+	stateData := make([]byte, 4)
+	err = py.pid.PeekData(obj+32, stateData)
+	if err != nil {
+		return "", Error(err)
+	}
+
+	// state bitfield: interned:2 kind:3 compact:1 ascii:1 ready:1
+	state := py.pid.ByteOrder().Uint32(stateData)
+	kind := int((state >> 2) & 7)
+	ascii := state&(1<<6) != 0
+
+	dataAddr := obj + 48 // sizeof(PyASCIIObject)
+	if !ascii {
+		dataAddr = obj + 72 // sizeof(PyCompactUnicodeObject)
+	}
+
+	if kind < 1 || kind > 4 {
+		return "", Errorf("unsupported unicode kind: %d", kind)
+	}
+
+	data := make([]byte, int(length)*kind)
+	err = py.pid.PeekData(dataAddr, data)
+	if err != nil {
+		return "", Error(err)
+	}
+
+	switch kind {
+	case 2:
+		units := make([]uint16, length)
+		for i := range units {
+			units[i] = py.pid.ByteOrder().Uint16(data[i*2:])
+		}
+		return string(utf16.Decode(units)), nil
+
+	case 4:
+		runes := make([]rune, length)
+		for i := range runes {
+			runes[i] = rune(py.pid.ByteOrder().Uint32(data[i*4:]))
+		}
+		return string(runes), nil
+
+	default:
+		runes := make([]rune, length)
+		for i, c := range data {
+			runes[i] = rune(c)
+		}
+		return string(runes), nil
+	}
+}
+
+// findSymbolAddress returns the runtime address of a symbol in the ELF file
+// mapped at 'base'
+func findSymbolAddress(path, name string, base uintptr) (uintptr, error) {
+	file, err := elf.Open(path)
+	if err != nil {
+		return 0, Error(err)
+	}
+	defer file.Close()
+
+	dynsyms, _ := file.DynamicSymbols()
+	syms, _ := file.Symbols()
+
+	for _, sym := range append(dynsyms, syms...) {
+		if sym.Name != name {
+			continue
+		}
+
+		if file.Type == elf.ET_DYN {
+			return base + uintptr(sym.Value), nil
+		}
+
+		return uintptr(sym.Value), nil
+	}
+
+	return 0, Errorf("symbol not found: %s", name)
+}
+
+// String returns the python thread stack as a string
+func (thread *PythonThread) String() string {
+	str := fmt.Sprintf("thread state %#x", thread.ThreadState)
+	for _, f := range thread.Frames {
+		str += "\n  " + f.String()
+	}
+	return str
+}
@@ -0,0 +1,160 @@
+package raztracer
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestLineFromLnotab(t *testing.T) {
+	// bytecode offset/line increments: 0:+1, 6:+1, 14:+2, 18:-1
+	lnotab := []byte{0, 1, 6, 1, 8, 2, 4, 0xff}
+
+	tests := []struct{ lasti, line int }{
+		{0, 11},
+		{4, 11},
+		{6, 12},
+		{13, 12},
+		{14, 14},
+		{18, 13},
+		{100, 13},
+	}
+
+	for _, test := range tests {
+		if line := lineFromLnotab(lnotab, 10, test.lasti); line != test.line {
+			t.Errorf("lasti %d: got line %d, expected %d", test.lasti, line, test.line)
+		}
+	}
+}
+
+func TestLineFromLineTable(t *testing.T) {
+	// ranges: [0,2) +1, [2,6) +1, [6,8) no line, [8,14) +2
+	table := []byte{2, 1, 4, 1, 2, 0x80, 6, 2}
+
+	tests := []struct{ lasti, line int }{
+		{0, 6},
+		{3, 7},
+		{6, 7}, // no line number, the previous one is kept
+		{8, 9},
+		{13, 9},
+	}
+
+	for _, test := range tests {
+		if line := lineFromLineTable(table, 5, test.lasti); line != test.line {
+			t.Errorf("lasti %d: got line %d, expected %d", test.lasti, line, test.line)
+		}
+	}
+}
+
+func TestPythonOffsets(t *testing.T) {
+	const ptr = 8
+	const varObjectHead = 3 * ptr // ob_refcnt, ob_type, ob_size
+
+	for version, off := range pythonVersions {
+		fields := map[string]uintptr{
+			"interpHead":    off.interpHead,
+			"interpTstate":  off.interpTstate,
+			"tstateNext":    off.tstateNext,
+			"tstateFrame":   off.tstateFrame,
+			"frameBack":     off.frameBack,
+			"frameCode":     off.frameCode,
+			"frameLasti":    off.frameLasti,
+			"codeFilename":  off.codeFilename,
+			"codeName":      off.codeName,
+			"codeLineTable": off.codeLineTable,
+		}
+		for name, offset := range fields {
+			if offset%ptr != 0 {
+				t.Errorf("%s: %s is not pointer aligned: %d", version, name, offset)
+			}
+		}
+
+		// PyFrameObject starts with PyObject_VAR_HEAD, f_back and f_code
+		if off.frameBack != varObjectHead || off.frameCode != off.frameBack+ptr {
+			t.Errorf("%s: unexpected frame layout: f_back %d, f_code %d", version, off.frameBack, off.frameCode)
+		}
+
+		// co_filename, co_name and the line table are adjacent in PyCodeObject
+		if off.codeName != off.codeFilename+ptr || off.codeLineTable != off.codeName+ptr {
+			t.Errorf("%s: unexpected code layout: co_filename %d, co_name %d, line table %d",
+				version, off.codeFilename, off.codeName, off.codeLineTable)
+		}
+
+		// co_firstlineno is an int among the leading int fields
+		if off.codeFirstLineNo%4 != 0 || off.codeFirstLineNo < 2*ptr || off.codeFirstLineNo >= off.codeFilename {
+			t.Errorf("%s: unexpected co_firstlineno offset: %d", version, off.codeFirstLineNo)
+		}
+
+		// f_lasti was counted in code units since 3.10
+		wordCode := version == "3.10"
+		if off.wordCodeLasti != wordCode {
+			t.Errorf("%s: wordCodeLasti is %v", version, off.wordCodeLasti)
+		}
+	}
+}
+
+func TestNewPythonInterpreter(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 is not installed")
+	}
+
+	cmd := exec.Command(python, "-c", "import time\nprint('ready', flush=True)\ndef waiting():\n    time.sleep(10)\nwaiting()")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the killed interpreter is reaped, so the tracers of the other tests don't see its exit
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+
+	ready := make([]byte, 6)
+	if _, err := stdout.Read(ready); err != nil {
+		t.Fatal(err)
+	}
+
+	version, err := exec.Command(python, "-c", "import sys; print('%d.%d' % sys.version_info[:2])").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	py, err := NewPythonInterpreter(Process(cmd.Process.Pid))
+	if _, supported := pythonVersions[strings.TrimSpace(string(version))]; !supported {
+		if err == nil || !strings.Contains(err.Error(), "unsupported python version") {
+			t.Errorf("python %s is not supported, got %v", version, err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tracer, err := NewTracer(cmd.Process.Pid)
+	skipIfNotPermitted(t, err)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tracer.Detach()
+
+	// the interpreter is stopped in time.sleep() called by waiting()
+	threads, err := py.Threads()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, thread := range threads {
+		for _, frame := range thread.Frames {
+			if frame.Function == "waiting" && frame.Line == 4 {
+				return
+			}
+		}
+	}
+
+	t.Errorf("the python frame of waiting() was not found in %v", threads)
+}
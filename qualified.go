@@ -0,0 +1,95 @@
+package raztracer
+
+import (
+	"debug/dwarf"
+	"strings"
+)
+
+// scopeSeparator separates the namespaces and classes in qualified names
+const scopeSeparator = "::"
+
+// anonymousNamespace is the scope name of namespaces without a name
+const anonymousNamespace = "(anonymous namespace)"
+
+// qualifiedNames returns the fully qualified names (namespace::Class::method)
+// of the named entries in the compilation unit by their offset
+func (cu *CUEntry) qualifiedNames() (map[dwarf.Offset]string, error) {
+	names := make(map[dwarf.Offset]string)
+
+	reader := cu.entry.data.dwarfData.Reader()
+	reader.Seek(cu.entry.entry.Offset)
+
+	// the CU entry itself
+	if _, err := reader.Next(); err != nil {
+		return nil, Error(err)
+	}
+
+	scopes := []string{""}
+
+	for len(scopes) > 0 {
+		entry, err := reader.Next()
+		if err != nil {
+			return names, Error(err)
+		}
+		if entry == nil {
+			break
+		}
+
+		if entry.Tag == 0 {
+			scopes = scopes[:len(scopes)-1]
+			continue
+		}
+
+		scope := scopes[len(scopes)-1]
+		name, hasName := entry.Val(dwarf.AttrName).(string)
+		if !hasName && entry.Tag == dwarf.TagNamespace {
+			name, hasName = anonymousNamespace, true
+		}
+
+		qualified := scope
+		if hasName {
+			qualified = joinScope(scope, name)
+			names[entry.Offset] = qualified
+		}
+
+		if entry.Children {
+			switch entry.Tag {
+			case dwarf.TagNamespace, dwarf.TagClassType, dwarf.TagStructType,
+				dwarf.TagUnionType, dwarf.TagSubprogram:
+				scopes = append(scopes, qualified)
+			default:
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+
+	return names, nil
+}
+
+// qualifiedName returns the qualified name of the entry. Out-of-line definitions
+// and inlined instances take the name of their declaration.
+func qualifiedName(de *DebugEntry, names map[dwarf.Offset]string) (string, bool) {
+	for _, attr := range []dwarf.Attr{dwarf.AttrSpecification, dwarf.AttrAbstractOrigin} {
+		if off, ok := de.Val(attr).(dwarf.Offset); ok {
+			if name, found := names[off]; found {
+				return name, true
+			}
+		}
+	}
+
+	name, found := names[de.entry.Offset]
+	return name, found
+}
+
+func joinScope(scope, name string) string {
+	if len(scope) == 0 {
+		return name
+	}
+	return scope + scopeSeparator + name
+}
+
+// matchFunctionName returns whether the qualified function name matches 'name',
+// which can be partially qualified (e.g. Class::method matches ns::Class::method)
+func matchFunctionName(qualified, name string) bool {
+	return qualified == name || strings.HasSuffix(qualified, scopeSeparator+name)
+}
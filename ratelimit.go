@@ -0,0 +1,115 @@
+package raztracer
+
+import (
+	"syscall"
+	"time"
+)
+
+// terminatingSignals are never dropped by the rate limits as they end the process by default
+var terminatingSignals = append([]syscall.Signal{
+	syscall.SIGTERM,
+	syscall.SIGINT,
+	syscall.SIGQUIT,
+	syscall.SIGKILL,
+}, DefaultFatalSignals...)
+
+// rateLimiter allows a limited number of events in every one second window
+type rateLimiter struct {
+	limit  int
+	window time.Time
+	count  int
+}
+
+func newRateLimiter(perSecond int) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+
+	return &rateLimiter{limit: perSecond}
+}
+
+func (rl *rateLimiter) allow(now time.Time) bool {
+	if rl == nil {
+		return true
+	}
+
+	if now.Sub(rl.window) >= time.Second {
+		rl.window = now
+		rl.count = 0
+	}
+
+	if rl.count >= rl.limit {
+		return false
+	}
+
+	rl.count++
+	return true
+}
+
+// SetEventRateLimit sets the maximum number of reported events per second (0 means unlimited).
+// Events over the limit are not reported, the process is continued automatically.
+// Terminating signals, exits and exit path breakpoints are always reported.
+func (t *Tracer) SetEventRateLimit(perSecond int) {
+	t.eventLimit = newRateLimiter(perSecond)
+}
+
+// SetBreakpointRateLimit sets the maximum number of reported hits per second
+// of the breakpoint at 'addr' (0 means unlimited)
+func (t *Tracer) SetBreakpointRateLimit(addr uintptr, perSecond int) error {
+	if _, found := t.breakpoints[addr]; !found {
+		return Errorf("breakpoint not found at %#x", addr)
+	}
+
+	limiter := newRateLimiter(perSecond)
+	if limiter == nil {
+		delete(t.bpLimits, addr)
+	} else {
+		t.bpLimits[addr] = limiter
+	}
+
+	return nil
+}
+
+// isRateLimited returns false if the event must be reported regardless of the rate limits
+func isRateLimited(evt *TraceEvent) bool {
+	if evt.IsExitPath || evt.Reason.Terminated() || evt.Reason.IsEvent(EventExit) {
+		return false
+	}
+
+	if evt.IsBreakpoint {
+		return true
+	}
+
+	for _, sig := range terminatingSignals {
+		if evt.Reason.IsSignal(sig) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// dropEvent returns true if the event exceeds the rate limits and shouldn't be reported
+func (t *Tracer) dropEvent(evt *TraceEvent) bool {
+	if !isRateLimited(evt) {
+		return false
+	}
+
+	now := time.Now()
+
+	if evt.IsBreakpoint && !t.bpLimits[evt.PC].allow(now) {
+		t.stats.DroppedEvents++
+		t.stats.DroppedBreakpoints[evt.PC]++
+		return true
+	}
+
+	if !t.eventLimit.allow(now) {
+		t.stats.DroppedEvents++
+		if evt.IsBreakpoint {
+			t.stats.DroppedBreakpoints[evt.PC]++
+		}
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,92 @@
+package raztracer
+
+import (
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	if newRateLimiter(0) != nil {
+		t.Error("a zero limit should mean no limiter")
+	}
+
+	var unlimited *rateLimiter
+	if !unlimited.allow(time.Now()) {
+		t.Error("a nil limiter should allow everything")
+	}
+
+	rl := newRateLimiter(2)
+	start := time.Unix(1000, 0)
+
+	expected := []struct {
+		at    time.Duration
+		allow bool
+	}{
+		{0, true},
+		{100 * time.Millisecond, true},
+		{200 * time.Millisecond, false},
+		{999 * time.Millisecond, false},
+		{time.Second, true}, // new window
+		{1500 * time.Millisecond, true},
+		{1900 * time.Millisecond, false},
+	}
+
+	for _, e := range expected {
+		if allow := rl.allow(start.Add(e.at)); allow != e.allow {
+			t.Errorf("at %v: allow is %v, expected %v", e.at, allow, e.allow)
+		}
+	}
+}
+
+func signalEvent(sig syscall.Signal) *TraceEvent {
+	return &TraceEvent{
+		Signal: sig,
+		Reason: StopReason{Kind: StopSignalDelivery, Signal: sig},
+	}
+}
+
+func TestDropEvent(t *testing.T) {
+	tracer := &Tracer{
+		breakpoints: make(map[uintptr]*Breakpoint),
+		bpLimits:    make(map[uintptr]*rateLimiter),
+		stats:       newTracerStats(),
+	}
+	tracer.SetEventRateLimit(1)
+
+	bp := &TraceEvent{IsBreakpoint: true, PC: 0x1000, Reason: StopReason{Kind: StopSignalDelivery, Signal: syscall.SIGTRAP}}
+	if tracer.dropEvent(bp) {
+		t.Fatal("the first event was dropped")
+	}
+
+	// the limit is exhausted for the rest of the second
+	if !tracer.dropEvent(bp) {
+		t.Error("a breakpoint over the limit was reported")
+	}
+	if !tracer.dropEvent(signalEvent(syscall.SIGUSR1)) {
+		t.Error("a non-fatal signal over the limit was reported")
+	}
+
+	exitPath := &TraceEvent{IsBreakpoint: true, IsExitPath: true, PC: 0x2000, Reason: bp.Reason}
+	mustReport := map[string]*TraceEvent{
+		"SIGSEGV":   signalEvent(syscall.SIGSEGV),
+		"SIGABRT":   signalEvent(syscall.SIGABRT),
+		"SIGTERM":   signalEvent(syscall.SIGTERM),
+		"exit path": exitPath,
+		"exited":    {Reason: StopReason{Kind: StopExited}},
+		"killed":    {Reason: StopReason{Kind: StopKilled, Signal: syscall.SIGKILL}},
+		"exit":      {Reason: StopReason{Kind: StopPtraceEvent, Event: EventExit}},
+	}
+	for name, evt := range mustReport {
+		if tracer.dropEvent(evt) {
+			t.Errorf("%s event was dropped", name)
+		}
+	}
+
+	if dropped := tracer.stats.DroppedEvents; dropped != 2 {
+		t.Errorf("%d dropped events are counted, expected 2", dropped)
+	}
+	if dropped := tracer.stats.DroppedBreakpoints[0x1000]; dropped != 1 {
+		t.Errorf("%d dropped hits are counted for the breakpoint, expected 1", dropped)
+	}
+}
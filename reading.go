@@ -1,8 +1,10 @@
 package raztracer
 
 import (
+	"bytes"
 	"encoding/hex"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/razzie/raztracer/internal/dwarf/op"
@@ -10,52 +12,125 @@ import (
 
 // Reading contains the PC dependent location and value of a variable
 type Reading struct {
-	Name     string `json:"name"`
-	Type     string `json:"type,omitempty"`
-	Size     int64  `json:"size,omitempty"`
-	Location string `json:"location"`
-	Value    string `json:"value"`
-	Error    string `json:"error"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type,omitempty"`
+	Size      int64     `json:"size,omitempty"`
+	Location  string    `json:"location"`
+	Address   uintptr   `json:"address,omitempty"` // address of the raw data (0 if in registers)
+	Value     string    `json:"value"`
+	Raw       []byte    `json:"raw,omitempty"`
+	Error     string    `json:"error"`
+	Truncated bool      `json:"truncated,omitempty"` // the value was cut at a reading limit
+	Children  []Reading `json:"children,omitempty"`  // members of the dereferenced data
 }
 
 // NewReading returns a new Reading
 func NewReading(v *VariableEntry, pid int, pc uintptr, regs *op.DwarfRegisters) (*Reading, error) {
+	r, err := NewReadingWithOptions(v, pid, pc, regs, DefaultReadingOptions)
+	return r, Error(err)
+}
+
+// NewReadingWithOptions returns a new Reading using the given options
+func NewReadingWithOptions(v *VariableEntry, pid int, pc uintptr, regs *op.DwarfRegisters, opts ReadingOptions) (*Reading, error) {
+	r, err := newReading(v, Process(pid), pid, pc, regs, opts)
+	return r, Error(err)
+}
+
+func newReading(v *VariableEntry, mem MemoryReader, pid int, pc uintptr, regs *op.DwarfRegisters, opts ReadingOptions) (*Reading, error) {
+	r, err := readVariable(v, mem, pid, pc, regs, opts)
+	if err != nil {
+		return r, Error(err)
+	}
+
+	if !v.IsPointer {
+		formatReading(mem, r, v.Type, r.Address, r.Raw, v.Size, false)
+	} else if opts.PointerDepth > 0 && !isStringType(v.Type) {
+		formatReading(mem, r, strings.TrimSuffix(v.Type, "*"), r.Address, r.Raw, v.DerefSize, true)
+	}
+
+	return r, nil
+}
+
+func readVariable(v *VariableEntry, mem MemoryReader, pid int, pc uintptr, regs *op.DwarfRegisters, opts ReadingOptions) (*Reading, error) {
 	r := &Reading{
 		Name: v.Name,
 		Type: v.Type,
 		Size: v.DerefSize,
 	}
 
-	loc, data, err := v.GetValue(pid, pc, regs)
+	loc, data, err := v.getValue(mem, pc, regs)
 	if loc != nil {
 		r.Location = loc.String()
+		r.Address = loc.Address()
 	}
 	if err != nil {
-		r.Error = fmt.Sprint(err.Err)
+		r.Error = errorMessage(err)
 		return r, Error(err)
 	}
 
 	if v.IsPointer {
-		addr := ReadAddress(data)
+		addr := ReadAddressWithOrder(data, byteOrderOf(mem))
+		if opts.PointerDepth <= 0 {
+			r.Value = fmt.Sprintf("%#x", addr)
+			r.Raw = data
+			return r, nil
+		}
+
 		r.Value = fmt.Sprintf("%#x : ", addr)
+		r.Address = addr
 
 		if isStringType(v.Type) {
 			v.Size = 0
-			data, err := readString(pid, uintptr(addr))
+			data, truncated, err := readString(Process(pid), uintptr(addr), opts.stringLimit())
 			if err != nil {
 				return r, Error(err)
 			}
 
 			r.Value += string(data)
+			r.Raw = data
+			r.Truncated = truncated
 			return r, nil
 		}
 
-		data = make([]byte, v.Size)
-		err := Process(pid).PeekData(addr, data)
+		size := v.Size
+		elem := v.pointeeType()
+		if opts.PointerDepth > 1 && elem != nil && v.DerefSize > size {
+			size = v.DerefSize
+		}
+
+		count := opts.arrayLimit()
+		if count > 1 && elem != nil && elem.Size() > 0 {
+			size = elem.Size() * int64(count)
+		}
+
+		if size > maxPointeeSize {
+			size = maxPointeeSize
+			r.Truncated = true
+		}
+
+		data = make([]byte, size)
+		err := mem.PeekData(addr, data)
+		if err != nil && count > 1 && v.Size < size {
+			// the pointer doesn't point to an array, read a single element
+			data = make([]byte, v.Size)
+			err = mem.PeekData(addr, data)
+			count = 1
+			r.Truncated = false
+		}
 		if err != nil {
 			r.Error = fmt.Sprintf("couldn't read data at location:%#x", addr)
 			return r, Error(err)
 		}
+
+		visited := map[uintptr]bool{addr: true}
+		if count > 1 && elem != nil && elem.Size() > 0 {
+			r.Children = expandPointeeArray(mem, elem, addr, data, opts.PointerDepth, visited)
+		} else {
+			r.Children = expandPointee(mem, elem, addr, data, opts.PointerDepth-1, visited)
+		}
+		r.Value += "0x" + hex.EncodeToString(data)
+		r.Raw = data
+		return r, nil
 	}
 
 	if len(data) > int(v.Size) {
@@ -63,16 +138,25 @@ func NewReading(v *VariableEntry, pid int, pc uintptr, regs *op.DwarfRegisters)
 	}
 
 	r.Value += "0x" + hex.EncodeToString(data)
+	r.Raw = data
 	return r, nil
 
 }
 
 // GetReadings returns returns variable readings
 func GetReadings(pid int, pc uintptr, regs *op.DwarfRegisters, vars ...*VariableEntry) ([]Reading, error) {
+	readings, err := GetReadingsWithOptions(pid, pc, regs, DefaultReadingOptions, vars...)
+	return readings, Error(err)
+}
+
+// GetReadingsWithOptions returns variable readings using the given options.
+// The memory locations of the variables are read in bulk.
+func GetReadingsWithOptions(pid int, pc uintptr, regs *op.DwarfRegisters, opts ReadingOptions, vars ...*VariableEntry) ([]Reading, error) {
 	var errors []error
 	readings := make([]Reading, 0, len(vars))
+	mem := newMemoryBatch(Process(pid), locationRanges(pc, regs, vars))
 	for _, v := range vars {
-		r, err := NewReading(v, pid, pc, regs)
+		r, err := newReading(v, mem, pid, pc, regs, opts)
 		if err != nil {
 			errors = append(errors, err)
 		} else {
@@ -101,35 +185,38 @@ func isStringType(typeName string) bool {
 	}
 }
 
-func readString(pid int, addr uintptr) ([]byte, error) {
+// readString reads a null terminated string of at most 'maxLen' bytes in page sized chunks.
+// Returns true if the string was truncated.
+func readString(proc Process, addr uintptr, maxLen int) ([]byte, bool, error) {
 	str := make([]byte, 0)
-	proc := Process(pid)
+	pageSize := uintptr(os.Getpagesize())
 
-	for {
-		var buf [SizeofPtr]byte
+	for len(str) < maxLen {
+		// chunks don't cross pages, so the string can end right before an unmapped page
+		chunk := pageSize - addr%pageSize
+		if remaining := uintptr(maxLen - len(str)); chunk > remaining {
+			chunk = remaining
+		}
 
-		err := proc.PeekData(addr, buf[:])
+		buf := make([]byte, chunk)
+		err := proc.ReadMemory(addr, buf)
+		if err != nil {
+			err = proc.PeekData(addr, buf)
+		}
 		if err != nil {
 			if len(str) == 0 {
-				return nil, Error(err)
+				return nil, false, Error(err)
 			}
-			break
+			return str, false, nil
 		}
-		addr += uintptr(len(buf))
+		addr += chunk
 
-		for i, c := range buf {
-			if c == 0 {
-				str = append(str, buf[:i]...)
-				return str, nil
-			}
+		if i := bytes.IndexByte(buf, 0); i >= 0 {
+			return append(str, buf[:i]...), false, nil
 		}
 
-		str = append(str, buf[:]...)
-
-		if len(str) > 256 {
-			break
-		}
+		str = append(str, buf...)
 	}
 
-	return str, nil
+	return str, true, nil
 }
@@ -0,0 +1,197 @@
+package raztracer
+
+import (
+	"debug/dwarf"
+	"encoding/hex"
+	"fmt"
+)
+
+// maxPointeeSize limits the amount of memory read when a pointer is dereferenced
+const maxPointeeSize = 4096
+
+// Default reading limits
+const (
+	DefaultMaxStringLen     = 256
+	DefaultMaxArrayElements = 1
+)
+
+// ReadingOptions control how variable values are read
+type ReadingOptions struct {
+	// PointerDepth is the number of pointer levels dereferenced. 0 only reads the address,
+	// 1 reads the pointed data, higher values also expand the pointer members of pointed structs.
+	PointerDepth int `json:"pointer_depth"`
+	// MaxStringLen is the maximum number of bytes read from C strings (DefaultMaxStringLen if 0)
+	MaxStringLen int `json:"max_string_len"`
+	// MaxArrayElements is the number of elements read when a pointer is dereferenced,
+	// treating the pointed data as an array (DefaultMaxArrayElements if 0)
+	MaxArrayElements int `json:"max_array_elements"`
+}
+
+// DefaultReadingOptions dereference pointers exactly one level
+var DefaultReadingOptions = ReadingOptions{
+	PointerDepth:     1,
+	MaxStringLen:     DefaultMaxStringLen,
+	MaxArrayElements: DefaultMaxArrayElements,
+}
+
+func (opts *ReadingOptions) stringLimit() int {
+	if opts.MaxStringLen <= 0 {
+		return DefaultMaxStringLen
+	}
+	return opts.MaxStringLen
+}
+
+func (opts *ReadingOptions) arrayLimit() int {
+	if opts.MaxArrayElements <= 0 {
+		return DefaultMaxArrayElements
+	}
+	return opts.MaxArrayElements
+}
+
+// SetReadingOptions sets the options used to read variables in events
+func (t *Tracer) SetReadingOptions(opts ReadingOptions) {
+	t.readingOpts = opts
+}
+
+// GetReadingOptions returns the options used to read variables in events
+func (t *Tracer) GetReadingOptions() ReadingOptions {
+	return t.readingOpts
+}
+
+// pointeeType returns the type pointed by the variable or nil if unknown
+func (v *VariableEntry) pointeeType() dwarf.Type {
+	off, ok := v.entry.Val(dwarf.AttrType).(dwarf.Offset)
+	if !ok || v.entry.data == nil {
+		return nil
+	}
+
+	typ, err := v.entry.data.dwarfData.Type(off)
+	if err != nil {
+		return nil
+	}
+
+	ptr, ok := unqualifiedType(typ).(*dwarf.PtrType)
+	if !ok {
+		return nil
+	}
+
+	return ptr.Type
+}
+
+// expandPointee returns the readings of the members of the pointed data.
+// Pointer members are dereferenced while 'depth' allows and the addresses
+// in 'visited' are not dereferenced again to break cycles.
+func expandPointee(mem MemoryReader, elem dwarf.Type, addr uintptr, data []byte, depth int, visited map[uintptr]bool) []Reading {
+	if depth <= 0 || elem == nil {
+		return nil
+	}
+
+	switch t := unqualifiedType(elem).(type) {
+	case *dwarf.StructType:
+		children := make([]Reading, 0, len(t.Field))
+		for _, field := range t.Field {
+			child := newMemberReading(mem, field.Name, field.Type, addr+uintptr(field.ByteOffset),
+				data, field.ByteOffset, depth, visited)
+			children = append(children, child)
+		}
+		return children
+
+	case *dwarf.PtrType:
+		return []Reading{newMemberReading(mem, "*", t, addr, data, 0, depth, visited)}
+
+	default:
+		return nil
+	}
+}
+
+func newMemberReading(mem MemoryReader, name string, typ dwarf.Type, addr uintptr,
+	data []byte, off int64, depth int, visited map[uintptr]bool) Reading {
+
+	r := Reading{
+		Name:     name,
+		Type:     typ.String(),
+		Size:     typ.Size(),
+		Location: fmt.Sprintf("%#x", addr),
+		Address:  addr,
+	}
+
+	if typ.Size() < 0 || off+typ.Size() > int64(len(data)) {
+		r.Error = "member is out of the read data"
+		return r
+	}
+
+	raw := data[off : off+typ.Size()]
+	r.Raw = raw
+
+	ptr, isPtr := unqualifiedType(typ).(*dwarf.PtrType)
+	if !isPtr {
+		r.Value = "0x" + hex.EncodeToString(raw)
+		formatReading(mem, &r, typ.String(), addr, raw, typ.Size(), false)
+		return r
+	}
+
+	pointee := ReadAddressWithOrder(raw, byteOrderOf(mem))
+	r.Value = fmt.Sprintf("%#x", pointee)
+
+	if pointee == 0 {
+		return r
+	}
+
+	if visited[pointee] {
+		r.Value += " : <cycle>"
+		return r
+	}
+
+	size := ptr.Type.Size()
+	if size <= 0 {
+		return r
+	}
+	if size > maxPointeeSize {
+		size = maxPointeeSize
+	}
+
+	pointeeData := make([]byte, size)
+	err := mem.PeekData(pointee, pointeeData)
+	if err != nil {
+		r.Error = fmt.Sprintf("couldn't read data at location:%#x", pointee)
+		return r
+	}
+
+	visited[pointee] = true
+	r.Value += " : 0x" + hex.EncodeToString(pointeeData)
+	r.Children = expandPointee(mem, ptr.Type, pointee, pointeeData, depth-1, visited)
+	formatReading(mem, &r, ptr.Type.String(), pointee, pointeeData, size, true)
+	return r
+}
+
+// expandPointeeArray returns the readings of the elements of the pointed array
+func expandPointeeArray(mem MemoryReader, elem dwarf.Type, addr uintptr, data []byte, depth int, visited map[uintptr]bool) []Reading {
+	size := elem.Size()
+	count := int64(len(data)) / size
+	children := make([]Reading, 0, count)
+
+	for i := int64(0); i < count; i++ {
+		off := i * size
+		child := newMemberReading(mem, fmt.Sprintf("[%d]", i), elem, addr+uintptr(off), data, off, depth, visited)
+		if child.Children == nil {
+			child.Children = expandPointee(mem, elem, child.Address, child.Raw, depth-1, visited)
+		}
+		children = append(children, child)
+	}
+
+	return children
+}
+
+// unqualifiedType strips the typedefs and qualifiers of the type
+func unqualifiedType(typ dwarf.Type) dwarf.Type {
+	for {
+		switch t := typ.(type) {
+		case *dwarf.TypedefType:
+			typ = t.Type
+		case *dwarf.QualType:
+			typ = t.Type
+		default:
+			return typ
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package raztracer
+
+// RegisterChange contains the old and new value of a register changed since the previous event
+type RegisterChange struct {
+	Old string `json:"old,omitempty"` // empty if this is the first event of the thread
+	New string `json:"new"`
+}
+
+// SetRegisterDiffs enables reporting only the registers that changed since the previous
+// event of the same thread in TraceEvent.RegisterDiff instead of every register
+func (t *Tracer) SetRegisterDiffs(enabled bool) {
+	t.registerDiffs = enabled
+	t.lastRegs = make(map[Process]map[string]string)
+}
+
+// diffRegisters returns the registers of the thread that changed since its previous event
+func (t *Tracer) diffRegisters(tid Process, regs map[string]string) map[string]RegisterChange {
+	prev := t.lastRegs[tid]
+	t.lastRegs[tid] = regs
+
+	diff := make(map[string]RegisterChange)
+	for reg, val := range regs {
+		old, found := prev[reg]
+		if found && old == val {
+			continue
+		}
+		diff[reg] = RegisterChange{Old: old, New: val}
+	}
+
+	return diff
+}
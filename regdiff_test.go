@@ -0,0 +1,102 @@
+package raztracer
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffRegisters(t *testing.T) {
+	tracer := &Tracer{}
+	tracer.SetRegisterDiffs(true)
+
+	tests := []struct {
+		tid      Process
+		regs     map[string]string
+		expected map[string]RegisterChange
+	}{
+		{1, map[string]string{"a": "0x1", "b": "0x2"}, map[string]RegisterChange{"a": {New: "0x1"}, "b": {New: "0x2"}}},
+		{1, map[string]string{"a": "0x1", "b": "0x3"}, map[string]RegisterChange{"b": {Old: "0x2", New: "0x3"}}},
+		{1, map[string]string{"a": "0x1", "b": "0x3"}, map[string]RegisterChange{}},
+		{2, map[string]string{"a": "0x1"}, map[string]RegisterChange{"a": {New: "0x1"}}}, // threads are diffed separately
+		{1, map[string]string{"a": "0x4", "c": "0x5"}, map[string]RegisterChange{"a": {Old: "0x1", New: "0x4"}, "c": {New: "0x5"}}},
+	}
+
+	for i, test := range tests {
+		diff := tracer.diffRegisters(test.tid, test.regs)
+		if len(diff) != len(test.expected) {
+			t.Errorf("#%d: got %v, expected %v", i, diff, test.expected)
+			continue
+		}
+		for reg, change := range test.expected {
+			if diff[reg] != change {
+				t.Errorf("#%d: got %v, expected %v", i, diff, test.expected)
+				break
+			}
+		}
+	}
+
+	// enabling the diffs again forgets the previous registers
+	tracer.SetRegisterDiffs(true)
+	if diff := tracer.diffRegisters(1, map[string]string{"a": "0x4"}); diff["a"] != (RegisterChange{New: "0x4"}) {
+		t.Errorf("the previous registers were kept: %v", diff)
+	}
+}
+
+func TestRegisterDiffEvents(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	cmd := startTracee(t, 100)
+	defer cmd.Process.Kill()
+
+	tracer, err := NewTracer(cmd.Process.Pid)
+	skipIfNotPermitted(t, err)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tracer.Detach()
+
+	tracer.SetRegisterDiffs(true)
+	if _, err := tracer.SetBreakpointAtFunction("traced"); err != nil {
+		t.Fatal(err)
+	}
+
+	var diffs []map[string]RegisterChange
+	tracer.Run()
+	for deadline := time.Now().Add(time.Second); len(diffs) < 2 && time.Now().Before(deadline); {
+		evt, err := tracer.WaitForEvent(100 * time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if evt == nil || !evt.IsBreakpoint {
+			continue
+		}
+
+		if evt.Registers != nil {
+			t.Error("every register is reported along with the diff")
+		}
+		diffs = append(diffs, evt.RegisterDiff)
+	}
+	if len(diffs) < 2 {
+		t.Fatalf("traced() was hit %d times", len(diffs))
+	}
+
+	// the first event reports every register
+	for reg, change := range diffs[0] {
+		if len(change.Old) > 0 || len(change.New) == 0 {
+			t.Errorf("unexpected change of %s in the first event: %v", reg, change)
+		}
+	}
+
+	// the second one only the changed ones, but not the PC of the breakpoint
+	if len(diffs[1]) == 0 || len(diffs[1]) >= len(diffs[0]) {
+		t.Errorf("%d registers changed between the events of %d registers", len(diffs[1]), len(diffs[0]))
+	}
+	for reg, change := range diffs[1] {
+		if strings.HasSuffix(reg, "(PC)") || change.Old == change.New || len(change.Old) == 0 {
+			t.Errorf("unexpected change of %s in the second event: %v", reg, change)
+		}
+	}
+}
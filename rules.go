@@ -0,0 +1,235 @@
+package raztracer
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Rule action types
+const (
+	ActionWriteCore = "core"     // writes a core file to Path
+	ActionSnapshot  = "snapshot" // writes the event in JSON format to Path
+	ActionCount     = "count"    // increments Counter
+	ActionDetach    = "detach"   // detaches from the process
+)
+
+// RuleAction is an action performed when a rule triggers.
+// Paths can contain %p (PID), %n (event sequence number) and %t (unix time) placeholders.
+type RuleAction struct {
+	Type    string `yaml:"type" json:"type"`
+	Path    string `yaml:"path,omitempty" json:"path,omitempty"`
+	Counter string `yaml:"counter,omitempty" json:"counter,omitempty"`
+}
+
+// Rule maps an event predicate to a list of actions.
+// Every specified condition has to match for the rule to trigger.
+type Rule struct {
+	Name       string       `yaml:"name" json:"name"`
+	Signal     string       `yaml:"signal,omitempty" json:"signal,omitempty"`         // signal name, e.g. SIGSEGV
+	Breakpoint string       `yaml:"breakpoint,omitempty" json:"breakpoint,omitempty"` // function name of the breakpoint
+	After      int          `yaml:"after,omitempty" json:"after,omitempty"`           // triggers from the Nth matching event
+	Actions    []RuleAction `yaml:"actions" json:"actions"`
+
+	// When is an optional custom predicate for programmatically configured rules
+	When func(*Tracer, *TraceEvent) bool `yaml:"-" json:"-"`
+
+	signal syscall.Signal
+	hits   int
+}
+
+// RuleEngine performs the actions of the rules matching the trace events
+type RuleEngine struct {
+	mutex    sync.Mutex
+	rules    []*Rule
+	counters map[string]uint64
+}
+
+// NewRuleEngine returns a new RuleEngine
+func NewRuleEngine(rules ...*Rule) (*RuleEngine, error) {
+	e := &RuleEngine{counters: make(map[string]uint64)}
+
+	for _, rule := range rules {
+		err := e.AddRule(rule)
+		if err != nil {
+			return nil, Error(err)
+		}
+	}
+
+	return e, nil
+}
+
+// LoadRules returns a RuleEngine with the rules read from YAML:
+//
+//	rules:
+//	- name: crash
+//	  signal: SIGSEGV
+//	  actions:
+//	  - type: core
+//	    path: /tmp/core.%p
+func LoadRules(r io.Reader) (*RuleEngine, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	var config struct {
+		Rules []*Rule `yaml:"rules"`
+	}
+
+	err = yaml.UnmarshalStrict(data, &config)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	e, err := NewRuleEngine(config.Rules...)
+	return e, Error(err)
+}
+
+// AddRule validates and adds a rule to the engine
+func (e *RuleEngine) AddRule(rule *Rule) error {
+	if len(rule.Signal) > 0 {
+		sig, err := ParseSignal(rule.Signal)
+		if err != nil {
+			return Error(err)
+		}
+		rule.signal = sig
+	}
+
+	for _, action := range rule.Actions {
+		switch action.Type {
+		case ActionWriteCore, ActionSnapshot:
+			if len(action.Path) == 0 {
+				return Errorf("rule %s: missing path for %s action", rule.Name, action.Type)
+			}
+
+		case ActionCount:
+			if len(action.Counter) == 0 {
+				return Errorf("rule %s: missing counter name", rule.Name)
+			}
+
+		case ActionDetach:
+
+		default:
+			return Errorf("rule %s: unknown action: %s", rule.Name, action.Type)
+		}
+	}
+
+	e.mutex.Lock()
+	e.rules = append(e.rules, rule)
+	e.mutex.Unlock()
+	return nil
+}
+
+// Counters returns a copy of the counters incremented by count actions
+func (e *RuleEngine) Counters() map[string]uint64 {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	counters := make(map[string]uint64, len(e.counters))
+	for name, value := range e.counters {
+		counters[name] = value
+	}
+	return counters
+}
+
+// Handle performs the actions of the rules matching the event.
+// It has to be called in the tracer's thread (e.g. from a TraceManager event function).
+func (e *RuleEngine) Handle(t *Tracer, evt *TraceEvent) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	var errors []error
+	detach := false
+
+	for _, rule := range e.rules {
+		if !rule.matches(t, evt) {
+			continue
+		}
+
+		for _, action := range rule.Actions {
+			if action.Type == ActionDetach {
+				detach = true
+				continue
+			}
+
+			err := e.perform(t, evt, &action)
+			if err != nil {
+				errors = append(errors, Errorf("rule %s: %v", rule.Name, err))
+			}
+		}
+	}
+
+	if detach && !t.IsDetached() {
+		err := t.Detach()
+		if err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	return MergeErrors(errors)
+}
+
+// HandleEvent implements EventHandler, the event is passed on unchanged
+func (e *RuleEngine) HandleEvent(t *Tracer, evt *TraceEvent) (*TraceEvent, error) {
+	err := e.Handle(t, evt)
+	return evt, err
+}
+
+func (rule *Rule) matches(t *Tracer, evt *TraceEvent) bool {
+	if rule.signal != 0 && evt.Signal != rule.signal {
+		return false
+	}
+
+	if len(rule.Breakpoint) > 0 {
+		if !evt.IsBreakpoint || !matchFunctionName(t.breakpointFunction(evt.PC), rule.Breakpoint) {
+			return false
+		}
+	}
+
+	if rule.When != nil && !rule.When(t, evt) {
+		return false
+	}
+
+	rule.hits++
+	return rule.hits >= rule.After
+}
+
+func (e *RuleEngine) perform(t *Tracer, evt *TraceEvent, action *RuleAction) error {
+	switch action.Type {
+	case ActionWriteCore:
+		return Error(t.WriteCore(expandRulePath(action.Path, evt)))
+
+	case ActionSnapshot:
+		file, err := os.Create(expandRulePath(action.Path, evt))
+		if err != nil {
+			return Error(err)
+		}
+		defer file.Close()
+
+		enc := json.NewEncoder(file)
+		enc.SetIndent("", "  ")
+		return Error(enc.Encode(evt))
+
+	case ActionCount:
+		e.counters[action.Counter]++
+	}
+
+	return nil
+}
+
+func expandRulePath(path string, evt *TraceEvent) string {
+	return strings.NewReplacer(
+		"%p", strconv.Itoa(int(evt.PID)),
+		"%n", strconv.FormatUint(evt.Seq, 10),
+		"%t", strconv.FormatInt(time.Now().Unix(), 10),
+	).Replace(path)
+}
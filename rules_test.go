@@ -0,0 +1,218 @@
+package raztracer
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestLoadRules(t *testing.T) {
+	e, err := LoadRules(strings.NewReader(`
+rules:
+- name: crash
+  signal: segv
+  actions:
+  - type: core
+    path: /tmp/core.%p
+- name: hot
+  breakpoint: traced
+  after: 3
+  actions:
+  - type: count
+    counter: hot
+  - type: detach
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(e.rules) != 2 {
+		t.Fatalf("got %d rules, expected 2", len(e.rules))
+	}
+	if e.rules[0].signal != syscall.SIGSEGV {
+		t.Errorf("the signal of the crash rule is %v", e.rules[0].signal)
+	}
+	if rule := e.rules[1]; rule.Breakpoint != "traced" || rule.After != 3 || len(rule.Actions) != 2 {
+		t.Errorf("unexpected hot rule: %+v", rule)
+	}
+
+	invalid := map[string]string{
+		"unknown field":  "rules:\n- name: x\n  sginal: SIGSEGV\n",
+		"unknown signal": "rules:\n- name: x\n  signal: SIGFOO\n",
+		"unknown action": "rules:\n- name: x\n  actions:\n  - type: reboot\n",
+		"missing path":   "rules:\n- name: x\n  actions:\n  - type: snapshot\n",
+		"missing count":  "rules:\n- name: x\n  actions:\n  - type: count\n",
+	}
+	for name, yaml := range invalid {
+		if _, err := LoadRules(strings.NewReader(yaml)); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+// newRuleTracer returns a tracer without a process that resolves the PCs of breakpointEvent
+func newRuleTracer() *Tracer {
+	return &Tracer{debugData: &DebugData{functionCache: make(map[uintptr]*FunctionEntry)}}
+}
+
+// breakpointEvent returns a breakpoint event in 'function' without a backtrace,
+// as if the backtrace depth was 0
+func breakpointEvent(tracer *Tracer, function string) *TraceEvent {
+	cache := tracer.debugData.functionCache
+	pc := uintptr(0x1000 + 0x100*len(cache))
+	for addr, fn := range cache {
+		if fn.Name == function {
+			pc = addr
+		}
+	}
+	cache[pc] = &FunctionEntry{Name: function}
+
+	return &TraceEvent{
+		IsBreakpoint: true,
+		Signal:       syscall.SIGTRAP,
+		PC:           pc,
+	}
+}
+
+func TestRuleMatching(t *testing.T) {
+	e, err := NewRuleEngine(
+		&Rule{Name: "usr1", Signal: "SIGUSR1", Actions: []RuleAction{{Type: ActionCount, Counter: "usr1"}}},
+		&Rule{Name: "third", Breakpoint: "traced", After: 3, Actions: []RuleAction{{Type: ActionCount, Counter: "third"}}},
+		&Rule{Name: "scoped", Breakpoint: "run", Actions: []RuleAction{{Type: ActionCount, Counter: "scoped"}}},
+		&Rule{
+			Name:    "custom",
+			When:    func(t *Tracer, evt *TraceEvent) bool { return evt.Seq%2 == 0 },
+			Actions: []RuleAction{{Type: ActionCount, Counter: "even"}},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tracer := newRuleTracer()
+	events := []*TraceEvent{
+		breakpointEvent(tracer, "traced"),
+		breakpointEvent(tracer, "traced"),
+		{Signal: syscall.SIGUSR1, Reason: StopReason{Kind: StopSignalDelivery, Signal: syscall.SIGUSR1}},
+		breakpointEvent(tracer, "traced"),
+		breakpointEvent(tracer, "ns::Server::run"),
+		breakpointEvent(tracer, "other"),
+		breakpointEvent(tracer, "traced"),
+	}
+
+	for i, evt := range events {
+		evt.Seq = uint64(i + 1)
+		if err := e.Handle(tracer, evt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := map[string]uint64{"usr1": 1, "third": 2, "scoped": 1, "even": 3}
+	counters := e.Counters()
+	for name, value := range expected {
+		if counters[name] != value {
+			t.Errorf("counter %s is %d, expected %d", name, counters[name], value)
+		}
+	}
+}
+
+func TestRuleSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "raztracer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	e, err := NewRuleEngine(&Rule{
+		Name:    "snapshot",
+		Actions: []RuleAction{{Type: ActionSnapshot, Path: filepath.Join(dir, "event.%p.%n.json")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tracer := newRuleTracer()
+	evt := breakpointEvent(tracer, "traced")
+	evt.PID = 42
+	evt.Seq = 7
+	if err := e.Handle(tracer, evt); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "event.42.7.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var snapshot TraceEvent
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.PID != 42 || snapshot.Seq != 7 || !snapshot.IsBreakpoint {
+		t.Errorf("unexpected snapshot: %s", data)
+	}
+
+	// the failing action is reported with the name of the rule
+	e.rules[0].Actions[0].Path = filepath.Join(dir, "missing", "event.json")
+	if err := e.Handle(tracer, evt); err == nil || !strings.Contains(err.Error(), "rule snapshot") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestTraceReportsRuleErrors(t *testing.T) {
+	cmd := startTracee(t, 200)
+	defer cmd.Process.Kill()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rules, err := NewRuleEngine(&Rule{
+		Name:       "broken",
+		Breakpoint: "traced",
+		Actions:    []RuleAction{{Type: ActionSnapshot, Path: "/nonexistent/raztracer/event.json"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errs := make(chan error, 16)
+	cfg := TraceConfig{
+		Functions: []string{"traced"},
+		Rules:     rules,
+		OnError: func(pid int, err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+			cancel()
+		},
+	}
+
+	events, err := Trace(ctx, cmd.Process.Pid, cfg)
+	skipIfNotPermitted(t, err)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range events {
+	}
+
+	select {
+	case err := <-errs:
+		if !strings.Contains(err.Error(), "rule broken") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	default:
+		t.Error("the error of the rule action wasn't reported")
+	}
+
+	if status := waitTracee(t, cmd); status != 0 {
+		t.Errorf("the test program exited with %d after detaching", status)
+	}
+}
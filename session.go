@@ -0,0 +1,115 @@
+package raztracer
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+// SessionInfo describes the traced process at the time of attaching
+type SessionInfo struct {
+	PID        Process         `json:"pid"`
+	ProgName   string          `json:"progname"`
+	Executable string          `json:"exe"`
+	Cmdline    []string        `json:"cmdline"`
+	Environ    []string        `json:"environ"`
+	Cwd        string          `json:"cwd"`
+	BuildID    string          `json:"build_id,omitempty"`
+	MountNS    string          `json:"mount_ns,omitempty"`
+	Root       string          `json:"root,omitempty"`
+	Libraries  []SharedLibrary `json:"libs"`
+	AttachTime time.Time       `json:"attach_time"`
+}
+
+// NewSessionInfo collects the session information of the process
+func NewSessionInfo(pid Process, progName string, debugData *DebugData) *SessionInfo {
+	info := &SessionInfo{
+		PID:        pid,
+		ProgName:   progName,
+		AttachTime: time.Now(),
+	}
+
+	info.Executable, _ = pid.Executable()
+	info.Cmdline, _ = pid.Cmdline()
+	info.Environ, _ = pid.Environ()
+	info.Cwd, _ = pid.Cwd()
+	info.MountNS, _ = pid.MountNamespace()
+	info.Root = pid.RootPath()
+	info.Libraries, _ = pid.SharedLibs()
+
+	if debugData != nil {
+		info.BuildID, _ = debugData.GetBuildID()
+	}
+
+	return info
+}
+
+// Executable returns the path of the executable of the process
+func (pid Process) Executable() (string, error) {
+	exe, err := os.Readlink(fmt.Sprintf("/proc/%d/exe", pid))
+	return exe, Error(err)
+}
+
+// Cmdline returns the command line arguments of the process
+func (pid Process) Cmdline() ([]string, error) {
+	data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	return splitNullTerminated(data), nil
+}
+
+// Environ returns the environment variables of the process
+func (pid Process) Environ() ([]string, error) {
+	data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/environ", pid))
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	return splitNullTerminated(data), nil
+}
+
+// Cwd returns the current working directory of the process
+func (pid Process) Cwd() (string, error) {
+	cwd, err := os.Readlink(fmt.Sprintf("/proc/%d/cwd", pid))
+	return cwd, Error(err)
+}
+
+// String returns the session information as a string
+func (info *SessionInfo) String() string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "pid: %d (%s)\n", info.PID, info.ProgName)
+	fmt.Fprintf(&buf, "exe: %s\n", info.Executable)
+	fmt.Fprintf(&buf, "build-id: %s\n", info.BuildID)
+	fmt.Fprintf(&buf, "cmdline: %s\n", strings.Join(info.Cmdline, " "))
+	fmt.Fprintf(&buf, "cwd: %s\n", info.Cwd)
+	if len(info.Root) > 0 {
+		fmt.Fprintf(&buf, "root: %s (%s)\n", info.Root, info.MountNS)
+	}
+	fmt.Fprintf(&buf, "attached: %s\n", info.AttachTime.Format(time.RFC3339))
+	return buf.String()
+}
+
+// HostPath maps a path in the mount namespace of the process to a path accessible by the tracer
+func (info *SessionInfo) HostPath(p string) string {
+	return info.PID.ResolvePath(p)
+}
+
+func splitNullTerminated(data []byte) []string {
+	data = bytes.TrimRight(data, "\x00")
+	if len(data) == 0 {
+		return nil
+	}
+
+	parts := bytes.Split(data, []byte{0})
+	results := make([]string, len(parts))
+	for i, part := range parts {
+		results[i] = string(part)
+	}
+
+	return results
+}
@@ -1,13 +1,17 @@
 package raztracer
 
 import (
-	"strings"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
 )
 
 // SharedLibrary represents a shared library
 type SharedLibrary struct {
-	Name       string
-	StaticBase uintptr
+	Name       string  `json:"name"`           // path in the mount namespace of the process
+	Path       string  `json:"path,omitempty"` // path accessible by the tracer
+	StaticBase uintptr `json:"static_base"`
 }
 
 // SharedLibs returns the shared libraries loaded by the process and their static bases
@@ -19,20 +23,68 @@ func (pid Process) SharedLibs() ([]SharedLibrary, error) {
 
 	var lastLib string
 	var libs []SharedLibrary
+	root := pid.RootPath()
 
 	for _, region := range regions {
 		if region.Pathname == lastLib {
 			continue
 		}
 
-		if !strings.HasSuffix(region.Pathname, ".so") {
+		if !isSharedLibPath(region.Pathname) {
 			continue
 		}
 
 		lastLib = region.Pathname
-		lib := SharedLibrary{Name: region.Pathname, StaticBase: region.Address[0]}
+		lib := SharedLibrary{
+			Name:       region.Pathname,
+			Path:       root + region.Pathname,
+			StaticBase: region.Address[0],
+		}
 		libs = append(libs, lib)
 	}
 
 	return libs, nil
 }
+
+// sharedLibPattern matches the file names of shared libraries, e.g. libc.so.6
+var sharedLibPattern = regexp.MustCompile(`\.so(\.[0-9]+)*$`)
+
+// isSharedLibPath returns true if the path is a shared library (.so or .so.N)
+func isSharedLibPath(path string) bool {
+	return sharedLibPattern.MatchString(filepath.Base(path))
+}
+
+// RootPath returns the path prefix under which the tracer can access the files of the process.
+// It is empty unless the process is in a different mount namespace (e.g. a container).
+func (pid Process) RootPath() string {
+	ns, err := pid.MountNamespace()
+	if err != nil {
+		return ""
+	}
+
+	selfNs, err := os.Readlink("/proc/self/ns/mnt")
+	if err != nil || ns == selfNs {
+		return ""
+	}
+
+	return fmt.Sprintf("/proc/%d/root", pid)
+}
+
+// ResolvePath returns the path under which the tracer can access a file of the process
+func (pid Process) ResolvePath(p string) string {
+	return pid.RootPath() + p
+}
+
+// MountNamespace returns the mount namespace identifier of the process
+func (pid Process) MountNamespace() (string, error) {
+	ns, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/mnt", pid))
+	return ns, Error(err)
+}
+
+func (lib *SharedLibrary) path() string {
+	if len(lib.Path) > 0 {
+		return lib.Path
+	}
+
+	return lib.Name
+}
@@ -0,0 +1,65 @@
+package raztracer
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/razzie/raztracer/internal/dwarf/op"
+)
+
+// isSignalFrame checks whether 'pc' is in a signal trampoline by the 'S' augmentation
+// of its CIE or by the code of the sigreturn call
+func (it *StackIterator) isSignalFrame(pc uintptr) bool {
+	fde, _ := it.data.getFDEFromPC(pc)
+	if fde != nil && fde.CIE != nil && strings.Contains(fde.CIE.Augmentation, "S") {
+		return true
+	}
+
+	code := make([]byte, len(sigreturnCode))
+	err := it.proc.PeekData(pc, code)
+	return err == nil && bytes.Equal(code, sigreturnCode)
+}
+
+// newSignalFrameEntry returns a dummy function entry for a signal trampoline
+func (it *StackIterator) newSignalFrameEntry(pc uintptr) *FunctionEntry {
+	if fn, _ := it.data.GetFunctionFromPC(pc); fn != nil {
+		return fn
+	}
+
+	return &FunctionEntry{
+		Name:              "<signal handler called>",
+		LowPC:             pc,
+		HighPC:            pc + uintptr(len(sigreturnCode)),
+		BreakpointAddress: pc,
+	}
+}
+
+// advanceSignalFrame restores the registers of the interrupted frame
+// from the signal context saved on the stack
+func (it *StackIterator) advanceSignalFrame() bool {
+	sp := uintptr(it.regs.SP())
+	if it.frame > 0 {
+		// the return address of the signal handler was popped by the time the trampoline runs
+		sp = uintptr(it.regs.CFA)
+	}
+
+	gregs := make([]byte, len(sigcontextRegs)*int(SizeofPtr))
+	addr := sp + sigcontextOffset
+	err := it.proc.PeekData(addr, gregs)
+	if err != nil {
+		it.fail(UnwindUnreadableMemory, addr, err)
+		it.err = Error(err)
+		return false
+	}
+
+	for i, reg := range sigcontextRegs {
+		val := ReadAddress(gregs[i*int(SizeofPtr):])
+		it.regs.AddReg(reg, op.DwarfRegisterFromUint64(uint64(val)))
+	}
+
+	it.regs.CFA = int64(sp)
+	it.retaddr = ReadAddress(gregs[(len(sigcontextRegs)-1)*int(SizeofPtr):])
+	it.regs.AddReg(it.regs.PCRegNum, op.DwarfRegisterFromUint64(uint64(it.retaddr)))
+
+	return true
+}
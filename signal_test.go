@@ -0,0 +1,113 @@
+package raztracer
+
+import (
+	"context"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// exit status of the test program: SIGUSR1 count + 16 * SIGCONT count
+func TestDetachForwardsPendingSignal(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	cmd := startTracee(t, 100)
+	defer cmd.Process.Kill()
+
+	tracer, err := NewTracer(cmd.Process.Pid)
+	skipIfNotPermitted(t, err)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tracer.Run()
+	syscall.Kill(cmd.Process.Pid, syscall.SIGUSR1)
+
+	evt, err := tracer.WaitForEvent(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evt == nil || evt.Signal != syscall.SIGUSR1 {
+		t.Fatalf("expected a SIGUSR1 event, got %v", evt)
+	}
+
+	// the signal is still pending when detaching
+	report, err := tracer.DetachWithReport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Clean() {
+		t.Errorf("detach left risks behind:\n%s", report)
+	}
+
+	if status := waitTracee(t, cmd); status != 1 {
+		t.Errorf("the test program handled %d SIGUSR1 and %d SIGCONT, expected 1 SIGUSR1", status%16, status/16)
+	}
+}
+
+func TestDetachRightAfterAttach(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	cmd := startTracee(t, 50)
+	defer cmd.Process.Kill()
+
+	tracer, err := NewTracer(cmd.Process.Pid)
+	skipIfNotPermitted(t, err)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the threads are still in the stop of the attach
+	report, err := tracer.DetachWithReport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Clean() {
+		t.Errorf("detach left risks behind:\n%s", report)
+	}
+
+	// a second detach must not stop the untraced process
+	report, err = tracer.DetachWithReport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.ThreadsResumed) > 0 || !report.Clean() {
+		t.Errorf("the second detach was not a no-op:\n%s", report)
+	}
+
+	if status := waitTracee(t, cmd); status != 0 {
+		t.Errorf("the test program handled %d SIGUSR1 and %d SIGCONT, expected none", status%16, status/16)
+	}
+}
+
+func TestContinueDoesNotInjectSignals(t *testing.T) {
+	cmd := startTracee(t, 100)
+	defer cmd.Process.Kill()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	events, err := Trace(ctx, cmd.Process.Pid, TraceConfig{Functions: []string{"traced"}})
+	skipIfNotPermitted(t, err)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hits := 0
+	for evt := range events {
+		if evt.IsBreakpoint {
+			if hits++; hits == 10 {
+				cancel()
+			}
+		} else {
+			t.Errorf("unexpected signal event: %v", evt.Signal)
+		}
+	}
+
+	if status := waitTracee(t, cmd); status != 0 {
+		t.Errorf("the test program handled %d SIGUSR1 and %d SIGCONT, expected none", status%16, status/16)
+	}
+}
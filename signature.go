@@ -0,0 +1,151 @@
+package raztracer
+
+import (
+	"debug/dwarf"
+	"fmt"
+	"strings"
+)
+
+// Parameter is a formal parameter of a function
+type Parameter struct {
+	Name string `json:"name,omitempty"`
+	Type string `json:"type"`
+}
+
+// Signature contains the return type and parameters of a function
+type Signature struct {
+	Name       string      `json:"name"`
+	Return     string      `json:"return"`
+	Params     []Parameter `json:"params"`
+	Variadic   bool        `json:"variadic,omitempty"`
+	returnType dwarf.Type
+}
+
+// Signature returns the return type and parameters of the function from its debug info
+func (fn *FunctionEntry) Signature() (*Signature, error) {
+	if fn.signature != nil {
+		return fn.signature, nil
+	}
+
+	if fn.entry.data == nil {
+		return nil, Errorf("no debug info for %s", fn.Name)
+	}
+
+	sig := &Signature{
+		Name:   fn.Name,
+		Return: "void",
+		Params: make([]Parameter, 0),
+	}
+
+	if typ := fn.entry.data.entryType(&fn.entry); typ != nil {
+		sig.Return = typ.String()
+		sig.returnType = typ
+	}
+
+	children, err := fn.entry.Children(1)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	for _, child := range children {
+		switch child.entry.Tag {
+		case dwarf.TagFormalParameter:
+			param := Parameter{Type: "?"}
+			param.Name, _ = child.Val(dwarf.AttrName).(string)
+			if typ := fn.entry.data.entryType(&child); typ != nil {
+				param.Type = typ.String()
+			}
+			sig.Params = append(sig.Params, param)
+
+		case dwarf.TagUnspecifiedParameters:
+			sig.Variadic = true
+		}
+	}
+
+	fn.signature = sig
+	return sig, nil
+}
+
+// String returns the signature as a C declaration
+func (sig *Signature) String() string {
+	params := make([]string, 0, len(sig.Params)+1)
+	for _, param := range sig.Params {
+		if len(param.Name) > 0 {
+			params = append(params, param.Type+" "+param.Name)
+		} else {
+			params = append(params, param.Type)
+		}
+	}
+
+	if sig.Variadic {
+		params = append(params, "...")
+	} else if len(params) == 0 {
+		params = append(params, "void")
+	}
+
+	return fmt.Sprintf("%s %s(%s)", sig.Return, sig.Name, strings.Join(params, ", "))
+}
+
+// decodeReturnValue formats the value of the return register according to the return type.
+// Returns false if the return type isn't passed in the return register.
+func (sig *Signature) decodeReturnValue(value uint64) (string, bool) {
+	if sig.returnType == nil {
+		return "", false
+	}
+
+	switch t := unqualifiedType(sig.returnType).(type) {
+	case *dwarf.PtrType:
+		return fmt.Sprintf("%#x", value), true
+
+	case *dwarf.BoolType:
+		return fmt.Sprint(value&0xff != 0), true
+
+	case *dwarf.IntType, *dwarf.CharType:
+		return fmt.Sprint(signExtend(value, t.Size())), true
+
+	case *dwarf.UintType, *dwarf.UcharType:
+		return fmt.Sprint(truncateValue(value, t.Size())), true
+
+	case *dwarf.EnumType:
+		v := signExtend(value, t.Size())
+		for _, enumerator := range t.Val {
+			if enumerator.Val == v {
+				return fmt.Sprintf("%s (%d)", enumerator.Name, v), true
+			}
+		}
+		return fmt.Sprint(v), true
+
+	default:
+		return "", false
+	}
+}
+
+// entryType returns the type of the debug entry or nil if it has none
+func (d *DebugData) entryType(de *DebugEntry) dwarf.Type {
+	off, ok := de.Val(dwarf.AttrType).(dwarf.Offset)
+	if !ok {
+		return nil
+	}
+
+	typ, err := d.dwarfData.Type(off)
+	if err != nil {
+		return nil
+	}
+
+	return typ
+}
+
+func truncateValue(value uint64, size int64) uint64 {
+	if size <= 0 || size >= 8 {
+		return value
+	}
+	return value & (1<<(uint(size)*8) - 1)
+}
+
+func signExtend(value uint64, size int64) int64 {
+	if size <= 0 || size >= 8 {
+		return int64(value)
+	}
+	shift := 64 - uint(size)*8
+	return int64(value<<shift) >> shift
+}
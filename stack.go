@@ -0,0 +1,138 @@
+package raztracer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultStackMargin is the default distance from the stack limit that triggers a warning
+const DefaultStackMargin = 64 * 1024
+
+// StackUsage contains the stack pointer of a thread relative to its stack mapping
+type StackUsage struct {
+	SP        uintptr `json:"sp"`
+	Base      uintptr `json:"base"`  // highest address of the stack
+	Limit     uintptr `json:"limit"` // lowest address the stack can grow to
+	Depth     uint64  `json:"depth"`
+	Remaining uint64  `json:"remaining"`
+}
+
+// stackBounds is the cached stack mapping of a thread
+type stackBounds struct {
+	region MemRegion
+	limit  uintptr
+}
+
+// SetStackMargin sets how close (in bytes) a thread's stack pointer can get to the
+// stack limit before a warning is added to the event (0 disables the warning)
+func (t *Tracer) SetStackMargin(margin uint64) {
+	t.stackMargin = margin
+}
+
+// GetStackUsage returns the stack usage of the stopped thread
+func (t *Tracer) GetStackUsage() (*StackUsage, error) {
+	sp, err := t.getSP()
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	bounds, found := t.stacks[t.tid]
+	if !found || sp < bounds.region.Address[0] || sp >= bounds.region.Address[1] {
+		bounds, err = t.findStackBounds(sp)
+		if err != nil {
+			return nil, Error(err)
+		}
+
+		t.stacks[t.tid] = bounds
+	}
+
+	usage := &StackUsage{
+		SP:    sp,
+		Base:  bounds.region.Address[1],
+		Limit: bounds.limit,
+		Depth: uint64(bounds.region.Address[1] - sp),
+	}
+
+	if sp > bounds.limit {
+		usage.Remaining = uint64(sp - bounds.limit)
+	}
+
+	return usage, nil
+}
+
+// checkStack updates the stack statistics of the stopped thread
+// and adds a warning to the event if the stack is about to overflow
+func (t *Tracer) checkStack(evt *TraceEvent) {
+	usage, err := t.GetStackUsage()
+	if err != nil {
+		return
+	}
+
+	evt.Stack = usage
+
+	if usage.Depth > t.stats.MaxStackDepth[evt.TID] {
+		t.stats.MaxStackDepth[evt.TID] = usage.Depth
+	}
+
+	if t.stackMargin > 0 && usage.Remaining < t.stackMargin {
+		evt.Warnings = append(evt.Warnings, fmt.Sprintf(
+			"stack of thread %d is %d bytes from its limit (depth: %d bytes)",
+			evt.TID, usage.Remaining, usage.Depth))
+	}
+}
+
+func (t *Tracer) findStackBounds(sp uintptr) (stackBounds, error) {
+	regions, err := t.pid.MemRegions()
+	if err != nil {
+		return stackBounds{}, Error(err)
+	}
+
+	for _, region := range regions {
+		if sp < region.Address[0] || sp >= region.Address[1] {
+			continue
+		}
+
+		bounds := stackBounds{region: region, limit: region.Address[0]}
+
+		// the main thread's stack grows on demand up to the stack size limit
+		if region.Pathname == "[stack]" {
+			if size, err := t.pid.StackSizeLimit(); err == nil && size > 0 && size < uint64(region.Address[1]) {
+				bounds.limit = region.Address[1] - uintptr(size)
+			}
+		}
+
+		return bounds, nil
+	}
+
+	return stackBounds{}, Errorf("stack pointer is not mapped: %#x", sp)
+}
+
+// StackSizeLimit returns the soft stack size limit of the process (0 means unlimited)
+func (pid Process) StackSizeLimit() (uint64, error) {
+	file, err := os.Open(fmt.Sprintf("/proc/%d/limits", pid))
+	if err != nil {
+		return 0, Error(err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "Max stack size") {
+			continue
+		}
+
+		fields := strings.Fields(strings.TrimPrefix(line, "Max stack size"))
+		if len(fields) == 0 || fields[0] == "unlimited" {
+			return 0, nil
+		}
+
+		size, err := strconv.ParseUint(fields[0], 10, 64)
+		return size, Error(err)
+	}
+
+	return 0, Errorf("stack size limit not found")
+}
@@ -11,9 +11,14 @@ type StackIterator struct {
 	pc      uintptr
 	retaddr uintptr
 	regs    *op.DwarfRegisters
+	current *op.DwarfRegisters // registers of the current frame
 	fn      *FunctionEntry
 	data    *DebugData
 	err     error
+	frame   int
+	noFDE   bool
+	diag    *UnwindDiagnostic
+	opts    ReadingOptions
 }
 
 // NewStackIterator returns a new StackIterator
@@ -30,7 +35,8 @@ func NewStackIterator(pid Process, data *DebugData) (*StackIterator, error) {
 		proc:    pid,
 		retaddr: pc,
 		regs:    regs,
-		data:    data}
+		data:    data,
+		opts:    DefaultReadingOptions}
 
 	if pc == 0 { // PC could be 0 in case of a segfault
 		if !stack.advanceRegs() {
@@ -49,16 +55,71 @@ func (it *StackIterator) Next() bool {
 		return false
 	}
 
+	if it.isSignalFrame(it.pc) {
+		it.fn = it.newSignalFrameEntry(it.pc)
+		it.regs.StaticBase = uint64(it.fn.StaticBase)
+		current := copyRegisters(it.regs)
+		if !it.advanceSignalFrame() {
+			return false
+		}
+
+		current.CFA = it.regs.CFA
+		it.current = current
+		it.frame++
+		return true
+	}
+
 	it.fn, _ = it.data.GetFunctionFromPC(it.pc)
 	if it.fn == nil {
+		// stripped libraries have no symbols for their local functions, but their CFI is still usable
+		it.fn = it.newNoSymbolEntry(it.pc)
+	}
+	if it.fn == nil {
+		it.diagnoseMissingFunction()
 		return false
 	}
 
 	fb, _ := it.fn.GetFrameBase(it.pc, it.regs)
 	it.regs.FrameBase = int64(fb)
 	it.regs.StaticBase = uint64(it.fn.StaticBase)
+	current := copyRegisters(it.regs)
 
-	return it.advanceRegs()
+	if !it.advanceRegs() {
+		return false
+	}
+
+	// the frame base may depend on the CFA that is only known after unwinding the frame
+	current.CFA = it.regs.CFA
+	if fb, err := it.fn.GetFrameBase(it.pc, current); err == nil {
+		current.FrameBase = int64(fb)
+	}
+
+	it.current = current
+	it.frame++
+	return true
+}
+
+// Registers returns the register values of the current frame as they were
+// before the frame was unwound, together with its CFA and frame base
+func (it *StackIterator) Registers() *op.DwarfRegisters {
+	return it.current
+}
+
+// Function returns the function of the current frame
+func (it *StackIterator) Function() *FunctionEntry {
+	return it.fn
+}
+
+// PC returns the program counter of the current frame
+func (it *StackIterator) PC() uintptr {
+	return it.pc
+}
+
+func copyRegisters(regs *op.DwarfRegisters) *op.DwarfRegisters {
+	cp := *regs
+	cp.Regs = make([]*op.DwarfRegister, len(regs.Regs))
+	copy(cp.Regs, regs.Regs)
+	return &cp
 }
 
 // Frame returns the current stack frame
@@ -67,21 +128,114 @@ func (it *StackIterator) Frame() (*BacktraceFrame, error) {
 		return nil, Error(it.err)
 	}
 
-	frame, err := NewBacktraceFrame(int(it.proc), it.fn, it.pc, it.regs)
+	frame, err := NewBacktraceFrameWithOptions(int(it.proc), it.fn, it.pc, it.regs, it.opts)
 	return frame, Error(err)
 }
 
+// SetReadingOptions sets the options used to read the variables of the frames
+func (it *StackIterator) SetReadingOptions(opts ReadingOptions) {
+	it.opts = opts
+}
+
 // Err returns the error message from the last iteration
 func (it *StackIterator) Err() error {
 	return it.err
 }
 
+// Diagnostic returns the reason why the iteration stopped early
+// or nil if the stack was fully unwound
+func (it *StackIterator) Diagnostic() *UnwindDiagnostic {
+	return it.diag
+}
+
+func (it *StackIterator) fail(reason UnwindFailure, addr uintptr, detail error) {
+	it.diag = &UnwindDiagnostic{
+		Reason:  reason,
+		Frame:   it.frame,
+		PC:      it.pc,
+		CFA:     uintptr(it.regs.CFA),
+		Address: addr,
+		NoFDE:   it.noFDE,
+	}
+
+	if detail != nil {
+		if tracedErr, ok := detail.(*TracedError); ok {
+			detail = tracedErr.Err
+		}
+		it.diag.Detail = detail.Error()
+	}
+}
+
+// newNoSymbolEntry returns a dummy function entry for an address that is covered
+// by CFI but not by any symbol, or nil if there is no CFI for the address
+func (it *StackIterator) newNoSymbolEntry(pc uintptr) *FunctionEntry {
+	fde, _ := it.data.getFDEFromPC(pc)
+	if fde == nil {
+		return nil
+	}
+
+	fn := &FunctionEntry{
+		Name:              "<no symbol>",
+		LowPC:             uintptr(fde.Begin()),
+		HighPC:            uintptr(fde.End()),
+		StaticBase:        it.data.staticBase,
+		BreakpointAddress: uintptr(fde.Begin()),
+	}
+
+	for i, lib := range it.data.libs {
+		if lib.StaticBase <= pc && lib.StaticBase > fn.StaticBase {
+			fn.Lib = &it.data.libs[i]
+			fn.StaticBase = lib.StaticBase
+		}
+	}
+
+	// frame entries contain the static base, function entries don't
+	fn.LowPC -= fn.StaticBase
+	fn.HighPC -= fn.StaticBase
+	fn.BreakpointAddress -= fn.StaticBase
+	return fn
+}
+
+func (it *StackIterator) diagnoseMissingFunction() {
+	regions, _ := it.proc.MemRegions()
+	if isExecutableAddress(regions, it.pc) {
+		it.fail(UnwindNoFunction, it.pc, nil)
+	} else {
+		it.fail(UnwindBadReturnAddress, it.pc, nil)
+	}
+
+	it.diag.PC = 0
+}
+
+func (it *StackIterator) diagnoseRuleError(rule frame.DWRule, cfa int64, err error) {
+	switch rule.Rule {
+	case frame.RuleOffset:
+		it.fail(UnwindUnreadableMemory, uintptr(cfa+rule.Offset), err)
+
+	case frame.RuleFramePointer:
+		var addr uintptr
+		if reg := it.regs.Reg(rule.Reg); reg != nil {
+			addr = uintptr(reg.Uint64Val)
+		}
+		it.fail(UnwindUnreadableMemory, addr, err)
+
+	case frame.RuleExpression, frame.RuleValExpression:
+		it.fail(UnwindExpressionFailure, 0, err)
+
+	default:
+		it.fail(UnwindUnsupportedRule, 0, err)
+	}
+}
+
 func (it *StackIterator) advanceRegs() bool {
 	framectx, _ := it.data.GetFrameContextFromPC(it.pc)
+	it.noFDE = framectx == nil
 	framectx = FixFrameContext(framectx, it.pc, it.regs)
 
-	cfareg, _ := it.executeFrameRegRule(framectx.CFA, 0)
+	cfareg, err := it.executeFrameRegRule(framectx.CFA, 0)
 	if cfareg == nil {
+		it.regs.CFA = 0
+		it.fail(UnwindUndefinedCFA, 0, err)
 		it.err = Errorf("CFA becomes undefined at PC %#x", it.pc)
 		return false
 	}
@@ -94,8 +248,19 @@ func (it *StackIterator) advanceRegs() bool {
 		reg, err := it.executeFrameRegRule(regRule, it.regs.CFA)
 		it.regs.AddReg(i, reg)
 		if i == framectx.RetAddrReg {
+			if err != nil {
+				it.diagnoseRuleError(regRule, it.regs.CFA, err)
+			}
+
 			if reg == nil {
+				if err == nil && !it.noFDE {
+					// CFI marks the outermost frame (e.g. _start) with an undefined return address
+					it.retaddr = 0
+					return true
+				}
+
 				if err == nil {
+					it.fail(UnwindUndefinedRetAddr, 0, nil)
 					it.err = Errorf("undefined return address at %#x", it.pc)
 					return false
 				}
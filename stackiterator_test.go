@@ -0,0 +1,50 @@
+package raztracer
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestBacktraceThroughStrippedLibc(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	cmd := startTracee(t, 100)
+	defer cmd.Process.Kill()
+
+	tracer, err := NewTracer(cmd.Process.Pid)
+	skipIfNotPermitted(t, err)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tracer.Detach()
+
+	tracer.SetBacktraceDepth(32)
+	tracer.SetUnwindDiagnostics(true)
+	if _, err := tracer.SetBreakpointAtFunction("traced"); err != nil {
+		t.Fatal(err)
+	}
+
+	tracer.Run()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); {
+		evt, err := tracer.WaitForEvent(100 * time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if evt == nil || !evt.IsBreakpoint {
+			continue
+		}
+
+		// main() is called by libc functions without local symbols
+		if evt.BacktraceEnd != BacktraceComplete {
+			t.Errorf("the backtrace ended with %s: %v", evt.BacktraceEnd, evt.Unwind)
+		}
+		if len(evt.Backtrace) < 3 || evt.Backtrace[1].fn.Name != "main" {
+			t.Errorf("the backtrace doesn't continue after main(): %v", evt.Backtrace)
+		}
+		return
+	}
+
+	t.Fatal("traced() was not hit")
+}
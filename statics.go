@@ -0,0 +1,85 @@
+package raztracer
+
+import (
+	"debug/dwarf"
+	"path"
+	"strings"
+)
+
+// staticSeparator separates the defining file from the name of static functions and variables
+const staticSeparator = "::"
+
+// FileName returns the base name of the source file of the compilation unit
+func (cu *CUEntry) FileName() string {
+	return path.Base(cu.entry.Name())
+}
+
+// isStaticEntry returns whether the function or variable has internal linkage
+func isStaticEntry(de DebugEntry) bool {
+	if external, _ := de.Val(dwarf.AttrExternal).(bool); external {
+		return false
+	}
+
+	// definitions of declarations (e.g. C++ static members) inherit the linkage
+	_, isDefinition := de.Val(dwarf.AttrSpecification).(dwarf.Offset)
+	return !isDefinition
+}
+
+// splitStaticName splits a name qualified by its defining file (e.g. file.c::counter).
+// Returns false if the name isn't qualified by a file name.
+func splitStaticName(name string) (file, base string, ok bool) {
+	i := strings.Index(name, staticSeparator)
+	if i <= 0 || !strings.Contains(name[:i], ".") {
+		return "", name, false
+	}
+
+	return name[:i], name[i+len(staticSeparator):], true
+}
+
+// GetGlobal returns the global variable with the given name.
+// File-scope static variables are named by their defining file (e.g. file.c::counter),
+// but can be found by their plain name too if it isn't ambiguous.
+func (d *DebugData) GetGlobal(name string) (*VariableEntry, error) {
+	var matches []*VariableEntry
+
+	for _, v := range d.globals {
+		if v.Name == name {
+			return v, nil
+		}
+
+		if _, base, ok := splitStaticName(v.Name); ok && base == name {
+			matches = append(matches, v)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, Errorf("global variable not found: %s", name)
+
+	case 1:
+		return matches[0], nil
+
+	default:
+		names := make([]string, len(matches))
+		for i, v := range matches {
+			names[i] = v.Name
+		}
+		return nil, Errorf("ambiguous global variable name %s: %s", name, strings.Join(names, ", "))
+	}
+}
+
+// ReadGlobal returns the reading of the global variable with the given name
+func (t *Tracer) ReadGlobal(name string) (*Reading, error) {
+	v, err := t.debugData.GetGlobal(name)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	regs, err := GetDwarfRegs(t.callThread())
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	r, err := NewReadingWithOptions(v, int(t.pid), 0, regs, t.readingOpts)
+	return r, Error(err)
+}
@@ -0,0 +1,37 @@
+package raztracer
+
+// TracerStats contains the statistics of a Tracer
+type TracerStats struct {
+	Events             uint64             `json:"events"`
+	DroppedEvents      uint64             `json:"dropped_events"`
+	DroppedBreakpoints map[uintptr]uint64 `json:"dropped_breakpoints"`
+	FilteredHits       uint64             `json:"filtered_hits"` // breakpoint hits of filtered out threads
+	MaxStackDepth      map[Process]uint64 `json:"max_stack_depth"`
+	SymbolCache        SymbolCacheStats   `json:"symbol_cache"`
+	DebugDataMemory    MemoryUsage        `json:"debug_data_memory"`
+	Overhead           OverheadStats      `json:"overhead"`
+}
+
+func newTracerStats() TracerStats {
+	return TracerStats{
+		DroppedBreakpoints: make(map[uintptr]uint64),
+		MaxStackDepth:      make(map[Process]uint64),
+	}
+}
+
+// GetStats returns a copy of the tracer's statistics
+func (t *Tracer) GetStats() TracerStats {
+	stats := t.stats
+	stats.SymbolCache = t.debugData.SymbolCacheStats()
+	stats.DebugDataMemory = t.debugData.MemoryUsage()
+	stats.Overhead = t.profile.overhead()
+	stats.DroppedBreakpoints = make(map[uintptr]uint64, len(t.stats.DroppedBreakpoints))
+	for addr, count := range t.stats.DroppedBreakpoints {
+		stats.DroppedBreakpoints[addr] = count
+	}
+	stats.MaxStackDepth = make(map[Process]uint64, len(t.stats.MaxStackDepth))
+	for tid, depth := range t.stats.MaxStackDepth {
+		stats.MaxStackDepth[tid] = depth
+	}
+	return stats
+}
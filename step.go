@@ -0,0 +1,163 @@
+package raztracer
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// DefaultStepBudget is the default maximum number of instructions executed by a single step
+const DefaultStepBudget = 10000
+
+// StepMode is the granularity of a step
+type StepMode string
+
+// Step modes
+const (
+	StepModeLine        StepMode = "line"
+	StepModeColumn      StepMode = "column" // stops at statements of the same line too
+	StepModeInstruction StepMode = "instruction"
+)
+
+// StepInfo describes how a step was executed
+type StepInfo struct {
+	Requested       StepMode `json:"requested"`
+	Mode            StepMode `json:"mode"` // instruction if the step was downgraded
+	Instructions    int      `json:"instructions"`
+	BudgetExhausted bool     `json:"budget_exhausted"`
+	From            string   `json:"from,omitempty"`
+	To              string   `json:"to,omitempty"`
+	Reason          string   `json:"reason,omitempty"` // reason of the downgrade
+}
+
+// SetStepBudget sets the maximum number of instructions executed by a single step
+// (DefaultStepBudget is used if 'budget' is not positive)
+func (t *Tracer) SetStepBudget(budget int) {
+	if budget <= 0 {
+		budget = DefaultStepBudget
+	}
+
+	t.stepBudget = budget
+}
+
+// StepLine executes the stopped thread until it reaches the beginning of a statement
+// (is_stmt row of the line table) on a different source line.
+// If the thread is in code without line info, the step falls back to instruction stepping
+// until code with line info is reached or the step budget is exhausted.
+// The downgrade is reported in the returned event.
+func (t *Tracer) StepLine() (*TraceEvent, error) {
+	evt, err := t.stepSource(StepModeLine)
+	return evt, Error(err)
+}
+
+// StepColumn works like StepLine, but it also stops at the statements of the same line
+// starting at a different column, which is useful for dense one-liners
+func (t *Tracer) StepColumn() (*TraceEvent, error) {
+	evt, err := t.stepSource(StepModeColumn)
+	return evt, Error(err)
+}
+
+func (t *Tracer) stepSource(mode StepMode) (*TraceEvent, error) {
+	if t.tid == 0 {
+		return nil, Errorf("no stopped thread")
+	}
+
+	pc, err := t.GetPC()
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	info := &StepInfo{
+		Requested: mode,
+		Mode:      mode,
+	}
+
+	start, _ := t.lineAt(pc)
+	if start == nil {
+		info.downgrade(fmt.Sprintf("no line info at %#x", pc))
+	} else {
+		info.From = start.position(mode)
+	}
+
+	for {
+		if info.Instructions >= t.stepBudget {
+			info.BudgetExhausted = true
+			break
+		}
+
+		err := t.stepInstruction(pc)
+		if err != nil {
+			return nil, Error(err)
+		}
+		info.Instructions++
+
+		pc, err = t.GetPC()
+		if err != nil {
+			return nil, Error(err)
+		}
+
+		line, isStmt := t.lineAt(pc)
+		if line == nil {
+			if info.Mode != StepModeInstruction {
+				info.downgrade(fmt.Sprintf("stepped into code without line info at %#x", pc))
+			}
+			continue
+		}
+
+		// returning into the middle of a line or reaching a non-statement row doesn't end the step
+		if !isStmt {
+			continue
+		}
+
+		if start == nil || !line.sameStatement(start, mode) {
+			info.To = line.position(mode)
+			break
+		}
+	}
+
+	evt, err := t.newStepEvent(pc, info)
+	return evt, Error(err)
+}
+
+// lineAt returns the line entry of 'pc' or nil if there is no line info for it.
+// 'isStmt' is true if 'pc' is the beginning of a statement.
+func (t *Tracer) lineAt(pc uintptr) (line *LineEntry, isStmt bool) {
+	fn, _ := t.debugData.GetFunctionFromPC(pc)
+	if fn == nil || fn.entry.data == nil {
+		return nil, false
+	}
+
+	line, _ = NewLineEntry(pc-fn.StaticBase, fn.entry.data)
+	if line == nil {
+		return nil, false
+	}
+
+	return line, line.IsStmt && line.Address == pc-fn.StaticBase
+}
+
+func (t *Tracer) newStepEvent(pc uintptr, info *StepInfo) (*TraceEvent, error) {
+	evt := &TraceEvent{
+		Signal: syscall.SIGTRAP,
+		PID:    t.pid,
+		TID:    t.tid,
+		PC:     pc,
+		Step:   info,
+	}
+
+	if info.Mode != info.Requested {
+		evt.Warnings = append(evt.Warnings, "step downgraded to instruction stepping: "+info.Reason)
+	}
+
+	if info.BudgetExhausted {
+		evt.Warnings = append(evt.Warnings, fmt.Sprintf("step stopped after %d instructions", info.Instructions))
+	}
+
+	t.stats.Events++
+	evt.Seq = t.stats.Events
+
+	return evt, Error(t.readEventData(evt))
+}
+
+func (info *StepInfo) downgrade(reason string) {
+	info.Mode = StepModeInstruction
+	info.Reason = reason
+}
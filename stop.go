@@ -0,0 +1,61 @@
+package raztracer
+
+import (
+	"encoding/binary"
+	"os"
+	"sync/atomic"
+	"syscall"
+)
+
+// RequestStop asks the traced process to stop and report a stop event.
+// It is safe to call from any goroutine, even while WaitForEvent is blocked in another one.
+// The stop is reported by WaitForEvent as an event with IsStopRequest set.
+//
+// The SIGSTOP is sent to the main thread by tgkill, so it can be told apart from a SIGSTOP
+// sent by someone else, which is reported and delivered as usual.
+func (t *Tracer) RequestStop() error {
+	// the flag must be set before the signal can arrive
+	atomic.StoreInt32(&t.stopRequested, 1)
+
+	err := syscall.Tgkill(int(t.pid), int(t.pid), syscall.SIGSTOP)
+	if err != nil {
+		atomic.StoreInt32(&t.stopRequested, 0)
+		return Error(err)
+	}
+
+	return nil
+}
+
+// isRequestedStop returns true if the SIGSTOP of the event was sent by RequestStop
+func (t *Tracer) isRequestedStop(evt *TraceEvent) bool {
+	if !evt.Reason.IsSignal(syscall.SIGSTOP) || evt.TID != t.pid || atomic.LoadInt32(&t.stopRequested) == 0 {
+		return false
+	}
+
+	info, err := evt.TID.getSigInfo()
+	if err != nil {
+		return false
+	}
+
+	code := int32(binary.LittleEndian.Uint32(info[sigInfoCodeOffset:]))
+	sender := int32(binary.LittleEndian.Uint32(info[sigInfoPIDOffset:]))
+	if code != siTKill || int(sender) != os.Getpid() {
+		return false
+	}
+
+	return atomic.CompareAndSwapInt32(&t.stopRequested, 1, 0)
+}
+
+// RequestStop asks the traced process to stop, the stop event is passed to the event function.
+// Unlike HandleRequest, it doesn't wait for the tracer's thread.
+func (proc *TraceManager) RequestStop() error {
+	tracer := proc.getTracer()
+	if tracer == nil {
+		return Errorf("the inner tracer is already detached")
+	}
+
+	if err := tracer.RequestStop(); err != nil {
+		return Error(err)
+	}
+	return nil
+}
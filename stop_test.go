@@ -0,0 +1,64 @@
+package raztracer
+
+import (
+	"runtime"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRequestStop(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	cmd := startTracee(t, 100)
+	defer cmd.Process.Kill()
+
+	tracer, err := NewTracer(cmd.Process.Pid)
+	skipIfNotPermitted(t, err)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tracer.Run()
+	if err := tracer.RequestStop(); err != nil {
+		tracer.Detach()
+		t.Fatal(err)
+	}
+
+	evt, err := tracer.WaitForEvent(time.Second)
+	if err != nil {
+		tracer.Detach()
+		t.Fatal(err)
+	}
+	if evt == nil || !evt.IsStopRequest {
+		tracer.Detach()
+		t.Fatalf("expected a stop request event, got %v", evt)
+	}
+
+	// a SIGSTOP from someone else is not mistaken for the requested stop
+	atomic.StoreInt32(&tracer.stopRequested, 1)
+	syscall.Kill(cmd.Process.Pid, syscall.SIGSTOP)
+
+	evt, err = tracer.WaitForEvent(time.Second)
+	if err != nil {
+		tracer.Detach()
+		t.Fatal(err)
+	}
+	if evt == nil || evt.IsStopRequest || evt.Signal != syscall.SIGSTOP {
+		tracer.Detach()
+		t.Fatalf("expected an external SIGSTOP event, got %v", evt)
+	}
+	if atomic.LoadInt32(&tracer.stopRequested) == 0 {
+		t.Error("the external SIGSTOP consumed the stop request")
+	}
+
+	if err := tracer.Detach(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the tracee may be left in the group-stop of the external SIGSTOP
+	cmd.Process.Kill()
+	cmd.Wait()
+}
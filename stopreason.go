@@ -0,0 +1,164 @@
+package raztracer
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// StopKind is the kind of state change reported by wait
+type StopKind string
+
+// Stop kinds
+const (
+	StopSignalDelivery StopKind = "signal"       // a signal is about to be delivered to the thread
+	StopGroup          StopKind = "group_stop"   // the thread stopped by a stop signal
+	StopPtraceEvent    StopKind = "ptrace_event" // clone, fork, exec, etc.
+	StopExited         StopKind = "exited"       // the thread exited normally
+	StopKilled         StopKind = "killed"       // the thread was terminated by a signal
+	StopContinued      StopKind = "continued"    // the thread was resumed by SIGCONT
+)
+
+// PtraceEvent is the kind of a ptrace event stop
+type PtraceEvent string
+
+// Ptrace events
+const (
+	EventClone     PtraceEvent = "clone"
+	EventFork      PtraceEvent = "fork"
+	EventVfork     PtraceEvent = "vfork"
+	EventVforkDone PtraceEvent = "vfork_done"
+	EventExec      PtraceEvent = "exec"
+	EventExit      PtraceEvent = "exit"
+	EventSeccomp   PtraceEvent = "seccomp"
+	EventStop      PtraceEvent = "stop"
+)
+
+const ptraceEventStop = 128
+const ptraceEventSeccomp = 7
+
+var ptraceEvents = map[int]PtraceEvent{
+	syscall.PTRACE_EVENT_CLONE:      EventClone,
+	syscall.PTRACE_EVENT_FORK:       EventFork,
+	syscall.PTRACE_EVENT_VFORK:      EventVfork,
+	syscall.PTRACE_EVENT_VFORK_DONE: EventVforkDone,
+	syscall.PTRACE_EVENT_EXEC:       EventExec,
+	syscall.PTRACE_EVENT_EXIT:       EventExit,
+	ptraceEventSeccomp:              EventSeccomp,
+	ptraceEventStop:                 EventStop,
+}
+
+// StopReason is the decoded wait status of a thread
+type StopReason struct {
+	Kind     StopKind       `json:"kind"`
+	Signal   syscall.Signal `json:"signal,omitempty"`    // stop or termination signal
+	Event    PtraceEvent    `json:"event,omitempty"`     // set for ptrace event stops
+	ExitCode int            `json:"exit_code,omitempty"` // set for exited threads
+	CoreDump bool           `json:"core_dump,omitempty"` // the killed thread dumped core
+}
+
+// DecodeWaitStatus decodes the wait status and its ptrace event bits.
+// Group stops are only recognized when they come with PTRACE_EVENT_STOP (PTRACE_SEIZE),
+// otherwise they are reported as signal deliveries; use Process.DecodeStop to distinguish them.
+func DecodeWaitStatus(status syscall.WaitStatus) StopReason {
+	switch {
+	case status.Exited():
+		return StopReason{Kind: StopExited, ExitCode: status.ExitStatus()}
+
+	case status.Signaled():
+		return StopReason{Kind: StopKilled, Signal: status.Signal(), CoreDump: status.CoreDump()}
+
+	case status.Continued():
+		return StopReason{Kind: StopContinued}
+
+	case status.Stopped():
+		reason := StopReason{Kind: StopSignalDelivery, Signal: status.StopSignal()}
+
+		// TrapCause only reports the event of SIGTRAP stops,
+		// but PTRACE_EVENT_STOP comes with the stop signal of group stops
+		if cause := int(status>>16) & 0xff; cause > 0 {
+			event, found := ptraceEvents[cause]
+			if !found {
+				event = PtraceEvent(fmt.Sprintf("event%d", cause))
+			}
+
+			reason.Event = event
+			reason.Kind = StopPtraceEvent
+			if event == EventStop && isStopSignal(reason.Signal) {
+				reason.Kind = StopGroup
+			}
+		}
+
+		return reason
+	}
+
+	return StopReason{Kind: StopKind(fmt.Sprintf("unknown (%#x)", uint32(status)))}
+}
+
+// DecodeStop decodes the wait status of the stopped thread. Unlike DecodeWaitStatus,
+// it tells group stops from stop signal deliveries by querying the pending signal.
+func (pid Process) DecodeStop(status syscall.WaitStatus) StopReason {
+	reason := DecodeWaitStatus(status)
+	if reason.Kind == StopSignalDelivery && isStopSignal(reason.Signal) && !pid.hasSigInfo() {
+		reason.Kind = StopGroup
+	}
+
+	return reason
+}
+
+// IsSignal returns true if the reason is the delivery of 'sig'
+func (reason StopReason) IsSignal(sig syscall.Signal) bool {
+	return reason.Kind == StopSignalDelivery && reason.Signal == sig
+}
+
+// IsTrap returns true if the thread stopped by a breakpoint or single step
+func (reason StopReason) IsTrap() bool {
+	return reason.IsSignal(syscall.SIGTRAP)
+}
+
+// IsEvent returns true if the thread stopped by the ptrace event
+func (reason StopReason) IsEvent(event PtraceEvent) bool {
+	return reason.Kind == StopPtraceEvent && reason.Event == event
+}
+
+// Terminated returns true if the thread exited or was killed
+func (reason StopReason) Terminated() bool {
+	return reason.Kind == StopExited || reason.Kind == StopKilled
+}
+
+// String returns the stop reason in a human readable format
+func (reason StopReason) String() string {
+	switch reason.Kind {
+	case StopSignalDelivery, StopGroup:
+		return fmt.Sprintf("%s (%s)", reason.Kind, SignalName(reason.Signal))
+	case StopPtraceEvent:
+		return fmt.Sprintf("%s (%s)", reason.Kind, reason.Event)
+	case StopExited:
+		return fmt.Sprintf("%s (code %d)", reason.Kind, reason.ExitCode)
+	case StopKilled:
+		if reason.CoreDump {
+			return fmt.Sprintf("%s (%s, core dumped)", reason.Kind, SignalName(reason.Signal))
+		}
+		return fmt.Sprintf("%s (%s)", reason.Kind, SignalName(reason.Signal))
+	default:
+		return string(reason.Kind)
+	}
+}
+
+func isStopSignal(sig syscall.Signal) bool {
+	switch sig {
+	case syscall.SIGSTOP, syscall.SIGTSTP, syscall.SIGTTIN, syscall.SIGTTOU:
+		return true
+	default:
+		return false
+	}
+}
+
+// hasSigInfo returns false if the thread is in group stop, where PTRACE_GETSIGINFO fails with EINVAL
+func (pid Process) hasSigInfo() bool {
+	var siginfo [128]byte
+	countPtrace(1)
+	_, _, errno := syscall.Syscall6(syscall.SYS_PTRACE, syscall.PTRACE_GETSIGINFO,
+		uintptr(pid), 0, uintptr(unsafe.Pointer(&siginfo[0])), 0, 0)
+	return errno != syscall.EINVAL
+}
@@ -0,0 +1,60 @@
+package raztracer
+
+import (
+	"syscall"
+	"testing"
+)
+
+// stoppedStatus builds a wait status the way the kernel reports a stop
+func stoppedStatus(sig syscall.Signal, event int) syscall.WaitStatus {
+	return syscall.WaitStatus(event<<16 | int(sig)<<8 | 0x7f)
+}
+
+func TestDecodeWaitStatus(t *testing.T) {
+	tests := []struct {
+		status syscall.WaitStatus
+		reason StopReason
+	}{
+		{syscall.WaitStatus(3 << 8), StopReason{Kind: StopExited, ExitCode: 3}},
+		{syscall.WaitStatus(syscall.SIGKILL), StopReason{Kind: StopKilled, Signal: syscall.SIGKILL}},
+		{syscall.WaitStatus(syscall.SIGSEGV | 0x80), StopReason{Kind: StopKilled, Signal: syscall.SIGSEGV, CoreDump: true}},
+		{syscall.WaitStatus(0xffff), StopReason{Kind: StopContinued}},
+		{stoppedStatus(syscall.SIGUSR1, 0), StopReason{Kind: StopSignalDelivery, Signal: syscall.SIGUSR1}},
+		{stoppedStatus(syscall.SIGTRAP, 0), StopReason{Kind: StopSignalDelivery, Signal: syscall.SIGTRAP}},
+		{stoppedStatus(syscall.SIGTRAP, syscall.PTRACE_EVENT_CLONE), StopReason{Kind: StopPtraceEvent, Signal: syscall.SIGTRAP, Event: EventClone}},
+		{stoppedStatus(syscall.SIGTRAP, ptraceEventStop), StopReason{Kind: StopPtraceEvent, Signal: syscall.SIGTRAP, Event: EventStop}},
+		{stoppedStatus(syscall.SIGSTOP, ptraceEventStop), StopReason{Kind: StopGroup, Signal: syscall.SIGSTOP, Event: EventStop}},
+		{stoppedStatus(syscall.SIGTTIN, ptraceEventStop), StopReason{Kind: StopGroup, Signal: syscall.SIGTTIN, Event: EventStop}},
+		{stoppedStatus(syscall.SIGTRAP, 42), StopReason{Kind: StopPtraceEvent, Signal: syscall.SIGTRAP, Event: "event42"}},
+	}
+
+	for _, test := range tests {
+		if reason := DecodeWaitStatus(test.status); reason != test.reason {
+			t.Errorf("%#x: expected %+v, got %+v", uint32(test.status), test.reason, reason)
+		}
+	}
+}
+
+func TestStopReasonString(t *testing.T) {
+	tests := []struct {
+		reason StopReason
+		str    string
+	}{
+		{StopReason{Kind: StopExited, ExitCode: 3}, "exited (code 3)"},
+		{StopReason{Kind: StopPtraceEvent, Event: EventExec}, "ptrace_event (exec)"},
+		{StopReason{Kind: StopContinued}, "continued"},
+	}
+
+	for _, test := range tests {
+		if str := test.reason.String(); str != test.str {
+			t.Errorf("expected %q, got %q", test.str, str)
+		}
+	}
+
+	if !(StopReason{Kind: StopKilled}).Terminated() || (StopReason{Kind: StopGroup}).Terminated() {
+		t.Error("only exited and killed threads are terminated")
+	}
+	if !(StopReason{Kind: StopSignalDelivery, Signal: syscall.SIGTRAP}).IsTrap() {
+		t.Error("SIGTRAP delivery is not a trap")
+	}
+}
@@ -0,0 +1,76 @@
+package raztracer
+
+import (
+	"fmt"
+	"path"
+)
+
+// symbolCache contains the source locations of PCs symbolized in earlier events
+type symbolCache struct {
+	sources map[uintptr]string
+	hits    uint64
+	misses  uint64
+}
+
+// SymbolCacheStats contains the hit and miss counts of the symbol cache
+type SymbolCacheStats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+}
+
+// lineSource returns the "file:line" source location of the PC or an empty string
+// if there is no line info for it. The PC is relative to the static base of the module.
+// The result is cached until the cache is invalidated.
+func (d *DebugData) lineSource(pc uintptr) string {
+	if d.symbols.sources == nil {
+		d.symbols.sources = make(map[uintptr]string)
+	}
+
+	if source, found := d.symbols.sources[pc]; found {
+		d.symbols.hits++
+		return source
+	}
+
+	d.symbols.misses++
+
+	var source string
+	lineEntry, _ := NewLineEntry(pc, d)
+	if lineEntry != nil {
+		source = fmt.Sprintf("%s:%d", path.Base(lineEntry.Filename), lineEntry.Line)
+	}
+
+	d.symbols.sources[pc] = source
+	d.budget.addCache(symbolCacheEntrySize + int64(len(source)))
+	return source
+}
+
+// InvalidateSymbolCache drops the cached function and source lookups of PCs.
+// It is called when libraries are loaded, since the same addresses may belong to other code.
+func (d *DebugData) InvalidateSymbolCache() {
+	d.functionCache = make(map[uintptr]*FunctionEntry)
+	d.symbols.sources = nil
+
+	for _, lib := range d.libData {
+		lib.functionCache = make(map[uintptr]*FunctionEntry)
+		lib.symbols.sources = nil
+	}
+
+	if d.budget.owner == d {
+		d.budget.caches = 0
+	}
+}
+
+// SymbolCacheStats returns the hit and miss counts of the symbol cache including the libraries
+func (d *DebugData) SymbolCacheStats() SymbolCacheStats {
+	stats := SymbolCacheStats{
+		Hits:   d.symbols.hits,
+		Misses: d.symbols.misses,
+	}
+
+	for _, lib := range d.libData {
+		stats.Hits += lib.symbols.hits
+		stats.Misses += lib.symbols.misses
+	}
+
+	return stats
+}
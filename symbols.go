@@ -0,0 +1,240 @@
+package raztracer
+
+import (
+	"debug/dwarf"
+	"debug/elf"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/razzie/raztracer/internal/dwarf/op"
+)
+
+// SymbolKind is a bitmask of symbol kinds
+type SymbolKind int
+
+// Symbol kinds
+const (
+	SymbolFunction SymbolKind = 1 << iota // function with debug info
+	SymbolVariable                        // global variable with debug info
+	SymbolELF                             // symbol table entry without debug info
+	SymbolAll      = SymbolFunction | SymbolVariable | SymbolELF
+)
+
+// SearchMode specifies how the pattern of a symbol query is matched
+type SearchMode int
+
+// Search modes
+const (
+	SearchPrefix SearchMode = iota
+	SearchSubstring
+	SearchRegexp
+)
+
+// SymbolQuery contains the parameters of a symbol search
+type SymbolQuery struct {
+	Pattern string
+	Mode    SearchMode
+	Limit   int // maximum number of results (0 means unlimited)
+}
+
+// Symbol is a result of a symbol search
+type Symbol struct {
+	Name    string     `json:"name"`
+	Kind    SymbolKind `json:"kind"`
+	Module  string     `json:"module"`
+	Address uintptr    `json:"address"`
+	Size    uint64     `json:"size,omitempty"`
+	Type    string     `json:"type,omitempty"`
+}
+
+// String returns the name of the symbol kind
+func (kind SymbolKind) String() string {
+	switch kind {
+	case SymbolFunction:
+		return "function"
+	case SymbolVariable:
+		return "variable"
+	case SymbolELF:
+		return "elf"
+	default:
+		return "mixed"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (kind SymbolKind) MarshalText() ([]byte, error) {
+	return []byte(kind.String()), nil
+}
+
+// SearchSymbols returns the functions, global variables and ELF symbols
+// of the binary and its loaded libraries that match the query, sorted by name
+func (d *DebugData) SearchSymbols(query SymbolQuery, kinds SymbolKind) ([]Symbol, error) {
+	match, err := query.matcher()
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	var results []Symbol
+	seen := make(map[string]bool) // debug info symbols of the binary
+
+	if kinds&(SymbolFunction|SymbolELF) != 0 {
+		for _, fn := range d.functions {
+			if !match(fn.Name) {
+				continue
+			}
+
+			if fn.entry.data == nil {
+				if kinds&SymbolELF != 0 {
+					results = append(results, Symbol{
+						Name:    fn.Name,
+						Kind:    SymbolELF,
+						Module:  fn.moduleName(),
+						Address: fn.LowPC + fn.StaticBase,
+						Size:    uint64(fn.HighPC - fn.LowPC),
+					})
+				}
+				continue
+			}
+
+			if fn.entry.data == d {
+				seen[fn.Name] = true
+			}
+
+			if kinds&SymbolFunction != 0 {
+				results = append(results, Symbol{
+					Name:    fn.Name,
+					Kind:    SymbolFunction,
+					Module:  fn.moduleName(),
+					Address: fn.LowPC + fn.StaticBase,
+					Size:    uint64(fn.HighPC - fn.LowPC),
+				})
+			}
+		}
+	}
+
+	for _, v := range d.globals {
+		if !match(v.Name) {
+			continue
+		}
+
+		seen[v.Name] = true
+
+		if kinds&SymbolVariable != 0 {
+			addr, _ := v.staticAddress()
+			results = append(results, Symbol{
+				Name:    v.Name,
+				Kind:    SymbolVariable,
+				Module:  d.moduleName(),
+				Address: addr,
+				Size:    uint64(v.Size),
+				Type:    v.Type,
+			})
+		}
+	}
+
+	if kinds&SymbolELF != 0 {
+		symbols, _ := d.elfData.Symbols()
+		for _, symbol := range symbols {
+			if seen[symbol.Name] || !isSearchableSymbol(symbol) || !match(symbol.Name) {
+				continue
+			}
+
+			results = append(results, Symbol{
+				Name:    symbol.Name,
+				Kind:    SymbolELF,
+				Module:  d.moduleName(),
+				Address: uintptr(symbol.Value) + d.staticBase,
+				Size:    symbol.Size,
+				Type:    strings.ToLower(strings.TrimPrefix(elf.ST_TYPE(symbol.Info).String(), "STT_")),
+			})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+
+	if query.Limit > 0 && len(results) > query.Limit {
+		results = results[:query.Limit]
+	}
+
+	return results, nil
+}
+
+func (query *SymbolQuery) matcher() (func(string) bool, error) {
+	switch query.Mode {
+	case SearchPrefix:
+		return func(name string) bool {
+			return strings.HasPrefix(name, query.Pattern)
+		}, nil
+
+	case SearchSubstring:
+		return func(name string) bool {
+			return strings.Contains(name, query.Pattern)
+		}, nil
+
+	case SearchRegexp:
+		re, err := regexp.Compile(query.Pattern)
+		if err != nil {
+			return nil, Error(err)
+		}
+		return re.MatchString, nil
+
+	default:
+		return nil, Errorf("unknown search mode: %d", query.Mode)
+	}
+}
+
+func isSearchableSymbol(symbol elf.Symbol) bool {
+	if len(symbol.Name) == 0 || symbol.Value == 0 || symbol.Section == elf.SHN_UNDEF {
+		return false
+	}
+
+	switch elf.ST_TYPE(symbol.Info) {
+	case elf.STT_FUNC, elf.STT_OBJECT, elf.STT_TLS, elf.STT_NOTYPE:
+		return true
+	default:
+		return false
+	}
+}
+
+func (d *DebugData) moduleName() string {
+	return path.Base(d.path)
+}
+
+func (fn *FunctionEntry) moduleName() string {
+	if fn.Lib != nil {
+		return path.Base(fn.Lib.Name)
+	}
+
+	if fn.entry.data != nil {
+		return fn.entry.data.moduleName()
+	}
+
+	return ""
+}
+
+func (fn *FunctionEntry) modulePath() string {
+	if fn.Lib != nil {
+		return fn.Lib.Name
+	}
+
+	if fn.entry.data != nil {
+		return fn.entry.data.path
+	}
+
+	return ""
+}
+
+// staticAddress returns the address of a variable located by a single DW_OP_addr operation
+func (v *VariableEntry) staticAddress() (uintptr, bool) {
+	data := v.entry.data
+	instr, ok := v.entry.Val(dwarf.AttrLocation).([]byte)
+	if !ok || len(instr) != 1+data.AddressSize() || op.Opcode(instr[0]) != op.DW_OP_addr {
+		return 0, false
+	}
+
+	return data.readAddress(instr[1:]) + v.staticBase, true
+}
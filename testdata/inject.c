@@ -0,0 +1,3 @@
+/* Shared library loaded into the test program by the injection test */
+
+int injected_marker = 42;
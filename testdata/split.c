@@ -0,0 +1,24 @@
+/* Test program of the DW_AT_ranges tests: split() is compiled into a hot and a cold part */
+
+#include <stdio.h>
+#include <stdlib.h>
+
+__attribute__((cold, noinline)) void report(int x)
+{
+	fprintf(stderr, "unlikely %d\n", x);
+}
+
+__attribute__((noinline)) int split(int x)
+{
+	if (x == 12345) {
+		report(x);
+		fprintf(stderr, "again %d\n", x);
+		exit(x);
+	}
+	return x * 2;
+}
+
+int main(int argc, char **argv)
+{
+	return split(argc);
+}
@@ -0,0 +1,61 @@
+/* Test program of the integration tests: prints "ready", calls traced() every 10ms,
+ * counts the received SIGUSR1 and SIGCONT signals and reports them in the exit status */
+
+#include <signal.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <unistd.h>
+
+int counter;
+volatile sig_atomic_t usr1_hits;
+volatile sig_atomic_t cont_hits;
+
+static void on_usr1(int sig)
+{
+	usr1_hits++;
+}
+
+static void on_cont(int sig)
+{
+	cont_hits++;
+}
+
+int traced(int x)
+{
+	counter += x;
+	return counter;
+}
+
+__attribute__((noinline)) int tail_target(int x)
+{
+	return x + 1;
+}
+
+/* pops its frame and jumps to tail_target even without optimization */
+__attribute__((noinline, optimize("O2"))) int tail_caller(int x)
+{
+	int y = tail_target(x);
+
+	return tail_target(x * y);
+}
+
+int main(int argc, char **argv)
+{
+	int iterations = argc > 1 ? atoi(argv[1]) : 100;
+	int i;
+
+	signal(SIGUSR1, on_usr1);
+	signal(SIGCONT, on_cont);
+
+	/* the libraries are loaded, the test can attach */
+	printf("ready\n");
+	fflush(stdout);
+
+	for (i = 0; i < iterations; i++) {
+		traced(i);
+		tail_caller(i);
+		usleep(10000);
+	}
+
+	return usr1_hits + 16 * cont_hits;
+}
@@ -0,0 +1,82 @@
+package raztracer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+)
+
+// ThreadFilter restricts the reported hits of a breakpoint to some threads.
+// A thread matches if its TID is listed or its name matches one of the glob patterns.
+type ThreadFilter struct {
+	Names []string  `json:"names,omitempty" yaml:"names"` // e.g. "io-worker-*"
+	TIDs  []Process `json:"tids,omitempty" yaml:"tids"`
+}
+
+// Match returns whether the thread matches the filter (a nil or empty filter matches every thread)
+func (f *ThreadFilter) Match(tid Process, name string) bool {
+	if f == nil || (len(f.Names) == 0 && len(f.TIDs) == 0) {
+		return true
+	}
+
+	for _, t := range f.TIDs {
+		if t == tid {
+			return true
+		}
+	}
+
+	for _, pattern := range f.Names {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetBreakpointThreadFilter sets the threads whose hits of the breakpoint at 'addr' are reported
+// (nil removes the filter). Hits of other threads are continued without reporting an event.
+func (t *Tracer) SetBreakpointThreadFilter(addr uintptr, filter *ThreadFilter) error {
+	if _, found := t.breakpoints[addr]; !found {
+		return Errorf("breakpoint not found at %#x", addr)
+	}
+
+	if filter == nil {
+		delete(t.bpThreads, addr)
+	} else {
+		t.bpThreads[addr] = filter
+	}
+
+	return nil
+}
+
+// skipBreakpointHit returns true if the breakpoint was hit by a thread filtered out
+func (t *Tracer) skipBreakpointHit(evt *TraceEvent) bool {
+	if !evt.IsBreakpoint {
+		return false
+	}
+
+	filter, found := t.bpThreads[evt.PC]
+	if !found {
+		return false
+	}
+
+	name, _ := evt.TID.ThreadName()
+	if filter.Match(evt.TID, name) {
+		return false
+	}
+
+	t.stats.FilteredHits++
+	return true
+}
+
+// ThreadName returns the name of the thread
+func (pid Process) ThreadName() (string, error) {
+	comm, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/comm", pid))
+	if err != nil {
+		return "", Errorf("thread not found: %d", pid)
+	}
+
+	return strings.TrimSuffix(string(comm), "\n"), nil
+}
@@ -0,0 +1,73 @@
+package raztracer
+
+import (
+	"sync/atomic"
+)
+
+// DefaultThreadStreamBuffer is the number of events buffered for the consumer of a thread stream
+const DefaultThreadStreamBuffer = 1024
+
+// ThreadEvent is a trace event with its sequence number in the event stream of its thread
+type ThreadEvent struct {
+	*TraceEvent
+	ThreadSeq uint64 `json:"thread_seq"` // dropped events are counted too, so drops leave gaps
+}
+
+// ThreadStream is the ordered event stream of a single thread
+type ThreadStream struct {
+	TID     Process
+	Events  <-chan *ThreadEvent
+	dropped uint64
+}
+
+// Dropped returns the number of events dropped because the buffer of the stream was full
+func (stream *ThreadStream) Dropped() uint64 {
+	return atomic.LoadUint64(&stream.dropped)
+}
+
+// DemuxThreads splits an event stream into ordered per-thread streams
+// buffering DefaultThreadStreamBuffer events per thread (see DemuxThreadsWithBuffer).
+func DemuxThreads(events <-chan *TraceEvent) <-chan *ThreadStream {
+	return DemuxThreadsWithBuffer(events, DefaultThreadStreamBuffer)
+}
+
+// DemuxThreadsWithBuffer splits an event stream into ordered per-thread streams.
+// A new ThreadStream is sent on the returned channel when the first event of a thread arrives.
+// Every stream buffers 'size' events, so a slow consumer of one thread doesn't block the others;
+// when the buffer of a stream is full, the events of its thread are dropped and counted.
+// Every channel is closed when 'events' is closed.
+func DemuxThreadsWithBuffer(events <-chan *TraceEvent, size int) <-chan *ThreadStream {
+	streams := make(chan *ThreadStream, size)
+
+	go func() {
+		outputs := make(map[Process]chan *ThreadEvent)
+		threads := make(map[Process]*ThreadStream)
+		seqs := make(map[Process]uint64)
+
+		for evt := range events {
+			out, found := outputs[evt.TID]
+			if !found {
+				out = make(chan *ThreadEvent, size)
+				stream := &ThreadStream{TID: evt.TID, Events: out}
+
+				outputs[evt.TID] = out
+				threads[evt.TID] = stream
+				streams <- stream // new threads are never dropped
+			}
+
+			seqs[evt.TID]++
+			select {
+			case out <- &ThreadEvent{TraceEvent: evt, ThreadSeq: seqs[evt.TID]}:
+			default:
+				atomic.AddUint64(&threads[evt.TID].dropped, 1)
+			}
+		}
+
+		for _, out := range outputs {
+			close(out)
+		}
+		close(streams)
+	}()
+
+	return streams
+}
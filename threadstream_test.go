@@ -0,0 +1,48 @@
+package raztracer
+
+import (
+	"testing"
+)
+
+func TestDemuxThreads(t *testing.T) {
+	events := make(chan *TraceEvent)
+	streams := DemuxThreadsWithBuffer(events, 2)
+
+	go func() {
+		for i := 0; i < 5; i++ {
+			events <- &TraceEvent{TID: 1, Seq: uint64(i)}
+			if i < 2 {
+				events <- &TraceEvent{TID: 2, Seq: uint64(i)}
+			}
+		}
+		close(events)
+	}()
+
+	var all []*ThreadStream
+	for stream := range streams {
+		all = append(all, stream)
+	}
+	if len(all) != 2 || all[0].TID != 1 || all[1].TID != 2 {
+		t.Fatalf("unexpected streams: %v", all)
+	}
+
+	// nobody read the stream of thread 1, only its buffer was kept
+	var seqs []uint64
+	for evt := range all[0].Events {
+		seqs = append(seqs, evt.ThreadSeq)
+	}
+	if len(seqs) != 2 || seqs[0] != 1 || seqs[1] != 2 {
+		t.Errorf("unexpected events of thread 1: %v", seqs)
+	}
+	if dropped := all[0].Dropped(); dropped != 3 {
+		t.Errorf("%d events of thread 1 were dropped, expected 3", dropped)
+	}
+
+	count := 0
+	for range all[1].Events {
+		count++
+	}
+	if count != 2 || all[1].Dropped() != 0 {
+		t.Errorf("thread 2 got %d events and dropped %d, expected 2 and none", count, all[1].Dropped())
+	}
+}
@@ -0,0 +1,64 @@
+package raztracer
+
+import (
+	"debug/elf"
+
+	"github.com/razzie/raztracer/internal/dwarf/op"
+)
+
+// staticTLSOffset returns the distance between the thread pointer and the TLS block of the
+// executable, which precedes the thread control block (x86-64 TLS variant II).
+// Returns false if the module has no TLS segment or is a shared library, whose TLS block
+// is allocated at an unknown place.
+func (d *DebugData) staticTLSOffset() (uint64, bool) {
+	if d == nil || d.isLib || d.elfData == nil {
+		return 0, false
+	}
+
+	for _, prog := range d.elfData.Progs {
+		if prog.Type != elf.PT_TLS {
+			continue
+		}
+
+		align := prog.Align
+		if align == 0 {
+			align = 1
+		}
+
+		// same as _dl_determine_tlsoffset in glibc for the first module
+		firstByte := -prog.Vaddr & (align - 1)
+		offset := (prog.Memsz-firstByte+align-1)/align*align + firstByte
+		return offset, true
+	}
+
+	return 0, false
+}
+
+// isTLSLocation returns whether the location expression is the address of a thread-local variable
+func isTLSLocation(instructions []byte) bool {
+	if len(instructions) == 0 {
+		return false
+	}
+
+	lastOp := op.Opcode(instructions[len(instructions)-1])
+	return lastOp == op.DW_OP_form_tls_address || lastOp == op.DW_OP_GNU_push_tls_address
+}
+
+// withTLS returns the registers with the static base of the module (for DW_OP_addr)
+// and the TLS block address of the module in the current thread
+func (d *DebugData) withTLS(regs *op.DwarfRegisters) *op.DwarfRegisters {
+	modRegs := *regs
+	modRegs.StaticBase = uint64(d.staticBase)
+
+	tp := regs.Reg(ThreadPointerDwarfReg)
+	if tp == nil || tp.Uint64Val == 0 {
+		return &modRegs
+	}
+
+	offset, ok := d.staticTLSOffset()
+	if ok {
+		modRegs.TLSBase = tp.Uint64Val - offset
+	}
+
+	return &modRegs
+}
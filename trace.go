@@ -0,0 +1,258 @@
+package raztracer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// TraceConfig contains the settings of a Trace session
+type TraceConfig struct {
+	Functions   []string            // breakpoints are set at these functions
+	Signals     []syscall.Signal    // signals to report (every signal if empty)
+	BreakOnExit bool                // break before the process exits, aborts or throws
+	NewThreads  bool                // report thread creation events
+	Rules       *RuleEngine         // rules are applied to every event before the handlers
+	Handlers    []EventHandler      // chain of event processors
+	Libraries   *LibraryFilter      // shared libraries to load debug info from (all if nil)
+	Depth       int                 // maximum number of backtrace frames (DefaultBacktraceDepth if 0)
+	Placement   BreakpointPlacement // where breakpoints are placed in functions (after prologue if empty)
+	Threads     *ThreadFilter       // only report the breakpoint hits of these threads (all if nil)
+	Exits       bool                // report the returns of the functions with call IDs
+	RegDiffs    bool                // only report the registers changed since the previous event of the thread
+	TrackLibs   bool                // refresh the shared libraries when libraries are loaded or unloaded
+	MemBudget   int64               // limit of the estimated memory usage of the debug data (unlimited if 0)
+	Profile     bool                // attach the collection overhead to every event
+	Latency     *LatencyRecorder    // latency probes, their functions get breakpoints too
+	AutoDetach  *AutoDetach         // conditions of detaching automatically (never if nil)
+
+	// OnError is called with the errors of the tracer and the event handlers.
+	// A tracer error is followed by detaching and closing the channel.
+	OnError func(pid int, err error)
+}
+
+// Trace attaches to the process, sets breakpoints at the configured functions and
+// returns the channel of trace events. The channel is closed when the context is done
+// or when the tracer detaches from the process.
+func Trace(ctx context.Context, pid int, cfg TraceConfig) (<-chan *TraceEvent, error) {
+	events := make(chan *TraceEvent, 16)
+
+	mgr, err := NewTraceManagerWithFilter(pid, cfg.Libraries, func(t *Tracer, evt *TraceEvent, err error) {
+		if err != nil && cfg.OnError != nil {
+			cfg.OnError(pid, err)
+		}
+
+		if evt == nil || !cfg.accepts(evt) {
+			return
+		}
+
+		select {
+		case events <- evt:
+		case <-ctx.Done():
+		}
+	})
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	handlers := cfg.Handlers
+	if cfg.Rules != nil {
+		handlers = append([]EventHandler{cfg.Rules}, handlers...)
+	}
+	if cfg.Latency != nil {
+		handlers = append([]EventHandler{cfg.Latency}, handlers...)
+	}
+
+	err = mgr.AddEventHandler(handlers...)
+	if err != nil {
+		mgr.Close()
+		return nil, Error(err)
+	}
+
+	err = mgr.HandleRequest(func(t *Tracer) error {
+		t.SetBacktraceDepth(cfg.Depth)
+		t.SetExitTracing(cfg.Exits)
+		t.SetRegisterDiffs(cfg.RegDiffs)
+		t.SetMemoryBudget(cfg.MemBudget)
+		t.SetProfiling(cfg.Profile)
+		t.SetAutoDetach(cfg.AutoDetach)
+		if len(cfg.Placement) > 0 {
+			t.SetBreakpointPlacement(cfg.Placement)
+		}
+		return Error(cfg.setBreakpoints(t))
+	})
+	if err != nil {
+		mgr.Close()
+		return nil, Error(err)
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			mgr.Close()
+		case <-mgr.Done():
+		}
+
+		<-mgr.Done()
+		close(events)
+	}()
+
+	return events, nil
+}
+
+// PartialTraceError is returned by TraceProcesses together with a valid event channel
+// when some of the processes couldn't be traced
+type PartialTraceError struct {
+	Failed map[Process]error
+}
+
+// Error implements error interface
+func (err *PartialTraceError) Error() string {
+	pids := make([]Process, 0, len(err.Failed))
+	for pid := range err.Failed {
+		pids = append(pids, pid)
+	}
+	sort.Slice(pids, func(i, j int) bool { return pids[i] < pids[j] })
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "failed to trace %d processes:", len(pids))
+	for _, pid := range pids {
+		fmt.Fprintf(&sb, "\n%d: %s", pid, errorMessage(err.Failed[pid]))
+	}
+
+	return sb.String()
+}
+
+// TraceProcesses traces multiple processes and merges their events into one channel.
+// If none of the processes could be traced, only an error is returned. If some of them
+// failed, their errors are returned in a PartialTraceError next to the channel.
+func TraceProcesses(ctx context.Context, processes []Process, cfg TraceConfig) (<-chan *TraceEvent, error) {
+	if len(processes) == 0 {
+		return nil, Errorf("no processes to trace")
+	}
+
+	var errors []error
+	var wg sync.WaitGroup
+	merged := make(chan *TraceEvent, 16)
+	partial := &PartialTraceError{Failed: make(map[Process]error)}
+
+	for _, pid := range processes {
+		events, err := Trace(ctx, int(pid), cfg)
+		if err != nil {
+			errors = append(errors, err)
+			partial.Failed[pid] = err
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for evt := range events {
+				select {
+				case merged <- evt:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+
+	if len(errors) == len(processes) {
+		return nil, MergeErrors(errors)
+	}
+
+	go func() {
+		wg.Wait()
+		close(merged)
+	}()
+
+	if len(partial.Failed) > 0 {
+		return merged, Error(partial)
+	}
+
+	return merged, nil
+}
+
+func (cfg *TraceConfig) setBreakpoints(t *Tracer) error {
+	functions := cfg.Functions
+	if cfg.Latency != nil {
+		functions = uniqueNames(append(append([]string{}, functions...), cfg.Latency.Functions()...))
+	}
+
+	if len(functions) == 0 && !cfg.BreakOnExit && !cfg.TrackLibs {
+		return nil
+	}
+
+	err := t.Interrupt()
+	if err != nil {
+		return Error(err)
+	}
+
+	var errors []error
+	for _, name := range functions {
+		locs, err := t.SetBreakpointAtFunction(name)
+		if err != nil {
+			errors = append(errors, err)
+		}
+
+		if cfg.Threads != nil {
+			for _, addr := range breakpointAddresses(locs) {
+				t.SetBreakpointThreadFilter(addr, cfg.Threads)
+			}
+		}
+	}
+
+	if cfg.BreakOnExit {
+		_, err := t.SetExitBreakpoints()
+		if err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	if cfg.TrackLibs {
+		err := t.SetLibraryTracking(true)
+		if err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	err = t.Run()
+	if err != nil {
+		errors = append(errors, err)
+	}
+
+	return MergeErrors(errors)
+}
+
+// uniqueNames returns the names without duplicates in their original order
+func uniqueNames(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	unique := names[:0]
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+func (cfg *TraceConfig) accepts(evt *TraceEvent) bool {
+	if evt.IsNewThread {
+		return cfg.NewThreads
+	}
+
+	if evt.IsBreakpoint || evt.IsWatchpoint || evt.IsStopRequest || len(cfg.Signals) == 0 {
+		return true
+	}
+
+	for _, sig := range cfg.Signals {
+		if evt.Signal == sig {
+			return true
+		}
+	}
+
+	return false
+}
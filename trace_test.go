@@ -0,0 +1,105 @@
+package raztracer
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTraceBreakpoints(t *testing.T) {
+	cmd := startTracee(t, 200)
+	defer cmd.Process.Kill()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cfg := TraceConfig{
+		Functions: []string{"traced"},
+		OnError: func(pid int, err error) {
+			t.Errorf("tracer error in %d: %v", pid, err)
+		},
+	}
+
+	events, err := Trace(ctx, cmd.Process.Pid, cfg)
+	skipIfNotPermitted(t, err)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hits := 0
+	for evt := range events {
+		if !evt.IsBreakpoint {
+			continue
+		}
+
+		if len(evt.Backtrace) == 0 || evt.Backtrace[0].fn.Name != "traced" {
+			t.Errorf("unexpected backtrace: %v", evt.Backtrace)
+		} else if source := evt.Backtrace[0].Source; !strings.HasPrefix(source, "tracee.c:") {
+			t.Errorf("traced() has no line info in the PIE test program: %s", source)
+		}
+
+		if findReading(evt.Globals, "counter") == nil {
+			t.Errorf("global 'counter' is missing from %v", evt.Globals)
+		}
+
+		if hits++; hits == 3 {
+			cancel()
+		}
+	}
+
+	if hits < 3 {
+		t.Fatalf("got %d breakpoint hits, expected at least 3", hits)
+	}
+
+	if status := waitTracee(t, cmd); status != 0 {
+		t.Errorf("the test program exited with %d after detaching", status)
+	}
+}
+
+func TestTraceProcessesPartialFailure(t *testing.T) {
+	cmd := startTracee(t, 200)
+	defer cmd.Process.Kill()
+
+	// a process that already exited can't be attached
+	exited := exec.Command("true")
+	if err := exited.Run(); err != nil {
+		t.Skip(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	processes := []Process{Process(cmd.Process.Pid), Process(exited.Process.Pid)}
+	events, err := TraceProcesses(ctx, processes, TraceConfig{Functions: []string{"traced"}})
+	skipIfNotPermitted(t, err)
+
+	var partial *PartialTraceError
+	if !errors.As(err, &partial) {
+		t.Fatalf("expected a partial failure, got %v", err)
+	}
+	if _, failed := partial.Failed[Process(exited.Process.Pid)]; !failed || len(partial.Failed) != 1 {
+		t.Errorf("unexpected failed processes: %v", partial.Failed)
+	}
+	if events == nil {
+		t.Fatal("no event channel next to the partial failure")
+	}
+
+	hits := 0
+	for evt := range events {
+		if evt.IsBreakpoint && evt.PID == Process(cmd.Process.Pid) {
+			hits++
+			cancel()
+		}
+	}
+
+	if hits == 0 {
+		t.Error("the traceable process didn't report any events")
+	}
+
+	if status := waitTracee(t, cmd); status != 0 {
+		t.Errorf("the test program exited with %d after detaching", status)
+	}
+}
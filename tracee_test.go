@@ -0,0 +1,121 @@
+package raztracer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// traceePath is the test program compiled from testdata/tracee.c (empty if it couldn't be compiled)
+var traceePath string
+
+// injectLibPath is the shared library compiled from testdata/inject.c (empty if it couldn't be compiled)
+var injectLibPath string
+
+// splitPath is the program compiled from testdata/split.c (empty if it couldn't be compiled)
+var splitPath string
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "raztracer")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if cc, err := exec.LookPath("cc"); err == nil {
+		// PIE, so the tests cover the relocation of the executable
+		path := filepath.Join(dir, "tracee")
+		if exec.Command(cc, "-g", "-O0", "-fPIE", "-pie", "-o", path, "testdata/tracee.c").Run() == nil {
+			traceePath = path
+		}
+
+		lib := filepath.Join(dir, "libinject.so")
+		if exec.Command(cc, "-shared", "-fPIC", "-o", lib, "testdata/inject.c").Run() == nil {
+			injectLibPath = lib
+		}
+
+		split := filepath.Join(dir, "split")
+		if exec.Command(cc, "-g", "-O2", "-freorder-blocks-and-partition", "-o", split, "testdata/split.c").Run() == nil {
+			splitPath = split
+		}
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// startTracee starts the test program and waits until it's ready to be traced.
+// The program calls traced() every 10ms 'iterations' times.
+func startTracee(t *testing.T, iterations int) *exec.Cmd {
+	t.Helper()
+
+	if len(traceePath) == 0 {
+		t.Skip("the test program could not be compiled")
+	}
+
+	cmd := exec.Command(traceePath, strconv.Itoa(iterations))
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	ready, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil || ready != "ready\n" {
+		cmd.Process.Kill()
+		t.Fatalf("the test program didn't start: %q %v", ready, err)
+	}
+
+	return cmd
+}
+
+// waitTracee waits for the test program to exit and returns its exit status.
+// The tracer has to be detached, otherwise the ptrace stops are reported to the test.
+func waitTracee(t *testing.T, cmd *exec.Cmd) int {
+	t.Helper()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			status := exitErr.Sys().(syscall.WaitStatus)
+			if status.Signaled() {
+				t.Fatalf("the test program was killed by %v", status.Signal())
+			}
+			return status.ExitStatus()
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		return 0
+
+	case <-time.After(10 * time.Second):
+		cmd.Process.Kill()
+		t.Fatal("the test program didn't exit")
+		return -1
+	}
+}
+
+// skipIfNotPermitted skips the test if the sandbox doesn't allow ptrace
+func skipIfNotPermitted(t *testing.T, err error) {
+	t.Helper()
+
+	if errors.Is(err, syscall.EPERM) {
+		t.Skip("ptrace is not permitted:", err)
+	}
+}
@@ -1,27 +1,48 @@
 package raztracer
 
 import (
+	"errors"
 	"fmt"
 	"runtime"
+	"sync"
 	"syscall"
 	"time"
 )
 
 // TraceManager is an automated tracer of a process that collects events
 type TraceManager struct {
+	mutex     sync.Mutex // guards 'tracer', which is also read by RequestStop and HandleRequest
 	tracer    *Tracer
 	eventFunc func(*Tracer, *TraceEvent, error)
 	requests  chan traceRequest
+	done      chan struct{}
+	handlers  []EventHandler
 	pid       int
+	filter    *LibraryFilter
+	closeOnce sync.Once
+	closeErr  error
 }
 
 // NewTraceManager creates a new TraceManager
 func NewTraceManager(pid int, eventFunc func(*Tracer, *TraceEvent, error)) (*TraceManager, error) {
+	mgr, err := NewTraceManagerWithFilter(pid, nil, eventFunc)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	return mgr, nil
+}
+
+// NewTraceManagerWithFilter creates a new TraceManager that only loads
+// the debug info of the shared libraries matching 'filter'
+func NewTraceManagerWithFilter(pid int, filter *LibraryFilter, eventFunc func(*Tracer, *TraceEvent, error)) (*TraceManager, error) {
 	TraceManager := &TraceManager{
 		tracer:    nil, // will be set later
 		eventFunc: eventFunc,
 		requests:  make(chan traceRequest, 1),
+		done:      make(chan struct{}),
 		pid:       pid,
+		filter:    filter,
 	}
 
 	errOut := make(chan error, 1)
@@ -34,30 +55,41 @@ func NewTraceManager(pid int, eventFunc func(*Tracer, *TraceEvent, error)) (*Tra
 	return TraceManager, nil
 }
 
-// Close detaches the tracer from the process and stops the tracer's thread
+// Close detaches the tracer from the process and stops the tracer's thread.
+// It does nothing if the tracer's thread already stopped, e.g. by auto-detach.
 func (proc *TraceManager) Close() error {
-	req := func(*Tracer) error {
-		err := proc.tracer.Detach()
-		proc.tracer = nil
-		return err
-	}
+	proc.closeOnce.Do(func() {
+		req := func(t *Tracer) error {
+			// stop requests must not reach the process after detaching
+			proc.setTracer(nil)
+			return t.Detach()
+		}
+
+		err := proc.HandleRequest(req)
+		if err != nil && err != errManagerStopped {
+			proc.closeErr = Error(err)
+		}
+	})
 
-	err := proc.HandleRequest(req)
-	close(proc.requests)
-	proc.requests = nil
-	return Error(err)
+	return proc.closeErr
+}
+
+// Done returns a channel that is closed when the tracer's thread stops
+func (proc *TraceManager) Done() <-chan struct{} {
+	return proc.done
 }
 
 func (proc *TraceManager) run(errOut chan<- error) {
 	runtime.LockOSThread()
+	defer close(proc.done)
 
-	tracer, err := NewTracer(proc.pid)
+	tracer, err := NewTracerWithFilter(proc.pid, proc.filter)
 	if err != nil {
 		errOut <- Error(err)
 		return
 	}
 
-	proc.tracer = tracer
+	proc.setTracer(tracer)
 
 	tracer.Run()
 	errOut <- nil // notify NewTraceManager everything is awesome
@@ -65,24 +97,43 @@ func (proc *TraceManager) run(errOut chan<- error) {
 	for {
 		select {
 		case req := <-proc.requests:
-			req.err <- Error(req.fn(tracer))
+			req.err <- req.fn(tracer)
 
 		default:
 		}
 
-		if proc.requests == nil {
+		// Close or another request detached the tracer
+		if tracer.IsDetached() {
+			proc.setTracer(nil)
 			return
 		}
 
 		event, err := tracer.WaitForEvent(100 * time.Millisecond)
 		if event == nil && err == nil {
+			if detached, _ := tracer.CheckAutoDetach(nil); detached {
+				proc.setTracer(nil)
+				return
+			}
 			continue
 		}
 
-		proc.eventFunc(tracer, event, Error(err))
+		if err != nil {
+			proc.eventFunc(tracer, event, Error(err))
+		} else if processed, handlerErr := processEvent(proc.handlers, tracer, event); processed != nil || handlerErr != nil {
+			proc.eventFunc(tracer, processed, handlerErr)
+		}
+
+		if err == nil {
+			tracer.CheckAutoDetach(event)
+		}
+
+		if tracer.IsDetached() {
+			proc.setTracer(nil)
+			return
+		}
 
-		if err != nil || (event != nil && event.Signal == syscall.SIGSEGV) {
-			proc.tracer = nil
+		if err != nil || (event != nil && event.Signal == syscall.SIGSEGV && !event.IsWatchpoint) {
+			proc.setTracer(nil)
 			err := tracer.Detach()
 			if err != nil {
 				fmt.Println(Error(err))
@@ -92,19 +143,61 @@ func (proc *TraceManager) run(errOut chan<- error) {
 	}
 }
 
-// HandleRequest is a blocking call to the provided function in the tracer's thread
-func (proc *TraceManager) HandleRequest(fn func(*Tracer) error) error {
-	if proc.tracer == nil {
-		return fmt.Errorf("the inner tracer is already detached")
-	}
+// AddEventHandler appends handlers to the event processing chain.
+// Events pass through the handlers in order before reaching the event function.
+func (proc *TraceManager) AddEventHandler(handlers ...EventHandler) error {
+	return proc.HandleRequest(func(*Tracer) error {
+		proc.handlers = append(proc.handlers, handlers...)
+		return nil
+	})
+}
 
+// errManagerStopped is returned by requests that can't be handled by the stopped tracer's thread
+var errManagerStopped = errors.New("the inner tracer is already detached")
+
+// HandleRequest is a blocking call to the provided function in the tracer's thread.
+// It returns an error if the tracer's thread stops before handling the request.
+func (proc *TraceManager) HandleRequest(fn func(*Tracer) error) error {
 	req := traceRequest{
 		fn:  fn,
-		err: make(chan error),
+		err: make(chan error, 1),
 	}
 
-	proc.requests <- req
-	return Error(<-req.err)
+	// the thread may stop on its own (e.g. auto-detach) while the request is queued
+	select {
+	case proc.requests <- req:
+	case <-proc.done:
+		return errManagerStopped
+	}
+
+	var err error
+	select {
+	case err = <-req.err:
+	case <-proc.done:
+		// the reply is sent before the thread stops
+		select {
+		case err = <-req.err:
+		default:
+			return errManagerStopped
+		}
+	}
+
+	if err != nil {
+		return Error(err)
+	}
+	return nil
+}
+
+func (proc *TraceManager) getTracer() *Tracer {
+	proc.mutex.Lock()
+	defer proc.mutex.Unlock()
+	return proc.tracer
+}
+
+func (proc *TraceManager) setTracer(tracer *Tracer) {
+	proc.mutex.Lock()
+	proc.tracer = tracer
+	proc.mutex.Unlock()
 }
 
 type traceRequest struct {
@@ -0,0 +1,76 @@
+package raztracer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTraceManagerStoppedOnItsOwn(t *testing.T) {
+	cmd := startTracee(t, 10)
+	defer cmd.Process.Kill()
+
+	mgr, err := NewTraceManager(cmd.Process.Pid, func(*Tracer, *TraceEvent, error) {})
+	skipIfNotPermitted(t, err)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the tracer's thread stops when the test program exits
+	select {
+	case <-mgr.Done():
+	case <-time.After(5 * time.Second):
+		mgr.Close()
+		t.Fatal("the tracer's thread didn't stop after the process exited")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- mgr.HandleRequest(func(*Tracer) error { return nil })
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("a request was handled by the stopped tracer's thread")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the request to the stopped tracer's thread blocks")
+	}
+
+	if err := mgr.Close(); err != nil {
+		t.Error(err)
+	}
+	if err := mgr.Close(); err != nil {
+		t.Error(err)
+	}
+
+	cmd.Wait()
+}
+
+func TestTraceManagerClose(t *testing.T) {
+	cmd := startTracee(t, 100)
+	defer cmd.Process.Kill()
+
+	mgr, err := NewTraceManager(cmd.Process.Pid, func(*Tracer, *TraceEvent, error) {})
+	skipIfNotPermitted(t, err)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mgr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.Close(); err != nil {
+		t.Error(err)
+	}
+
+	select {
+	case <-mgr.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the tracer's thread didn't stop after Close")
+	}
+
+	if status := waitTracee(t, cmd); status != 0 {
+		t.Errorf("the test program exited with %d after detaching", status)
+	}
+}
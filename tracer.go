@@ -3,7 +3,6 @@ package raztracer
 import (
 	"fmt"
 	"io/ioutil"
-	"os"
 	"strings"
 	"syscall"
 	"time"
@@ -11,29 +10,95 @@ import (
 
 // TraceEvent is received when a breakpoint is hit or the process receives a signal
 type TraceEvent struct {
-	Status       syscall.WaitStatus `json:"-"`
-	Signal       syscall.Signal     `json:"signal"`
-	PID          Process            `json:"pid"`
-	TID          Process            `json:"tid"`
-	IsBreakpoint bool               `json:"breakpoint"`
-	PC           uintptr            `json:"pc"`
-	Registers    map[string]string  `json:"regs"`
-	Globals      []Reading          `json:"globals"`
-	Backtrace    []*BacktraceFrame  `json:"backtrace"`
+	Status        syscall.WaitStatus        `json:"-"`
+	Seq           uint64                    `json:"seq"`
+	Signal        syscall.Signal            `json:"signal"`
+	Reason        StopReason                `json:"reason"`
+	PID           Process                   `json:"pid"`
+	TID           Process                   `json:"tid"`
+	IsBreakpoint  bool                      `json:"breakpoint"`
+	IsExitPath    bool                      `json:"exit_path"`
+	IsWatchpoint  bool                      `json:"watchpoint"`
+	WatchAddress  uintptr                   `json:"watch_addr,omitempty"`
+	IsNewThread   bool                      `json:"new_thread"`
+	IsStopRequest bool                      `json:"stop_request"`
+	NewTID        Process                   `json:"new_tid,omitempty"`
+	PC            uintptr                   `json:"pc"`
+	Stack         *StackUsage               `json:"stack,omitempty"`
+	Warnings      []string                  `json:"warnings,omitempty"`
+	Registers     map[string]string         `json:"regs,omitempty"`
+	RegisterDiff  map[string]RegisterChange `json:"reg_diff,omitempty"`
+	Globals       []Reading                 `json:"globals"`
+	Backtrace     []*BacktraceFrame         `json:"backtrace"`
+	BacktraceEnd  BacktraceEnd              `json:"backtrace_end,omitempty"`
+	Unwind        *UnwindDiagnostic         `json:"unwind,omitempty"`
+	Step          *StepInfo                 `json:"step,omitempty"`
+	Call          *CallInfo                 `json:"call,omitempty"`
+	Python        []PythonThread            `json:"python,omitempty"`
+	Overhead      *EventOverhead            `json:"overhead,omitempty"`
+	Latency       []LatencySample           `json:"latency,omitempty"`
 }
 
+// ExitFunctions contains the functions that terminate the process or unwind the stack
+var ExitFunctions = []string{"exit", "_exit", "abort", "__cxa_throw"}
+
 // Tracer is used to trace a running process
 type Tracer struct {
-	progName      string
-	pid, tid      Process
-	debugData     *DebugData
-	breakpoints   map[uintptr]*Breakpoint
-	deliverSignal syscall.Signal
+	progName          string
+	pid, tid          Process
+	debugData         *DebugData
+	breakpoints       map[uintptr]*Breakpoint
+	exitPaths         map[uintptr]bool
+	placement         BreakpointPlacement
+	bpFunctions       map[uintptr]string
+	exe               BinaryIdentity
+	watchpoints       map[uintptr]*Watchpoint
+	pendingWatch      *Watchpoint
+	deliverSignal     syscall.Signal
+	libArgCount       int
+	readingOpts       ReadingOptions
+	backtraceDepth    int
+	stepBudget        int
+	stackMargin       uint64
+	unwindDiagnostics bool
+	stacks            map[Process]stackBounds
+	session           *SessionInfo
+	python            *PythonInterpreter
+	stats             TracerStats
+	eventLimit        *rateLimiter
+	bpLimits          map[uintptr]*rateLimiter
+	bpThreads         map[uintptr]*ThreadFilter
+	stopRequested     int32 // set atomically by RequestStop
+	exitTracing       bool
+	retBreakpoints    map[uintptr]*returnBreakpoint
+	calls             map[Process][]*pendingCall
+	lastCallID        uint64
+	registerDiffs     bool
+	rendezvous        uintptr // breakpoint of the dynamic linker when tracking libraries
+	memoryBudget      int64
+	lastRegs          map[Process]map[string]string
+	profile           profiler
+	profiling         bool
+	coverage          *coverage
+	autoDetach        *AutoDetach
+	detached          bool
 }
 
 // NewTracer returns a Tracer instance attached to 'pid' process
 func NewTracer(pid int) (*Tracer, error) {
-	prog, err := os.Open(fmt.Sprintf("/proc/%d/exe", pid))
+	t, err := NewTracerWithFilter(pid, nil)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	return t, nil
+}
+
+// NewTracerWithFilter returns a Tracer instance attached to 'pid' process
+// that only loads the debug info of the shared libraries matching 'filter'
+func NewTracerWithFilter(pid int, filter *LibraryFilter) (*Tracer, error) {
+	proc := Process(pid)
+	exe, err := proc.exeIdentity()
 	if err != nil {
 		return nil, Errorf("process not found: %d", pid)
 	}
@@ -41,26 +106,43 @@ func NewTracer(pid int) (*Tracer, error) {
 	progNameBytes, _ := ioutil.ReadFile(fmt.Sprintf("/proc/%d/comm", pid))
 	progName := strings.TrimSuffix(string(progNameBytes), "\n")
 
-	debugData, err := NewDebugData(prog, 0)
+	debugData, err := loadDebugData(proc, filter)
 	if err != nil {
 		return nil, Error(err)
 	}
 
-	proc := Process(pid)
-	libs, _ := proc.SharedLibs()
-	for _, lib := range libs {
-		debugData.AddSharedLib(lib)
-	}
+	exe.BuildID, _ = debugData.GetBuildID()
+	python, _ := NewPythonInterpreter(proc)
 
 	breakpoints := make(map[uintptr]*Breakpoint)
 
 	t := &Tracer{
-		progName:      progName,
-		pid:           proc,
-		tid:           0,
-		debugData:     debugData,
-		breakpoints:   breakpoints,
-		deliverSignal: syscall.SIGCONT,
+		progName:       progName,
+		pid:            proc,
+		tid:            0,
+		debugData:      debugData,
+		breakpoints:    breakpoints,
+		exitPaths:      make(map[uintptr]bool),
+		bpFunctions:    make(map[uintptr]string),
+		placement:      PlaceAfterPrologue,
+		exe:            exe,
+		watchpoints:    make(map[uintptr]*Watchpoint),
+		deliverSignal:  0,
+		libArgCount:    len(ArgRegNums),
+		readingOpts:    DefaultReadingOptions,
+		backtraceDepth: DefaultBacktraceDepth,
+		stepBudget:     DefaultStepBudget,
+		stackMargin:    DefaultStackMargin,
+		stacks:         make(map[Process]stackBounds),
+		session:        NewSessionInfo(proc, progName, debugData),
+		python:         python,
+		stats:          newTracerStats(),
+		bpLimits:       make(map[uintptr]*rateLimiter),
+		bpThreads:      make(map[uintptr]*ThreadFilter),
+		retBreakpoints: make(map[uintptr]*returnBreakpoint),
+		calls:          make(map[Process][]*pendingCall),
+		lastRegs:       make(map[Process]map[string]string),
+		profile:        newProfiler(),
 	}
 
 	return t, t.Attach()
@@ -71,6 +153,11 @@ func (t *Tracer) GetProgName() string {
 	return t.progName
 }
 
+// GetSessionInfo returns the information collected about the process on attach
+func (t *Tracer) GetSessionInfo() *SessionInfo {
+	return t.session
+}
+
 // GetDebugData returns the debug data of the traced process
 func (t *Tracer) GetDebugData() *DebugData {
 	return t.debugData
@@ -92,48 +179,122 @@ func (t *Tracer) Attach() error {
 
 // Detach detaches the Tracer from the running process
 func (t *Tracer) Detach() error {
+	_, err := t.DetachWithReport()
+	return err
+}
+
+// DetachWithReport detaches the Tracer from the running process and reports
+// what was restored and what may have been left behind in the process.
+// Detaching an already detached tracer returns an empty report.
+func (t *Tracer) DetachWithReport() (*DetachReport, error) {
+	report := &DetachReport{
+		PID:                t.pid,
+		Time:               time.Now(),
+		BreakpointsRemoved: make([]uintptr, 0),
+		ThreadsResumed:     make([]Process, 0),
+	}
+
+	// the threads are no longer traced, interrupting them would stop the process for good
+	if t.detached {
+		return report, nil
+	}
+
 	if t.deliverSignal == syscall.SIGSEGV {
-		return nil
+		report.addRisk(RiskDetachSkipped, 0, t.tid, Errorf("SIGSEGV is pending"))
+		return report, nil
 	}
 
+	// the signal of the current stop is forwarded when detaching
+	stoppedTID, pendingSignal := t.tid, t.deliverSignal
+
 	threads, err := t.pid.Threads()
 	if err != nil {
-		return Error(err)
+		return report, Error(err)
 	}
 
 	var errors []error
 
 	for _, tid := range threads {
-		err := Error(tid.Interrupt())
-		if err != nil {
+		if err := tid.Interrupt(); err != nil {
+			report.addRisk(RiskThreadNotStopped, 0, tid, err)
 			errors = append(errors, Error(err))
 		}
 
 		t.tid = tid
-		err = Error(t.stepOverBreakpoint())
-		if err != nil {
+		if err := t.stepOverBreakpoint(); err != nil {
+			report.addRisk(RiskStepOverFailed, 0, tid, err)
 			errors = append(errors, Error(err))
 		}
 	}
 
-	for _, bp := range t.breakpoints {
-		err := Error(bp.Disable())
-		if err != nil {
+	regions, _ := t.pid.MemRegions()
+	for addr, bp := range t.breakpoints {
+		if !bp.IsEnabled() {
+			continue
+		}
+
+		if err := bp.Disable(); err != nil {
+			if isMappedAddress(regions, addr) {
+				report.addRisk(RiskBreakpointNotRestored, addr, 0, err)
+			} else {
+				report.addRisk(RiskBreakpointUnmapped, addr, 0, err)
+			}
 			errors = append(errors, Error(err))
+			continue
 		}
+
+		report.BreakpointsRemoved = append(report.BreakpointsRemoved, addr)
+		report.checkRestoredBreakpoint(bp, regions)
+	}
+
+	report.WatchpointsRemoved = len(t.watchpoints)
+	if err := t.removeWatchpoints(); err != nil {
+		report.addRisk(RiskWatchpointNotRestored, 0, 0, err)
+		errors = append(errors, Error(err))
 	}
 
 	t.tid = 0
 	t.breakpoints = make(map[uintptr]*Breakpoint)
+	t.exitPaths = make(map[uintptr]bool)
+	t.bpFunctions = make(map[uintptr]string)
+	t.bpLimits = make(map[uintptr]*rateLimiter)
+	t.bpThreads = make(map[uintptr]*ThreadFilter)
+	t.retBreakpoints = make(map[uintptr]*returnBreakpoint)
+	t.calls = make(map[Process][]*pendingCall)
+	t.stacks = make(map[Process]stackBounds)
+	t.lastRegs = make(map[Process]map[string]string)
+	t.coverage = nil
+	t.deliverSignal = 0
 
 	for _, tid := range threads {
-		err := Error(tid.Detach())
-		if err != nil {
+		if signals, _ := tid.pendingSignals(); len(signals) > 0 {
+			report.PendingSignals = append(report.PendingSignals, PendingSignals{Thread: tid, Signals: signals})
+		}
+
+		var sig syscall.Signal
+		if tid == stoppedTID {
+			sig = pendingSignal
+		}
+
+		if err := tid.DetachWithSig(sig); err != nil {
+			report.addRisk(RiskThreadNotResumed, 0, tid, err)
 			errors = append(errors, Error(err))
+			continue
 		}
+
+		report.ThreadsResumed = append(report.ThreadsResumed, tid)
 	}
 
-	return MergeErrors(errors)
+	t.detached = true
+	if len(errors) > 0 {
+		return report, MergeErrors(errors)
+	}
+	return report, nil
+}
+
+// IsDetached returns whether the tracer is detached from the process
+func (t *Tracer) IsDetached() bool {
+	return t.detached
 }
 
 // GetPC gets the program counter
@@ -196,43 +357,146 @@ func (t *Tracer) GetRegisters() (map[string]string, error) {
 
 // GetBacktrace gets the list of backtrace frames of the process
 func (t *Tracer) GetBacktrace(maxFrames int) ([]*BacktraceFrame, error) {
-	frames := make([]*BacktraceFrame, 0)
+	bt, err := t.GetBacktraceDetails(maxFrames)
+	if err != nil {
+		return bt.Frames, Error(err) // the frames before the failure
+	}
+
+	return bt.Frames, nil
+}
+
+// GetBacktraceDetails gets the backtrace of the process together with
+// the information whether it was truncated or stopped by an unwind failure
+func (t *Tracer) GetBacktraceDetails(maxFrames int) (*Backtrace, error) {
+	bt, err := t.getBacktrace(maxFrames, nil)
+	if err != nil {
+		return bt, Error(err)
+	}
+
+	return bt, nil
+}
+
+// getBacktrace unwinds the stack and reads the frames, measuring both in 'prof' if not nil
+func (t *Tracer) getBacktrace(maxFrames int, prof *eventProfile) (*Backtrace, error) {
+	bt := &Backtrace{
+		Frames: make([]*BacktraceFrame, 0),
+		End:    BacktraceComplete,
+	}
+
 	stack, err := NewStackIterator(t.tid, t.debugData)
 	if err != nil {
-		return frames, Error(err)
+		bt.End = BacktraceUnwindFailure
+		return bt, Error(err)
 	}
 
-	for i := 0; stack.Next() && i < maxFrames; i++ {
+	stack.SetReadingOptions(t.readingOpts)
+
+	for i := 0; ; i++ {
+		start := time.Now()
+		more := stack.Next()
+		prof.unwind(start)
+		if !more {
+			break
+		}
+
+		if i >= maxFrames {
+			bt.End = BacktraceTruncated
+			return bt, nil
+		}
+
+		start = time.Now()
 		frame, err := stack.Frame()
+		prof.variables(start)
 		if err != nil {
-			return frames, Error(err)
+			bt.End = BacktraceUnwindFailure
+			bt.Unwind = stack.Diagnostic()
+			return bt, Error(err)
+		}
+
+		if i == 0 {
+			t.addRegisterArgs(frame)
 		}
 
-		frames = append(frames, frame)
+		bt.Frames = append(bt.Frames, frame)
+	}
+
+	bt.Unwind = stack.Diagnostic()
+	if bt.Unwind != nil || stack.Err() != nil {
+		bt.End = BacktraceUnwindFailure
+	}
+
+	if err := stack.Err(); err != nil {
+		return bt, Error(err)
+	}
+
+	return bt, nil
+}
+
+// SetLibArgCount sets how many argument registers are captured when a function
+// without debug info is hit at its entry point (0 disables the capture)
+func (t *Tracer) SetLibArgCount(count int) {
+	t.libArgCount = count
+}
+
+func (t *Tracer) addRegisterArgs(frame *BacktraceFrame) {
+	fn := frame.fn
+	if fn.entry.data != nil || t.libArgCount <= 0 || frame.pc != fn.LowPC+fn.StaticBase {
+		return
+	}
+
+	regs, err := GetDwarfRegs(t.tid)
+	if err != nil {
+		return
+	}
+
+	frame.Variables = fn.GetRegisterArgs(regs, t.libArgCount)
+}
+
+// GetPythonBacktrace returns the Python level stacks if the process is a CPython interpreter
+func (t *Tracer) GetPythonBacktrace() ([]PythonThread, error) {
+	if t.python == nil {
+		return nil, Errorf("not a python process")
 	}
 
-	return frames, Error(stack.Err())
+	threads, err := t.python.Threads()
+	return threads, Error(err)
 }
 
 // GetGlobals returns the list of global variables
 func (t *Tracer) GetGlobals() ([]Reading, error) {
 	vars := t.debugData.GetGlobals()
 
-	regs, err := GetDwarfRegs(t.tid)
+	regs, err := GetDwarfRegs(t.callThread())
 	if err != nil {
 		return nil, Error(err)
 	}
 
-	values, err := GetReadings(int(t.pid), 0, regs, vars...)
+	values, err := GetReadingsWithOptions(int(t.pid), 0, regs, t.readingOpts, vars...)
 	return values, Error(err)
 }
 
+// GetDeliverSignal returns the signal that is delivered to the stopped thread on continue
+func (t *Tracer) GetDeliverSignal() syscall.Signal {
+	return t.deliverSignal
+}
+
+// SetDeliverSignal overrides the signal delivered to the stopped thread on continue.
+// Signal events are forwarded by default, 0 suppresses the signal.
+func (t *Tracer) SetDeliverSignal(sig syscall.Signal) {
+	t.deliverSignal = sig
+}
+
 func (t *Tracer) continueExecution() error {
 	if t.tid == 0 {
 		return nil
 	}
 
-	err := t.stepOverBreakpoint()
+	err := t.stepOverWatchpoint()
+	if err != nil {
+		return Error(err)
+	}
+
+	err = t.stepOverBreakpoint()
 	if err != nil {
 		return Error(err)
 	}
@@ -242,6 +506,7 @@ func (t *Tracer) continueExecution() error {
 		return Error(err)
 	}
 
+	t.profile.resumed()
 	t.tid = 0
 
 	return nil
@@ -277,11 +542,98 @@ func (t *Tracer) RemoveBreakpoint(addr uintptr) error {
 			}
 		}
 		delete(t.breakpoints, addr)
+		delete(t.exitPaths, addr)
+		delete(t.bpFunctions, addr)
+		delete(t.bpLimits, addr)
+		delete(t.bpThreads, addr)
+		delete(t.retBreakpoints, addr)
+
+		if addr == t.rendezvous {
+			t.rendezvous = 0
+		}
 	}
 
 	return nil
 }
 
+// SetBreakpointAtFunction sets breakpoints at the functions selected by 'name'
+// and returns where the breakpoint of each match landed. If the name matches multiple
+// functions, an AmbiguousFunctionError is returned unless a selector picks some of them
+// (see DebugData.ResolveFunction).
+func (t *Tracer) SetBreakpointAtFunction(name string) ([]BreakpointLocation, error) {
+	// breakpoints must not be computed from the debug data of a replaced executable
+	t.CheckBinary()
+
+	funcs, err := t.debugData.ResolveFunction(name)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	var locs []BreakpointLocation
+	var errors []error
+
+	for _, fn := range funcs {
+		for _, loc := range newBreakpointLocations(fn, t.debugData, t.placement) {
+			if _, exists := t.breakpoints[loc.Address]; !exists {
+				err := t.SetBreakpoint(loc.Address)
+				if err != nil {
+					loc.Error = errorMessage(err)
+					locs = append(locs, loc)
+					errors = append(errors, err)
+					continue
+				}
+			}
+
+			t.bpFunctions[loc.Address] = name
+
+			locs = append(locs, loc)
+		}
+	}
+
+	return locs, MergeErrors(errors)
+}
+
+// breakpointFunction returns the name of the function of the breakpoint at 'pc'.
+// Unlike the first backtrace frame, it's available at any backtrace depth.
+func (t *Tracer) breakpointFunction(pc uintptr) string {
+	if fn, _ := t.debugData.GetFunctionFromPC(pc); fn != nil {
+		return fn.Name
+	}
+
+	// the name the breakpoint was set by, e.g. if the function has no symbol
+	return t.bpFunctions[pc]
+}
+
+// SetExitBreakpoints sets breakpoints at the functions in ExitFunctions,
+// so the process is caught right before it terminates or unwinds.
+// Missing functions are fine (e.g. __cxa_throw in C programs) as long as some were found,
+// otherwise the error tells why each of them failed.
+func (t *Tracer) SetExitBreakpoints() ([]uintptr, error) {
+	var addrs []uintptr
+	var errors []error
+
+	for _, name := range ExitFunctions {
+		locs, err := t.SetBreakpointAtFunction(name + "#*")
+		if err != nil {
+			errors = append(errors, err)
+		}
+
+		fnAddrs := breakpointAddresses(locs)
+		for _, addr := range fnAddrs {
+			t.exitPaths[addr] = true
+		}
+
+		addrs = append(addrs, fnAddrs...)
+	}
+
+	if len(addrs) == 0 {
+		errors = append([]error{Errorf("none of the exit functions were found")}, errors...)
+		return nil, MergeErrors(errors)
+	}
+
+	return addrs, nil
+}
+
 func (t *Tracer) stepOverBreakpoint() error {
 	addr, err := t.GetPC()
 	if err != nil {
@@ -358,8 +710,46 @@ func (t *Tracer) Interrupt() error {
 	return MergeErrors(errors)
 }
 
-// WaitForEvent blocks until a trace event happens, then returns it
+// WaitForEvent blocks until a trace event happens, then returns it.
+// If the timeout is WaitForever, it doesn't return until the next event.
 func (t *Tracer) WaitForEvent(timeout time.Duration) (*TraceEvent, error) {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		wait := timeout
+		if timeout != WaitForever {
+			// a negative remaining time must not turn into WaitForever
+			wait = time.Until(deadline)
+			if wait < 0 {
+				wait = 0
+			}
+		}
+
+		evt, err := t.waitForStop(wait)
+		if err != nil {
+			return nil, Error(err)
+		} else if evt == nil {
+			return nil, nil
+		}
+
+		if !evt.IsStopRequest && (t.skipUnwatchedWrite(evt) || t.handleLibraryEvent(evt) || t.coverageHit(evt) || t.skipBreakpointHit(evt) || t.traceCall(evt) || t.dropEvent(evt)) {
+			continue
+		}
+
+		t.stats.Events++
+		evt.Seq = t.stats.Events
+
+		err = t.readEventData(evt)
+		if err != nil {
+			return evt, Error(err)
+		}
+
+		return evt, nil
+	}
+}
+
+// waitForStop continues the process and returns the basic information of the next stop
+func (t *Tracer) waitForStop(timeout time.Duration) (*TraceEvent, error) {
 	err := t.continueExecution()
 	if err != nil {
 		return nil, Error(err)
@@ -373,7 +763,8 @@ func (t *Tracer) WaitForEvent(timeout time.Duration) (*TraceEvent, error) {
 		return nil, nil
 	}
 
-	t.deliverSignal = syscall.SIGCONT
+	t.profile.stopped()
+	t.deliverSignal = 0
 	t.tid = wpid // important to set t.tid before reading PC
 
 	evt.PID = t.pid
@@ -383,40 +774,94 @@ func (t *Tracer) WaitForEvent(timeout time.Duration) (*TraceEvent, error) {
 		return nil, Error(err)
 	}
 
-	if evt.Status.Stopped() {
-		evt.Signal = evt.Status.StopSignal()
-	} else {
-		evt.Signal = evt.Status.Signal()
+	evt.Reason = wpid.DecodeStop(evt.Status)
+	evt.Signal = evt.Reason.Signal
+	if !evt.Reason.Terminated() {
+		t.checkStack(evt)
 	}
 
-	if evt.Signal == syscall.SIGTRAP {
+	if evt.Reason.IsEvent(EventClone) {
+		evt.IsNewThread = true
+		newTID, err := wpid.getEventMsg()
+		if err != nil {
+			return nil, Error(err)
+		}
+		evt.NewTID = Process(newTID)
+	} else if evt.Reason.IsTrap() {
 		_, evt.IsBreakpoint = t.breakpoints[evt.PC-trapInstructionSize]
 
 		if evt.IsBreakpoint {
 			evt.PC -= trapInstructionSize
+			evt.IsExitPath = t.exitPaths[evt.PC]
 			err := t.SetPC(evt.PC)
 			if err != nil {
 				return nil, Error(err)
 			}
 		}
+	} else if t.isRequestedStop(evt) {
+		evt.IsStopRequest = true // the signal is suppressed
+	} else if evt.Reason.IsSignal(syscall.SIGSEGV) {
+		wp, handled, err := t.handleWatchpointFault()
+		if err != nil {
+			return nil, Error(err)
+		}
+
+		if wp != nil {
+			evt.IsWatchpoint = true
+			evt.WatchAddress = wp.addr
+			if threads, _ := t.pid.Threads(); len(threads) > 1 {
+				evt.Warnings = append(evt.Warnings, "other threads are not watched while the write is stepped over")
+			}
+		} else if !handled {
+			t.deliverSignal = evt.Signal
+		}
 	} else {
 		t.deliverSignal = evt.Signal
 	}
 
-	evt.Registers, err = t.GetRegisters()
+	return evt, nil
+}
+
+// readEventData collects the registers, backtrace and variables of the event
+func (t *Tracer) readEventData(evt *TraceEvent) error {
+	prof := t.profile.beginEvent()
+	if t.profiling {
+		defer func() { evt.Overhead = prof.end() }()
+	}
+
+	start := time.Now()
+	regs, err := t.GetRegisters()
+	prof.variables(start)
+	evt.Registers = regs
 	if err != nil {
-		return evt, Error(err)
+		return Error(err)
+	}
+
+	if t.registerDiffs {
+		evt.RegisterDiff = t.diffRegisters(evt.TID, evt.Registers)
+		evt.Registers = nil
 	}
 
-	evt.Backtrace, err = t.GetBacktrace(8)
+	bt, err := t.getBacktrace(t.backtraceDepth, prof)
+	evt.Backtrace = bt.Frames
+	evt.BacktraceEnd = bt.End
+	if t.unwindDiagnostics {
+		evt.Unwind = bt.Unwind
+	}
 	if err != nil {
-		return evt, Error(err)
+		return Error(err)
 	}
 
+	start = time.Now()
 	evt.Globals, err = t.GetGlobals()
+	prof.variables(start)
 	if err != nil {
-		return evt, Error(err)
+		return Error(err)
 	}
 
-	return evt, nil
+	if t.python != nil {
+		evt.Python, _ = t.GetPythonBacktrace()
+	}
+
+	return nil
 }
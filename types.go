@@ -0,0 +1,217 @@
+package raztracer
+
+import (
+	"debug/dwarf"
+	"sort"
+)
+
+// TypeKind is the kind of a type
+type TypeKind string
+
+// Type kinds
+const (
+	KindBase     TypeKind = "base"
+	KindStruct   TypeKind = "struct"
+	KindUnion    TypeKind = "union"
+	KindClass    TypeKind = "class"
+	KindEnum     TypeKind = "enum"
+	KindPointer  TypeKind = "pointer"
+	KindArray    TypeKind = "array"
+	KindTypedef  TypeKind = "typedef"
+	KindFunction TypeKind = "function"
+	KindVoid     TypeKind = "void"
+	KindOther    TypeKind = "other"
+)
+
+// TypeInfo is the structured description of a type in the debug info
+type TypeInfo struct {
+	Name        string           `json:"name"`
+	Kind        TypeKind         `json:"kind"`
+	Size        int64            `json:"size"`
+	Module      string           `json:"module,omitempty"`
+	Elem        string           `json:"elem,omitempty"`  // pointed, element or aliased type
+	Count       int64            `json:"count,omitempty"` // number of array elements (-1 if unknown)
+	Members     []TypeMember     `json:"members,omitempty"`
+	Enumerators []TypeEnumerator `json:"enumerators,omitempty"`
+}
+
+// TypeMember is a member of a struct, union or class
+type TypeMember struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Offset    int64  `json:"offset"`
+	Size      int64  `json:"size"`
+	BitOffset int64  `json:"bit_offset,omitempty"`
+	BitSize   int64  `json:"bit_size,omitempty"`
+}
+
+// TypeEnumerator is a named value of an enum
+type TypeEnumerator struct {
+	Name  string `json:"name"`
+	Value int64  `json:"value"`
+}
+
+// Types returns the descriptions of every named type in the binary and its loaded libraries
+func (d *DebugData) Types() ([]*TypeInfo, error) {
+	var types []*TypeInfo
+	var errors []error
+
+	for _, data := range d.typeSources() {
+		for _, name := range data.typeNames() {
+			for _, off := range data.typeIndex[name] {
+				typ, err := data.newTypeInfo(off)
+				if err != nil {
+					errors = append(errors, Error(err))
+					continue
+				}
+
+				types = append(types, typ)
+			}
+		}
+	}
+
+	return types, MergeErrors(errors)
+}
+
+// FindType returns the descriptions of the types named 'name'
+func (d *DebugData) FindType(name string) ([]*TypeInfo, error) {
+	var types []*TypeInfo
+	var errors []error
+
+	for _, data := range d.typeSources() {
+		data.buildTypeIndex()
+
+		for _, off := range data.typeIndex[name] {
+			typ, err := data.newTypeInfo(off)
+			if err != nil {
+				errors = append(errors, Error(err))
+				continue
+			}
+
+			types = append(types, typ)
+		}
+	}
+
+	if len(types) == 0 && len(errors) == 0 {
+		return nil, Errorf("type not found: %s", name)
+	}
+
+	return types, MergeErrors(errors)
+}
+
+func (d *DebugData) typeSources() []*DebugData {
+	return append([]*DebugData{d}, d.libData...)
+}
+
+// typeNames returns the sorted names of the types in the index
+func (d *DebugData) typeNames() []string {
+	d.buildTypeIndex()
+
+	names := make([]string, 0, len(d.typeIndex))
+	for name := range d.typeIndex {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// buildTypeIndex collects the offsets of named type definitions on first use
+func (d *DebugData) buildTypeIndex() {
+	if d.typeIndex != nil {
+		return
+	}
+
+	d.typeIndex = make(map[string][]dwarf.Offset)
+
+	reader := d.dwarfData.Reader()
+	for entry, err := reader.Next(); entry != nil && err == nil; entry, err = reader.Next() {
+		switch entry.Tag {
+		case dwarf.TagBaseType, dwarf.TagStructType, dwarf.TagUnionType, dwarf.TagClassType,
+			dwarf.TagEnumerationType, dwarf.TagTypedef:
+		default:
+			continue
+		}
+
+		if decl, _ := entry.Val(dwarf.AttrDeclaration).(bool); decl {
+			continue
+		}
+
+		name, ok := entry.Val(dwarf.AttrName).(string)
+		if !ok {
+			continue
+		}
+
+		d.typeIndex[name] = append(d.typeIndex[name], entry.Offset)
+	}
+
+	// not enforced here, the index is used right after it's built
+	d.budget.caches += int64(len(d.typeIndex)) * typeIndexEntrySize
+}
+
+func (d *DebugData) newTypeInfo(off dwarf.Offset) (*TypeInfo, error) {
+	typ, err := d.dwarfData.Type(off)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	info := &TypeInfo{
+		Name:   typ.String(),
+		Kind:   KindOther,
+		Size:   typ.Size(),
+		Module: d.moduleName(),
+	}
+
+	switch t := typ.(type) {
+	case *dwarf.StructType:
+		info.Name = t.StructName
+		info.Kind = TypeKind(t.Kind)
+		for _, field := range t.Field {
+			info.Members = append(info.Members, TypeMember{
+				Name:      field.Name,
+				Type:      field.Type.String(),
+				Offset:    field.ByteOffset,
+				Size:      field.Type.Size(),
+				BitOffset: field.BitOffset,
+				BitSize:   field.BitSize,
+			})
+		}
+
+	case *dwarf.EnumType:
+		info.Name = t.EnumName
+		info.Kind = KindEnum
+		for _, val := range t.Val {
+			info.Enumerators = append(info.Enumerators, TypeEnumerator{
+				Name:  val.Name,
+				Value: val.Val,
+			})
+		}
+
+	case *dwarf.TypedefType:
+		info.Name = t.Name
+		info.Kind = KindTypedef
+		info.Elem = t.Type.String()
+
+	case *dwarf.PtrType:
+		info.Kind = KindPointer
+		info.Elem = t.Type.String()
+
+	case *dwarf.ArrayType:
+		info.Kind = KindArray
+		info.Elem = t.Type.String()
+		info.Count = t.Count
+
+	case *dwarf.FuncType:
+		info.Kind = KindFunction
+
+	case *dwarf.VoidType:
+		info.Kind = KindVoid
+
+	case *dwarf.BasicType, *dwarf.IntType, *dwarf.UintType, *dwarf.FloatType,
+		*dwarf.BoolType, *dwarf.CharType, *dwarf.UcharType, *dwarf.ComplexType,
+		*dwarf.AddrType, *dwarf.UnspecifiedType:
+		info.Kind = KindBase
+	}
+
+	return info, nil
+}
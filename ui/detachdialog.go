@@ -0,0 +1,45 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/razzie/raztracer"
+)
+
+// ConfirmDetach asks the user whether to detach from the process, then displays
+// the cleanup report of the detach including the risks left behind.
+// The tracer is only accessed in the tracer's thread of the manager.
+func ConfirmDetach(modal ModalHandler, proc *raztracer.TraceManager) {
+	var progName string
+	err := proc.HandleRequest(func(t *raztracer.Tracer) error {
+		progName = t.GetProgName()
+		return nil
+	})
+	if err != nil {
+		modal.ModalMessage(err.Error())
+		return
+	}
+
+	msg := fmt.Sprintf("Do you really want to detach from %s?", progName)
+	modal.ModalYesNo(msg, func() {
+		var report *raztracer.DetachReport
+		err := proc.HandleRequest(func(t *raztracer.Tracer) error {
+			var err error
+			report, err = t.DetachWithReport()
+			return err
+		})
+		if report == nil {
+			modal.ModalMessage(err.Error())
+			return
+		}
+
+		text := report.String()
+		if !report.Clean() {
+			text = "WARNING: the process may not be left clean\n\n" + text
+		}
+		if err != nil {
+			text += "\n" + err.Error()
+		}
+		modal.ModalMessage(text)
+	})
+}
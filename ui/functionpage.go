@@ -0,0 +1,45 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/razzie/raztracer"
+	"github.com/rivo/tview"
+)
+
+// NewFunctionPage returns a page that lists functions with their signatures.
+// 'selectFunc' is called with the function of the selected row.
+func NewFunctionPage(funcs []*raztracer.FunctionEntry, selectFunc func(*raztracer.FunctionEntry)) Page {
+	table := tview.NewTable().
+		SetFixed(1, 0).
+		SetSelectable(true, false)
+
+	for col, header := range []string{"Address", "Signature", "Library"} {
+		table.SetCell(0, col, tview.NewTableCell(colorize(header)).SetSelectable(false))
+	}
+
+	for i, fn := range funcs {
+		signature := fn.Name
+		if sig, err := fn.Signature(); err == nil {
+			signature = sig.String()
+		}
+
+		var lib string
+		if fn.Lib != nil {
+			lib = fn.Lib.Name
+		}
+
+		row := i + 1
+		table.SetCell(row, 0, tview.NewTableCell(fmt.Sprintf("%#x", fn.LowPC+fn.StaticBase)))
+		table.SetCell(row, 1, tview.NewTableCell(tview.Escape(signature)))
+		table.SetCell(row, 2, tview.NewTableCell(tview.Escape(lib)))
+	}
+
+	table.SetSelectedFunc(func(row, col int) {
+		if selectFunc != nil && row > 0 && row <= len(funcs) {
+			selectFunc(funcs[row-1])
+		}
+	})
+
+	return NewPage(table, "Functions")
+}
@@ -0,0 +1,37 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/razzie/raztracer"
+	"github.com/rivo/tview"
+)
+
+// NewInstructionTracePage returns a page that displays an instruction trace
+func NewInstructionTracePage(steps []*raztracer.InstructionStep) Page {
+	table := tview.NewTable().
+		SetFixed(1, 0).
+		SetSelectable(true, false)
+
+	for col, header := range []string{"PC", "Function", "Instruction", "Changed registers"} {
+		table.SetCell(0, col, tview.NewTableCell(colorize(header)).SetSelectable(false))
+	}
+
+	for i, step := range steps {
+		regs := make([]string, 0, len(step.Registers))
+		for reg, val := range step.Registers {
+			regs = append(regs, reg+"="+val)
+		}
+		sort.Strings(regs)
+
+		row := i + 1
+		table.SetCell(row, 0, tview.NewTableCell(fmt.Sprintf("%#x", step.PC)))
+		table.SetCell(row, 1, tview.NewTableCell(tview.Escape(step.Function)))
+		table.SetCell(row, 2, tview.NewTableCell(tview.Escape(step.Instruction)))
+		table.SetCell(row, 3, tview.NewTableCell(tview.Escape(strings.Join(regs, " "))))
+	}
+
+	return NewPage(table, "Instructions")
+}
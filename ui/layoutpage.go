@@ -0,0 +1,40 @@
+package ui
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/razzie/raztracer"
+	"github.com/rivo/tview"
+)
+
+// NewLayoutPage returns a page that displays the address space layout of a process
+func NewLayoutPage(layout *raztracer.Layout) Page {
+	view := tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true)
+
+	fmt.Fprintln(view, colorize("Memory regions:"))
+	for _, region := range layout.Regions {
+		fmt.Fprintf(view, "%#x-%#x %s %#x %s\n",
+			region.Address[0], region.Address[1], region.Permissions,
+			region.Offset, tview.Escape(region.Pathname))
+	}
+
+	for _, module := range layout.Modules {
+		debugInfo := "no debug info"
+		if module.DebugInfo {
+			debugInfo = "debug info loaded"
+		}
+
+		fmt.Fprintf(view, "\n%s %s (base: %#x, %s)\n",
+			colorize(tview.Escape(path.Base(module.Name))+":"),
+			tview.Escape(module.Name), module.StaticBase, debugInfo)
+
+		for _, sec := range module.Sections {
+			fmt.Fprintf(view, "%#x-%#x %s\n", sec.Address, sec.Address+uintptr(sec.Size), sec.Name)
+		}
+	}
+
+	return NewPage(view, "Layout")
+}
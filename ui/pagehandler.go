@@ -84,11 +84,11 @@ func (ph *PageHandler) ModalMessage(msg string) {
 // ModalYesNo displays a modal dialog with a message and yes/no options
 func (ph *PageHandler) ModalYesNo(msg string, yes func()) {
 	ph.modalYesNo.SetText(msg).SetDoneFunc(func(buttonIndex int, buttonLabel string) {
-		if buttonIndex == 0 {
-			yes()
-		}
 		ph.pageHandler.HidePage("modal_yes_no")
 		ph.modalActive = false
+		if buttonIndex == 0 {
+			yes() // can open another modal dialog
+		}
 	})
 	ph.pageHandler.SendToFront("modal_yes_no").ShowPage("modal_yes_no")
 	ph.modalActive = true
@@ -0,0 +1,43 @@
+package ui
+
+import (
+	"sort"
+
+	"github.com/razzie/raztracer"
+	"github.com/rivo/tview"
+)
+
+// NewRegisterPage returns a page that displays the registers of an event.
+// If the event contains register diffs, the previous values are displayed too.
+func NewRegisterPage(evt *raztracer.TraceEvent) Page {
+	table := tview.NewTable().
+		SetFixed(1, 0).
+		SetSelectable(true, false)
+
+	for col, header := range []string{"Register", "Value", "Previous value"} {
+		table.SetCell(0, col, tview.NewTableCell(colorize(header)).SetSelectable(false))
+	}
+
+	regs := make([]string, 0, len(evt.Registers)+len(evt.RegisterDiff))
+	for reg := range evt.Registers {
+		regs = append(regs, reg)
+	}
+	for reg := range evt.RegisterDiff {
+		regs = append(regs, reg)
+	}
+	sort.Strings(regs)
+
+	for i, reg := range regs {
+		row := i + 1
+		if change, changed := evt.RegisterDiff[reg]; changed {
+			table.SetCell(row, 0, tview.NewTableCell(colorize(tview.Escape(reg))))
+			table.SetCell(row, 1, tview.NewTableCell(colorize(change.New)))
+			table.SetCell(row, 2, tview.NewTableCell(change.Old))
+		} else {
+			table.SetCell(row, 0, tview.NewTableCell(tview.Escape(reg)))
+			table.SetCell(row, 1, tview.NewTableCell(evt.Registers[reg]))
+		}
+	}
+
+	return NewPage(table, "Registers")
+}
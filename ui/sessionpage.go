@@ -0,0 +1,42 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/razzie/raztracer"
+	"github.com/rivo/tview"
+)
+
+// NewSessionPage returns a page that displays the information of a trace session
+func NewSessionPage(info *raztracer.SessionInfo) Page {
+	view := tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true)
+
+	fmt.Fprintf(view, "%s %d (%s)\n", colorize("PID:"), info.PID, tview.Escape(info.ProgName))
+	fmt.Fprintf(view, "%s %s\n", colorize("Executable:"), tview.Escape(info.Executable))
+	fmt.Fprintf(view, "%s %s\n", colorize("Build ID:"), info.BuildID)
+	fmt.Fprintf(view, "%s %s\n", colorize("Command line:"), tview.Escape(strings.Join(info.Cmdline, " ")))
+	fmt.Fprintf(view, "%s %s\n", colorize("Working directory:"), tview.Escape(info.Cwd))
+	fmt.Fprintf(view, "%s %s\n", colorize("Attached:"), info.AttachTime.Format("2006-01-02 15:04:05"))
+	if len(info.Root) > 0 {
+		fmt.Fprintf(view, "%s %s (%s)\n", colorize("Container root:"), tview.Escape(info.Root), info.MountNS)
+	}
+
+	fmt.Fprintln(view, "\n"+colorize("Libraries:"))
+	for _, lib := range info.Libraries {
+		if lib.Path != lib.Name && len(lib.Path) > 0 {
+			fmt.Fprintf(view, "%#x %s -> %s\n", lib.StaticBase, tview.Escape(lib.Name), tview.Escape(lib.Path))
+		} else {
+			fmt.Fprintf(view, "%#x %s\n", lib.StaticBase, tview.Escape(lib.Name))
+		}
+	}
+
+	fmt.Fprintln(view, "\n"+colorize("Environment:"))
+	for _, env := range info.Environ {
+		fmt.Fprintln(view, tview.Escape(env))
+	}
+
+	return NewPage(view, "Session")
+}
@@ -0,0 +1,81 @@
+package raztracer
+
+import (
+	"fmt"
+)
+
+// UnwindFailure is the reason of an early terminated backtrace
+type UnwindFailure string
+
+// Possible unwind failures
+const (
+	UnwindNoFunction        UnwindFailure = "no_function"
+	UnwindUndefinedCFA      UnwindFailure = "undefined_cfa"
+	UnwindUndefinedRetAddr  UnwindFailure = "undefined_return_address"
+	UnwindBadReturnAddress  UnwindFailure = "bad_return_address"
+	UnwindUnreadableMemory  UnwindFailure = "unreadable_memory"
+	UnwindUnsupportedRule   UnwindFailure = "unsupported_rule"
+	UnwindExpressionFailure UnwindFailure = "expression_failure"
+)
+
+// UnwindDiagnostic explains why the unwinding of a stack stopped early
+type UnwindDiagnostic struct {
+	Reason  UnwindFailure `json:"reason"`
+	Frame   int           `json:"frame"`             // index of the frame that could not be unwound
+	PC      uintptr       `json:"pc"`                // PC of the frame that could not be unwound
+	CFA     uintptr       `json:"cfa,omitempty"`     // CFA of the frame if it was calculated
+	Address uintptr       `json:"address,omitempty"` // return address or unreadable memory address
+	NoFDE   bool          `json:"no_fde"`            // the frame pointer heuristic was used
+	Detail  string        `json:"detail,omitempty"`
+}
+
+// String returns the diagnostic as a human readable message
+func (diag *UnwindDiagnostic) String() string {
+	var msg string
+
+	switch diag.Reason {
+	case UnwindNoFunction:
+		msg = fmt.Sprintf("no function or CFI found at return address %#x", diag.Address)
+	case UnwindUndefinedCFA:
+		msg = fmt.Sprintf("CFA is undefined at PC %#x", diag.PC)
+	case UnwindUndefinedRetAddr:
+		msg = fmt.Sprintf("return address is undefined at PC %#x", diag.PC)
+	case UnwindBadReturnAddress:
+		msg = fmt.Sprintf("return address %#x is not in executable memory", diag.Address)
+	case UnwindUnreadableMemory:
+		msg = fmt.Sprintf("memory at %#x is unreadable", diag.Address)
+	default:
+		msg = string(diag.Reason)
+	}
+
+	msg = fmt.Sprintf("frame #%d: %s", diag.Frame, msg)
+
+	if diag.CFA != 0 {
+		msg += fmt.Sprintf(" (CFA: %#x)", diag.CFA)
+	}
+
+	if diag.NoFDE {
+		msg += fmt.Sprintf(" (no FDE for PC %#x, frame pointer heuristic used)", diag.PC)
+	}
+
+	if len(diag.Detail) > 0 {
+		msg += ": " + diag.Detail
+	}
+
+	return msg
+}
+
+// SetUnwindDiagnostics enables the reporting of the reason of early terminated backtraces in events
+func (t *Tracer) SetUnwindDiagnostics(enabled bool) {
+	t.unwindDiagnostics = enabled
+}
+
+// isExecutableAddress checks whether 'addr' is in an executable memory region of the process
+func isExecutableAddress(regions []MemRegion, addr uintptr) bool {
+	for _, region := range regions {
+		if addr >= region.Address[0] && addr < region.Address[1] {
+			return len(region.Permissions) > 2 && region.Permissions[2] == 'x'
+		}
+	}
+	return false
+}
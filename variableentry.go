@@ -68,7 +68,11 @@ func NewVariableEntry(de DebugEntry) (*VariableEntry, error) {
 }
 
 // GetValue returns the current location and raw value of the variable based on PC and registers
-func (v *VariableEntry) GetValue(pid int, pc uintptr, regs *op.DwarfRegisters) (*Location, []byte, *TracedError) {
+func (v *VariableEntry) GetValue(pid int, pc uintptr, regs *op.DwarfRegisters) (*Location, []byte, error) {
+	return v.getValue(Process(pid), pc, regs)
+}
+
+func (v *VariableEntry) getValue(mem MemoryReader, pc uintptr, regs *op.DwarfRegisters) (*Location, []byte, error) {
 	if v.Size == 0 && !v.IsPointer {
 		return nil, nil, nil
 	}
@@ -78,7 +82,7 @@ func (v *VariableEntry) GetValue(pid int, pc uintptr, regs *op.DwarfRegisters) (
 		return nil, nil, Error(err)
 	}
 
-	data, err := loc.Read(pid, regs)
+	data, err := loc.ReadFrom(mem, v.entry.data.withTLS(regs))
 	if err != nil {
 		return loc, nil, Error(err)
 	}
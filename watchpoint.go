@@ -0,0 +1,249 @@
+package raztracer
+
+import (
+	"os"
+	"syscall"
+)
+
+// Watchpoint is a software memory breakpoint that catches writes to a memory range
+// by removing the write permission from the pages containing it
+type Watchpoint struct {
+	addr  uintptr
+	size  uintptr
+	pages [2]uintptr
+	prot  int
+}
+
+// GetAddress returns the address of the watched memory range
+func (wp *Watchpoint) GetAddress() uintptr {
+	return wp.addr
+}
+
+// GetSize returns the size of the watched memory range
+func (wp *Watchpoint) GetSize() uintptr {
+	return wp.size
+}
+
+func (wp *Watchpoint) contains(addr uintptr) bool {
+	return addr >= wp.addr && addr < wp.addr+wp.size
+}
+
+func (wp *Watchpoint) containsPage(addr uintptr) bool {
+	return addr >= wp.pages[0] && addr < wp.pages[1]
+}
+
+// SetWatchpoint catches writes to the 'size' bytes long memory range at 'addr'.
+// The pages of the range are write protected by an injected mprotect() call,
+// so every write to these pages stops the writing thread.
+//
+// Only writes of the process itself are caught: when the kernel writes to a protected page
+// (e.g. read() into a watched buffer), the system call of the process fails with EFAULT
+// instead of raising SIGSEGV.
+//
+// The pages are writable while the caught write is stepped over, and the other threads of the
+// process keep running meanwhile, so their writes in this window are missed. Watchpoint events
+// of multithreaded processes carry a warning about it.
+func (t *Tracer) SetWatchpoint(addr, size uintptr) error {
+	if size == 0 {
+		return Errorf("invalid watchpoint size: %d", size)
+	}
+
+	if _, exists := t.watchpoints[addr]; exists {
+		return Errorf("watchpoint already exists %#x", addr)
+	}
+
+	regions, err := t.pid.MemRegions()
+	if err != nil {
+		return Error(err)
+	}
+
+	var region *MemRegion
+	for i := range regions {
+		if addr >= regions[i].Address[0] && addr+size <= regions[i].Address[1] {
+			region = &regions[i]
+			break
+		}
+	}
+
+	if region == nil {
+		return Errorf("address range is not mapped: %#x-%#x", addr, addr+size)
+	}
+
+	prot := regionProtection(region.Permissions)
+	if prot&syscall.PROT_WRITE == 0 {
+		return Errorf("address range is not writable: %#x-%#x", addr, addr+size)
+	}
+
+	pageSize := uintptr(os.Getpagesize())
+	wp := &Watchpoint{
+		addr:  addr,
+		size:  size,
+		pages: [2]uintptr{addr &^ (pageSize - 1), (addr + size + pageSize - 1) &^ (pageSize - 1)},
+		prot:  prot,
+	}
+
+	err = t.protectWatchpoint(wp, true)
+	if err != nil {
+		return Error(err)
+	}
+
+	t.watchpoints[addr] = wp
+	return nil
+}
+
+// RemoveWatchpoint removes the watchpoint at the given address
+func (t *Tracer) RemoveWatchpoint(addr uintptr) error {
+	wp, found := t.watchpoints[addr]
+	if !found {
+		return nil
+	}
+
+	delete(t.watchpoints, addr)
+	if t.pendingWatch == wp {
+		t.pendingWatch = nil
+	}
+
+	return Error(t.protectWatchpoint(wp, false))
+}
+
+// GetWatchpoints returns the active watchpoints
+func (t *Tracer) GetWatchpoints() []*Watchpoint {
+	watchpoints := make([]*Watchpoint, 0, len(t.watchpoints))
+	for _, wp := range t.watchpoints {
+		watchpoints = append(watchpoints, wp)
+	}
+	return watchpoints
+}
+
+// protectWatchpoint write protects or restores the original protection of the
+// watchpoint's pages. Pages shared with other watchpoints are kept protected.
+func (t *Tracer) protectWatchpoint(wp *Watchpoint, protect bool) error {
+	mprotect, err := t.findLibFunction("mprotect", "__mprotect")
+	if err != nil {
+		return Error(err)
+	}
+
+	pageSize := uintptr(os.Getpagesize())
+
+	for page := wp.pages[0]; page < wp.pages[1]; page += pageSize {
+		prot := wp.prot
+		if protect || t.isWatchedPage(page) {
+			prot &^= syscall.PROT_WRITE
+		}
+
+		ret, err := t.callFunction(mprotect, nil, uint(page), uint(pageSize), uint(prot))
+		if err != nil {
+			return Error(err)
+		}
+
+		if int(ret) != 0 {
+			return Errorf("mprotect failed at %#x", page)
+		}
+	}
+
+	return nil
+}
+
+func (t *Tracer) isWatchedPage(page uintptr) bool {
+	for _, wp := range t.watchpoints {
+		if wp.containsPage(page) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleWatchpointFault checks if the SIGSEGV of the stopped thread was caused by
+// a watchpoint and returns the watchpoint if the write hit a watched range.
+// 'handled' is true if the fault happened on a protected page.
+func (t *Tracer) handleWatchpointFault() (wp *Watchpoint, handled bool, err error) {
+	if len(t.watchpoints) == 0 {
+		return nil, false, nil
+	}
+
+	addr, err := t.tid.getFaultAddress()
+	if err != nil {
+		return nil, false, Error(err)
+	}
+
+	for _, wp := range t.watchpoints {
+		if wp.contains(addr) {
+			t.pendingWatch = wp
+			return wp, true, nil
+		}
+	}
+
+	for _, wp := range t.watchpoints {
+		if wp.containsPage(addr) {
+			t.pendingWatch = wp
+			return nil, true, nil
+		}
+	}
+
+	return nil, false, nil
+}
+
+// skipUnwatchedWrite returns true if the event is a write to a protected page
+// outside of the watched ranges, which is stepped over without being reported
+func (t *Tracer) skipUnwatchedWrite(evt *TraceEvent) bool {
+	return t.pendingWatch != nil && !evt.IsWatchpoint
+}
+
+// stepOverWatchpoint executes the faulting write with the original protection
+// of the pages, then re-arms the watchpoint
+func (t *Tracer) stepOverWatchpoint() error {
+	wp := t.pendingWatch
+	if wp == nil {
+		return nil
+	}
+
+	t.pendingWatch = nil
+
+	// pages shared with other watchpoints have to be writable too during the step
+	watchpoints := t.watchpoints
+	t.watchpoints = nil
+	err := t.protectWatchpoint(wp, false)
+	t.watchpoints = watchpoints
+	if err != nil {
+		return Error(err)
+	}
+
+	err = t.tid.SingleStep()
+	if err != nil {
+		return Error(err)
+	}
+
+	return Error(t.protectWatchpoint(wp, true))
+}
+
+func (t *Tracer) removeWatchpoints() error {
+	var errors []error
+
+	watchpoints := t.watchpoints
+	t.watchpoints = make(map[uintptr]*Watchpoint)
+	t.pendingWatch = nil
+
+	for _, wp := range watchpoints {
+		err := t.protectWatchpoint(wp, false)
+		if err != nil {
+			errors = append(errors, Error(err))
+		}
+	}
+
+	return MergeErrors(errors)
+}
+
+func regionProtection(perms string) int {
+	var prot int
+	for _, p := range perms {
+		switch p {
+		case 'r':
+			prot |= syscall.PROT_READ
+		case 'w':
+			prot |= syscall.PROT_WRITE
+		case 'x':
+			prot |= syscall.PROT_EXEC
+		}
+	}
+	return prot
+}
@@ -0,0 +1,84 @@
+package raztracer
+
+import (
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWatchpoint(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	cmd := startTracee(t, 200)
+	defer cmd.Process.Kill()
+
+	tracer, err := NewTracer(cmd.Process.Pid)
+	skipIfNotPermitted(t, err)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// usr1_hits is only written by the signal handler, but 'counter'
+	// on the same page is written in every iteration
+	hits, err := tracer.ReadGlobal("usr1_hits")
+	if err != nil || hits.Address == 0 {
+		tracer.Detach()
+		t.Fatal(hits, err)
+	}
+
+	if err := tracer.SetWatchpoint(hits.Address, 4); err != nil {
+		tracer.Detach()
+		t.Fatal(err)
+	}
+
+	tracer.Run()
+
+	start := time.Now()
+	evt, err := tracer.WaitForEvent(300 * time.Millisecond)
+	if err != nil {
+		tracer.Detach()
+		t.Fatal(err)
+	}
+	if evt != nil {
+		t.Errorf("unexpected event: %v", evt)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("WaitForEvent returned after %v, before its timeout", elapsed)
+	}
+
+	syscall.Kill(cmd.Process.Pid, syscall.SIGUSR1)
+
+	var watchEvt *TraceEvent
+	for watchEvt == nil {
+		evt, err := tracer.WaitForEvent(time.Second)
+		if err != nil || evt == nil {
+			tracer.Detach()
+			t.Fatal("the watchpoint wasn't hit", err)
+		}
+
+		if evt.IsWatchpoint {
+			watchEvt = evt
+		}
+	}
+
+	if watchEvt.WatchAddress != hits.Address {
+		t.Errorf("the watchpoint at %#x was hit instead of %#x", watchEvt.WatchAddress, hits.Address)
+	}
+	if len(watchEvt.Backtrace) == 0 || watchEvt.Backtrace[0].fn.Name != "on_usr1" {
+		t.Errorf("unexpected backtrace of the write: %v", watchEvt.Backtrace)
+	}
+
+	report, err := tracer.DetachWithReport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.WatchpointsRemoved != 1 || !report.Clean() {
+		t.Errorf("unexpected detach report:\n%s", report)
+	}
+
+	if status := waitTracee(t, cmd); status != 1 {
+		t.Errorf("the test program handled %d SIGUSR1 and %d SIGCONT, expected 1 SIGUSR1", status%16, status/16)
+	}
+}